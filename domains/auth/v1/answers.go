@@ -1,6 +1,17 @@
 package authv1
 
-import "github.com/soldatov-s/go-garage/providers/httpsrv"
+import (
+	"github.com/soldatov-s/go-garage-auth/models"
+	"github.com/soldatov-s/go-garage/providers/httpsrv"
+)
 
 // Return separated items
 type TokenDataResult httpsrv.ResultAnsw
+
+type TokenPairResult httpsrv.ResultAnsw
+
+type CSRFTokenResult httpsrv.ResultAnsw
+
+// Return array of items
+type SessionsDataResult httpsrv.ResultAnsw
+type ArrayOfSession []models.Session
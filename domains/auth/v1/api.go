@@ -1,12 +1,12 @@
 package authv1
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
-	"time"
 
-	"github.com/soldatov-s/go-garage-auth/internal/hmac"
+	"github.com/soldatov-s/go-garage-auth/internal/jwt"
 	"github.com/soldatov-s/go-garage-auth/models"
 	"github.com/soldatov-s/go-garage/providers/httpsrv"
 	"github.com/soldatov-s/go-garage/providers/httpsrv/echo"
@@ -17,17 +17,32 @@ const (
 	SessionCookie = "go-garage-session"
 )
 
+// getTokenFromRequest returns the token from Authorization header, query or session cookie,
+// query is ignored if query tokens are disabled
 func (a *AuthV1) getTokenFromRequest(ec echo.Context) (string, error) {
-	token := ec.QueryParam("token")
-	if token == "" {
-		sesionCookie, err := ec.Cookie(SessionCookie)
-		if err != nil {
-			return "", err
+	if token := bearerToken(ec); token != "" {
+		return token, nil
+	}
+
+	if !a.cfg.Token.DisableQueryToken {
+		if token := ec.QueryParam("token"); token != "" {
+			return token, nil
 		}
-		token = sesionCookie.Value
 	}
 
-	return token, nil
+	if sesionCookie, err := ec.Cookie(SessionCookie); err == nil && sesionCookie.Value != "" {
+		return sesionCookie.Value, nil
+	}
+
+	return "", ErrEmptyToken
+}
+
+// SessionClientFromRequest returns the description of the client for the new session
+func SessionClientFromRequest(ec echo.Context) *models.SessionClient {
+	return &models.SessionClient{
+		ClientIP:  ec.RealIP(),
+		UserAgent: ec.Request().UserAgent(),
+	}
 }
 
 func (a *AuthV1) revokePostHandler(ec echo.Context) (err error) {
@@ -38,9 +53,10 @@ func (a *AuthV1) revokePostHandler(ec echo.Context) (err error) {
 			SetProduces("application/json").
 			SetDescription("Revoke token Handler").
 			SetSummary("This handler for revoking token").
-			AddInQueryParameter("token", "Deleted token", reflect.Bool, false).
+			AddInQueryParameter("token", "Deleted token", reflect.String, false).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, false).
 			AddResponse(http.StatusOK, "OK", httpsrv.OkResult()).
-			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
 			AddResponse(http.StatusConflict, "DATA NOT DELETED", httpsrv.NotDeleted(err))
 
 		return nil
@@ -51,18 +67,24 @@ func (a *AuthV1) revokePostHandler(ec echo.Context) (err error) {
 	token, err := a.getTokenFromRequest(ec)
 	if err != nil {
 		log.Err(err).Msg("getting token from request failed")
-		return ec.BadRequest(err)
+		return a.bearerError(ec, http.StatusUnauthorized, "", err)
 	}
 
-	strategy, err := hmac.Get(a.ctx)
+	strategy, err := a.strategy()
 	if err != nil {
-		log.Err(err).Msgf("get token failed %s", token)
+		log.Err(err).Msg("get token strategy failed")
 		return ec.BadRequest(err)
 	}
 
+	// The token is never logged, it is still valid until the revocation
+	if err = strategy.Validate(token); err != nil {
+		log.Err(err).Msg("UNAUTHORIZED, token isn't valid")
+		return a.bearerError(ec, http.StatusUnauthorized, BearerErrorInvalidToken, err)
+	}
+
 	err = a.DeleteToken(strategy.Signature(token))
 	if err != nil {
-		log.Err(err).Msgf("revoking token failed %s", token)
+		log.Err(err).Msg("revoking token failed")
 
 		return ec.NotDeleted(err)
 	}
@@ -78,9 +100,10 @@ func (a *AuthV1) introspectGetHandler(ec echo.Context) (err error) {
 			SetProduces("application/json").
 			SetDescription("Introspect token Handler").
 			SetSummary("This handler for introspection token").
-			AddInQueryParameter("token", "Deleted token", reflect.Bool, false).
+			AddInQueryParameter("token", "Deleted token", reflect.String, false).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, false).
 			AddResponse(http.StatusOK, "OK", &TokenDataResult{Body: models.Token{}}).
-			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
 			AddResponse(http.StatusConflict, "DATA NOT DELETED", httpsrv.NotDeleted(err))
 
 		return nil
@@ -91,34 +114,273 @@ func (a *AuthV1) introspectGetHandler(ec echo.Context) (err error) {
 	token, err := a.getTokenFromRequest(ec)
 	if err != nil {
 		log.Err(err).Msg("getting session from request failed")
+		return a.bearerError(ec, http.StatusUnauthorized, "", err)
+	}
+
+	session, err := a.Introspect(token)
+	if err != nil {
+		log.Err(err).Msg("token isn't valid")
+		return ec.OK(TokenDataResult{Body: &models.TokenIntrospection{}})
+	}
+
+	log.Debug().Msgf("find session for subject %s", session.Subject)
+
+	intropsectResullt := &models.TokenIntrospection{
+		Active:      true,
+		Subject:     session.Subject,
+		Role:        session.Role.String(),
+		Permissions: session.Permissions,
+		Meta:        session.Meta.Map,
+		ExpiredAt:   session.ExpiredAt.Time.Unix(),
+	}
+	return ec.OK(TokenDataResult{Body: intropsectResullt})
+}
+
+func (a *AuthV1) refreshPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Refresh token Handler").
+			SetSummary("This handler exchanges refresh token for a new access token and refresh token").
+			AddInBodyParameter("refresh_token", "Refresh token", &models.RefreshRequest{}, true).
+			AddResponse(http.StatusOK, "OK", &TokenPairResult{Body: models.TokenPair{}}).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err))
+
+		return nil
+	}
+	// Main code of handler
+	log := ec.GetLog()
+
+	var req models.RefreshRequest
+
+	err = ec.Bind(&req)
+	if err != nil {
+		log.Err(err).Msg("BAD REQUEST")
 		return ec.BadRequest(err)
 	}
 
-	strategy, err := hmac.Get(a.ctx)
+	if !req.Validate() {
+		log.Err(ErrInvalidRefreshToken).Msg("BAD REQUEST")
+		return ec.BadRequest(ErrInvalidRefreshToken)
+	}
+
+	tokenPair, err := a.RefreshToken(req.RefreshToken, SessionClientFromRequest(ec))
+	if err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) ||
+			errors.Is(err, ErrRefreshTokenExpired) ||
+			errors.Is(err, ErrRefreshTokenReused) {
+			log.Err(err).Msg("UNAUTHORIZED")
+			return ec.Unauthorized(err)
+		}
+
+		log.Err(err).Msg("refreshing token failed")
+		return ec.InternalServerError(err)
+	}
+
+	return ec.OK(TokenPairResult{Body: tokenPair})
+}
+
+func (a *AuthV1) sessionsDeleteHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Revoke all sessions Handler").
+			SetSummary("This handler for revoking all tokens of user by user_id").
+			AddInPathParameter("id", "User id", reflect.Int64).
+			AddInQueryParameter("keep_current", "Keep session of token from request, if equal true", reflect.Bool, false).
+			AddInQueryParameter("token", "Current token", reflect.String, false).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "OK", httpsrv.OkResult()).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusNotAcceptable, "DATA NOT DELETED", httpsrv.NotDeleted(err))
+
+		return nil
+	}
+	// Main code of handler
+	log := ec.GetLog()
+
+	userID, err := ec.GetInt64Param("id")
 	if err != nil {
-		log.Err(err).Msgf("get token failed %s", token)
+		log.Err(err).Msgf("BAD REQUEST, id %s", ec.Param("id"))
 		return ec.BadRequest(err)
 	}
 
-	err = strategy.Validate(token)
+	var keepToken string
+	if ec.QueryParam("keep_current") == "true" {
+		keepToken, err = a.getTokenFromRequest(ec)
+		if err != nil {
+			log.Err(err).Msg("getting token from request failed")
+			return ec.BadRequest(err)
+		}
+	}
+
+	err = a.RevokeAllTokens(int(userID), keepToken)
 	if err != nil {
-		log.Err(err).Msgf("token %s isn't valid", token)
-		return ec.OK(TokenDataResult{Body: &models.TokenIntrospection{}})
+		log.Err(err).Msgf("DATA NOT DELETED, id %d", userID)
+		return ec.NotDeleted(err)
 	}
 
-	session, err := a.GetToken(strategy.Signature(token))
-	if err != nil || session.ExpiredAt.Time.Before(time.Now().UTC()) {
-		log.Err(err).Msgf("get token failed %s", token)
-		return ec.OK(TokenDataResult{Body: &models.TokenIntrospection{}})
+	return ec.OkResult()
+}
+
+func (a *AuthV1) sessionsGetHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Get sessions Handler").
+			SetSummary("This handler returns active sessions of user by user_id").
+			AddInPathParameter("id", "User id", reflect.Int64).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "Sessions", &SessionsDataResult{Body: ArrayOfSession{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err))
+
+		return nil
+	}
+	// Main code of handler
+	log := ec.GetLog()
+
+	userID, err := ec.GetInt64Param("id")
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, id %s", ec.Param("id"))
+		return ec.BadRequest(err)
 	}
 
-	log.Debug().Msgf("find session for subject %s", session.Subject)
+	sessions, err := a.GetSessions(int(userID))
+	if err != nil {
+		log.Err(err).Msgf("NOT FOUND, id %d", userID)
+		return ec.NotFound(err)
+	}
 
-	intropsectResullt := &models.TokenIntrospection{
-		Active:    true,
-		Subject:   session.Subject,
-		Meta:      session.Meta.Map,
-		ExpiredAt: session.ExpiredAt.Time.Unix(),
+	return ec.OK(SessionsDataResult{Body: sessions})
+}
+
+func (a *AuthV1) sessionDeleteHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Revoke session Handler").
+			SetSummary("This handler for revoking session of user by user_id and session_id").
+			AddInPathParameter("id", "User id", reflect.Int64).
+			AddInPathParameter("session_id", "Session id", reflect.String).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "OK", httpsrv.OkResult()).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err)).
+			AddResponse(http.StatusNotAcceptable, "DATA NOT DELETED", httpsrv.NotDeleted(err))
+
+		return nil
 	}
-	return ec.OK(TokenDataResult{Body: intropsectResullt})
+	// Main code of handler
+	log := ec.GetLog()
+
+	userID, err := ec.GetInt64Param("id")
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, id %s", ec.Param("id"))
+		return ec.BadRequest(err)
+	}
+
+	sessionID := ec.Param("session_id")
+
+	err = a.RevokeSession(int(userID), sessionID)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			log.Err(err).Msgf("NOT FOUND, id %d, session %s", userID, sessionID)
+			return ec.NotFound(err)
+		}
+
+		log.Err(err).Msgf("DATA NOT DELETED, id %d, session %s", userID, sessionID)
+		return ec.NotDeleted(err)
+	}
+
+	return ec.OkResult()
+}
+
+func (a *AuthV1) jwksGetHandler(ec echo.Context) (err error) {
+	// Main code of handler
+	log := ec.GetLog()
+
+	strategy, err := jwt.Get(a.ctx)
+	if err != nil {
+		log.Err(err).Msg("failed to get jwt strategy")
+		return ec.InternalServerError(err)
+	}
+
+	return ec.OK(strategy.JWKS())
+}
+
+func (a *AuthV1) csrfGetHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("CSRF Token Handler").
+			SetSummary("This handler returns CSRF token for the header of state-changing requests. "+
+				"Without the session a new login token is returned and set in CSRF cookie").
+			AddResponse(http.StatusOK, "CSRF token", &CSRFTokenResult{Body: models.CSRFToken{}}).
+			AddResponse(http.StatusInternalServerError, "INTERNAL SERVER ERROR", httpsrv.InternalServerError(err))
+
+		return nil
+	}
+	// Main code of handler
+	log := ec.GetLog()
+
+	if cookie, err := ec.Cookie(SessionCookie); err == nil && cookie.Value != "" {
+		return ec.OK(CSRFTokenResult{Body: &models.CSRFToken{Token: a.csrf.Token(cookie.Value)}})
+	}
+
+	token, err := a.csrf.NewLoginToken()
+	if err != nil {
+		log.Err(err).Msg("failed to generate CSRF token")
+		return ec.InternalServerError(err)
+	}
+
+	a.SetLoginCSRFCookie(ec, token)
+
+	return ec.OK(CSRFTokenResult{Body: &models.CSRFToken{Token: token}})
+}
+
+func (a *AuthV1) logoutPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Logout Handler").
+			SetSummary("This handler revokes the token from session cookie and clears the cookie").
+			AddResponse(http.StatusOK, "OK", httpsrv.OkResult()).
+			AddResponse(http.StatusConflict, "DATA NOT DELETED", httpsrv.NotDeleted(err))
+
+		return nil
+	}
+	// Main code of handler
+	log := ec.GetLog()
+
+	// Logout without session is not an error, the cookie is cleared anyway
+	if cookie, err := ec.Cookie(SessionCookie); err == nil {
+		if err = a.RevokeToken(cookie.Value); err != nil {
+			log.Err(err).Msg("revoking token failed")
+			return ec.NotDeleted(err)
+		}
+	}
+
+	a.ClearSessionCookies(ec)
+
+	return ec.OkResult()
 }
@@ -0,0 +1,141 @@
+package authv1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+	garageEcho "github.com/soldatov-s/go-garage/providers/httpsrv/echo"
+	echoSwagger "github.com/soldatov-s/go-swagger/echo-swagger"
+)
+
+// callerKey is a key of the caller's session in echo context
+const callerKey = "authv1.caller"
+
+// Check checks that the session of the caller is allowed
+type Check func(session *models.Token) error
+
+// MinRole allows callers with the role not lower than minRole
+func MinRole(minRole goGarageAuthTypes.Role) Check {
+	return func(session *models.Token) error {
+		if session.Role < minRole {
+			return fmt.Errorf("%w: subject %s has role %s", ErrRoleNotAllowed, session.Subject, session.Role)
+		}
+
+		return nil
+	}
+}
+
+// HasPermission allows callers with the permission granted by any of the assigned roles
+func HasPermission(permission string) Check {
+	return func(session *models.Token) error {
+		if !session.HasPermission(permission) {
+			return fmt.Errorf("%w: subject %s has no permission %s", ErrPermissionDenied, session.Subject, permission)
+		}
+
+		return nil
+	}
+}
+
+// Authenticated allows any authenticated caller
+func Authenticated() Check {
+	return func(session *models.Token) error {
+		return nil
+	}
+}
+
+// IsForbidden checks that the error is caused by the lack of rights of the caller
+func IsForbidden(err error) bool {
+	return errors.Is(err, ErrRoleNotAllowed) || errors.Is(err, ErrPermissionDenied) || errors.Is(err, ErrUserRestricted)
+}
+
+// RequireRole returns middleware which allows the route only to callers with the role not lower than minRole,
+// see Require
+func (a *AuthV1) RequireRole(minRole goGarageAuthTypes.Role, ownerParam string) echo.MiddlewareFunc {
+	return a.Require(MinRole(minRole), ownerParam)
+}
+
+// RequirePermission returns middleware which allows the route only to callers with the permission, see Require
+func (a *AuthV1) RequirePermission(permission, ownerParam string) echo.MiddlewareFunc {
+	return a.Require(HasPermission(permission), ownerParam)
+}
+
+// Require returns middleware which authenticates the caller by the token and allows the route
+// only to callers passed the check. If ownerParam isn't empty, the caller is also allowed
+// to the record of its own, the user ID of the record is taken from the path parameter ownerParam.
+// Restricted users aren't allowed at all. The session of the caller is available by Caller.
+func (a *AuthV1) Require(check Check, ownerParam string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			// The handlers describe themselves without a caller
+			if echoSwagger.IsBuildingSwagger(ec) {
+				return next(ec)
+			}
+
+			gec := garageEcho.Context{Context: ec}
+			log := gec.GetLog()
+
+			token, err := a.getTokenFromRequest(gec)
+			if err != nil {
+				log.Debug().Msgf("UNAUTHORIZED, token isn't found, %s %s", ec.Request().Method, ec.Path())
+				return a.bearerError(gec, http.StatusUnauthorized, "", err)
+			}
+
+			session, err := a.authorize(token, check, ec.Param(ownerParam))
+			switch {
+			case err == nil:
+			case IsForbidden(err):
+				log.Debug().Msgf("FORBIDDEN, %s %s: %s", ec.Request().Method, ec.Path(), err)
+				return a.bearerError(gec, http.StatusForbidden, BearerErrorInsufficientScope, err)
+			case isInvalidToken(err):
+				log.Err(err).Msg("UNAUTHORIZED")
+				return a.bearerError(gec, http.StatusUnauthorized, BearerErrorInvalidToken, err)
+			default:
+				log.Err(err).Msg("introspection failed")
+				return gec.InternalServerError(err)
+			}
+
+			ec.Set(callerKey, session)
+
+			return next(ec)
+		}
+	}
+}
+
+// authorize introspects the token and checks that the subject passes the check
+// or is the owner of the record, ownerID is empty if the owner isn't allowed
+func (a *AuthV1) authorize(token string, check Check, ownerID string) (*models.Token, error) {
+	session, err := a.Introspect(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Status == goGarageAuthTypes.Restricted {
+		return nil, fmt.Errorf("%w: subject %s", ErrUserRestricted, session.Subject)
+	}
+
+	if ownerID != "" && ownerID == session.Subject {
+		return session, nil
+	}
+
+	if err := check(session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// Caller returns the session of the caller authenticated by Require
+func Caller(ec echo.Context) (*models.Token, bool) {
+	session, ok := ec.Get(callerKey).(*models.Token)
+	return session, ok
+}
+
+// CallerHasPermission checks that the caller authenticated by Require has the permission
+func CallerHasPermission(ec echo.Context, permission string) bool {
+	session, ok := Caller(ec)
+	return ok && session.HasPermission(permission)
+}
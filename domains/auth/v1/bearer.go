@@ -0,0 +1,57 @@
+package authv1
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/soldatov-s/go-garage/providers/httpsrv/echo"
+)
+
+const (
+	headerAuthorization   = "Authorization"
+	headerWWWAuthenticate = "WWW-Authenticate"
+	bearerPrefix          = "bearer "
+	bearerRealm           = "go-garage-auth"
+)
+
+// Bearer error codes by RFC 6750 Section 3.1
+const (
+	BearerErrorInvalidToken      = "invalid_token"
+	BearerErrorInsufficientScope = "insufficient_scope"
+)
+
+// bearerToken returns the token from Authorization header, it is empty if there is no Bearer token
+func bearerToken(ec echo.Context) string {
+	return parseBearer(ec.Request().Header.Get(headerAuthorization))
+}
+
+// parseBearer returns the token from value of Authorization header
+func parseBearer(header string) string {
+	if len(header) <= len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return ""
+	}
+
+	return strings.TrimSpace(header[len(bearerPrefix):])
+}
+
+// bearerChallenge returns value of WWW-Authenticate header by RFC 6750 Section 3,
+// errCode is empty if the request has no token
+func bearerChallenge(errCode string) string {
+	challenge := `Bearer realm="` + bearerRealm + `"`
+	if errCode != "" {
+		challenge += `, error="` + errCode + `"`
+	}
+
+	return challenge
+}
+
+// bearerError answers with the error and the challenge
+func (a *AuthV1) bearerError(ec echo.Context, code int, errCode string, err error) error {
+	ec.Response().Header().Set(headerWWWAuthenticate, bearerChallenge(errCode))
+
+	if code == http.StatusForbidden {
+		return ec.Forbidden(err)
+	}
+
+	return ec.Unauthorized(err)
+}
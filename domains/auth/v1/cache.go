@@ -0,0 +1,296 @@
+package authv1
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/soldatov-s/go-garage-auth/models"
+	"github.com/soldatov-s/go-garage/providers/stats"
+)
+
+type cacheKey [sha256.Size]byte
+
+type cacheEntry struct {
+	key       cacheKey
+	session   *models.Token
+	err       error
+	expiredAt time.Time
+}
+
+// introspectionCache is a bounded LRU cache of introspection results. Positive results are indexed
+// by signature and subject of the token, so they can be invalidated on revocation.
+// Negative results are never invalidated, they expire after the negative TTL.
+// The cache is disabled until it is enabled by the listener of invalidations.
+type introspectionCache struct {
+	// accessed atomically, must be 64-bit aligned
+	hits   uint64
+	misses uint64
+
+	mu          sync.Mutex
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	order       *list.List
+	items       map[cacheKey]*list.Element
+	bySignature map[string]map[cacheKey]struct{}
+	bySubject   map[string]map[cacheKey]struct{}
+	// epoch is incremented on every invalidation
+	epoch uint64
+	// enabled is false while revocations of other replicas can't be received
+	enabled bool
+}
+
+func newIntrospectionCache(size int, ttl, negativeTTL time.Duration) *introspectionCache {
+	return &introspectionCache{
+		size:        size,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		order:       list.New(),
+		items:       make(map[cacheKey]*list.Element),
+		bySignature: make(map[string]map[cacheKey]struct{}),
+		bySubject:   make(map[string]map[cacheKey]struct{}),
+	}
+}
+
+// Get returns the cached result of introspection of the token, ok is false if the result isn't cached
+func (c *introspectionCache) Get(token string) (session *models.Token, ok bool, err error) {
+	key := sha256.Sum256([]byte(token))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok || !c.enabled {
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false, nil
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expiredAt) {
+		c.remove(el)
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(el)
+	atomic.AddUint64(&c.hits, 1)
+
+	if entry.err != nil {
+		return nil, true, entry.err
+	}
+
+	// Callers must not modify the cached session
+	cached := *entry.session
+
+	return &cached, true, nil
+}
+
+// Epoch returns the current epoch, it should be taken before introspection and passed to Set
+func (c *introspectionCache) Epoch() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.epoch
+}
+
+// Set caches the result of introspection of the token. The positive result is cached
+// not longer than the token lives. The result is dropped if there was an invalidation
+// since the epoch, because it might be read before the token was revoked.
+func (c *introspectionCache) Set(token string, session *models.Token, err error, epoch uint64) {
+	entry := &cacheEntry{
+		key: sha256.Sum256([]byte(token)),
+		err: err,
+	}
+
+	if err != nil {
+		entry.expiredAt = time.Now().Add(c.negativeTTL)
+	} else {
+		cached := *session
+		entry.session = &cached
+
+		entry.expiredAt = time.Now().Add(c.ttl)
+		if session.ExpiredAt.Time.Before(entry.expiredAt) {
+			entry.expiredAt = session.ExpiredAt.Time
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.epoch != epoch || !c.enabled {
+		return
+	}
+
+	if el, ok := c.items[entry.key]; ok {
+		c.remove(el)
+	}
+
+	c.items[entry.key] = c.order.PushFront(entry)
+
+	if entry.session != nil {
+		addToIndex(c.bySignature, entry.session.Signature, entry.key)
+		addToIndex(c.bySubject, entry.session.Subject, entry.key)
+	}
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// InvalidateSignature removes the results of the token with the signature
+func (c *introspectionCache) InvalidateSignature(signature string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.invalidate(c.bySignature[signature])
+}
+
+// InvalidateSubject removes the results of all tokens of the subject
+func (c *introspectionCache) InvalidateSubject(subject string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.invalidate(c.bySubject[subject])
+}
+
+// Purge removes all results
+func (c *introspectionCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.purge()
+}
+
+// SetEnabled enables or disables the cache, the disabled cache is purged and doesn't keep results
+func (c *introspectionCache) SetEnabled(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.enabled = enabled
+	if !enabled {
+		c.purge()
+	}
+}
+
+// Enabled checks that the cache is enabled
+func (c *introspectionCache) Enabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.enabled
+}
+
+func (c *introspectionCache) purge() {
+	c.epoch++
+	c.order.Init()
+	c.items = make(map[cacheKey]*list.Element)
+	c.bySignature = make(map[string]map[cacheKey]struct{})
+	c.bySubject = make(map[string]map[cacheKey]struct{})
+}
+
+func (c *introspectionCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *introspectionCache) invalidate(keys map[cacheKey]struct{}) {
+	c.epoch++
+
+	for key := range keys {
+		if el, ok := c.items[key]; ok {
+			c.remove(el)
+		}
+	}
+}
+
+func (c *introspectionCache) remove(el *list.Element) {
+	entry := c.order.Remove(el).(*cacheEntry)
+	delete(c.items, entry.key)
+
+	if entry.session != nil {
+		removeFromIndex(c.bySignature, entry.session.Signature, entry.key)
+		removeFromIndex(c.bySubject, entry.session.Subject, entry.key)
+	}
+}
+
+func addToIndex(index map[string]map[cacheKey]struct{}, value string, key cacheKey) {
+	keys, ok := index[value]
+	if !ok {
+		keys = make(map[cacheKey]struct{})
+		index[value] = keys
+	}
+
+	keys[key] = struct{}{}
+}
+
+func removeFromIndex(index map[string]map[cacheKey]struct{}, value string, key cacheKey) {
+	keys := index[value]
+	delete(keys, key)
+
+	if len(keys) == 0 {
+		delete(index, value)
+	}
+}
+
+// Metrics returns the metrics of the cache for the stats provider
+func (c *introspectionCache) Metrics(prefix string) stats.MapMetricsOptions {
+	metrics := make(stats.MapMetricsOptions)
+
+	metrics[prefix+"_introspection_cache_hits_total"] = counterMetric(prefix+"_introspection_cache_hits_total",
+		prefix+" introspection cache hits", &c.hits)
+	metrics[prefix+"_introspection_cache_misses_total"] = counterMetric(prefix+"_introspection_cache_misses_total",
+		prefix+" introspection cache misses", &c.misses)
+
+	metrics[prefix+"_introspection_cache_size"] = &stats.MetricOptions{
+		Metric: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: prefix + "_introspection_cache_size",
+				Help: prefix + " introspection cache size",
+			}),
+		MetricFunc: func(m interface{}) {
+			(m.(prometheus.Gauge)).Set(float64(c.Len()))
+		},
+	}
+
+	metrics[prefix+"_introspection_cache_enabled"] = &stats.MetricOptions{
+		Metric: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: prefix + "_introspection_cache_enabled",
+				Help: prefix + " introspection cache is enabled, 0 while the listener of token invalidations is down",
+			}),
+		MetricFunc: func(m interface{}) {
+			enabled := 0.0
+			if c.Enabled() {
+				enabled = 1
+			}
+
+			(m.(prometheus.Gauge)).Set(enabled)
+		},
+	}
+
+	return metrics
+}
+
+// counterMetric returns the counter which follows the value, the stats provider polls it periodically
+func counterMetric(name, help string, value *uint64) *stats.MetricOptions {
+	var reported uint64
+
+	return &stats.MetricOptions{
+		Metric: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: name,
+				Help: help,
+			}),
+		MetricFunc: func(m interface{}) {
+			current := atomic.LoadUint64(value)
+			(m.(prometheus.Counter)).Add(float64(current - reported))
+			reported = current
+		},
+	}
+}
@@ -0,0 +1,37 @@
+package authv1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/soldatov-s/go-garage-auth/models"
+)
+
+func TestIntrospectionCacheEnabled(t *testing.T) {
+	c := newIntrospectionCache(10, time.Minute, time.Second)
+	session := &models.Token{Subject: "1", Signature: "signature"}
+	session.ExpiredAt.Time = time.Now().Add(time.Hour)
+
+	// The results aren't cached until the listener of invalidations is connected
+	c.Set("token", session, nil, c.Epoch())
+	if _, ok, _ := c.Get("token"); ok {
+		t.Error("disabled: expected result not to be cached")
+	}
+
+	c.SetEnabled(true)
+	c.Set("token", session, nil, c.Epoch())
+	if _, ok, _ := c.Get("token"); !ok {
+		t.Error("enabled: expected result to be cached")
+	}
+
+	// The revocations of other replicas are lost while the listener is disconnected
+	c.SetEnabled(false)
+	if c.Len() != 0 {
+		t.Errorf("disconnected: expected purged cache, got %d results", c.Len())
+	}
+
+	c.SetEnabled(true)
+	if _, ok, _ := c.Get("token"); ok {
+		t.Error("reconnected: expected result cached before disconnection to be dropped")
+	}
+}
@@ -0,0 +1,66 @@
+package authv1
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/soldatov-s/go-garage/providers/httpsrv/echo"
+)
+
+func parseSameSite(sameSite string) (http.SameSite, error) {
+	switch strings.ToLower(sameSite) {
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return 0, ErrInvalidSameSite
+	}
+}
+
+// NewSessionCookie returns the session cookie with the token, it isn't accessible from JavaScript
+// and is sent only over HTTPS
+func (a *AuthV1) NewSessionCookie(token string, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Domain:   a.cfg.SessionCookie.Domain,
+		Path:     a.cfg.SessionCookie.Path,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: a.sameSite,
+	}
+}
+
+// ExpiredSessionCookie returns the cookie which removes the session cookie from the browser
+func (a *AuthV1) ExpiredSessionCookie() *http.Cookie {
+	cookie := a.NewSessionCookie("", time.Unix(0, 0))
+	cookie.MaxAge = -1
+
+	return cookie
+}
+
+// SetSessionCookies sets the session cookie with the token and the CSRF cookie of the session
+func (a *AuthV1) SetSessionCookies(ec echo.Context, token string, expires time.Time) {
+	cookie := a.NewSessionCookie(token, expires)
+
+	ec.SetCookie(cookie)
+	ec.SetCookie(a.csrf.Cookie(cookie))
+}
+
+// SetLoginCSRFCookie sets the CSRF cookie with the login token, it is used before the session is created
+func (a *AuthV1) SetLoginCSRFCookie(ec echo.Context, token string) {
+	ec.SetCookie(a.csrf.LoginCookie(a.NewSessionCookie("", time.Time{}), token))
+}
+
+// ClearSessionCookies removes the session cookie and the CSRF cookie from the browser
+func (a *AuthV1) ClearSessionCookies(ec echo.Context) {
+	cookie := a.ExpiredSessionCookie()
+
+	ec.SetCookie(cookie)
+	ec.SetCookie(a.csrf.Cookie(cookie))
+}
@@ -0,0 +1,22 @@
+package authv1
+
+import (
+	"errors"
+)
+
+var (
+	ErrEmptyToken          = errors.New("token is required")
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrTokenExpired        = errors.New("token has expired")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+	ErrInvalidClient       = errors.New("invalid client")
+	ErrInvalidClientConfig = errors.New("invalid oauth2 client, expected format client_id:client_secret")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token has expired")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used, token family revoked")
+	ErrRoleNotAllowed      = errors.New("role is not allowed")
+	ErrPermissionDenied    = errors.New("permission is not granted")
+	ErrUserRestricted      = errors.New("user is restricted")
+	ErrInvalidSameSite     = errors.New("invalid SameSite of session cookie, expected strict, lax or none")
+)
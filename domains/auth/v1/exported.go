@@ -2,13 +2,21 @@ package authv1
 
 import (
 	"context"
+	"net/http"
 
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	"github.com/rs/zerolog"
+	apiv1 "github.com/soldatov-s/go-garage-auth/api/v1"
 	"github.com/soldatov-s/go-garage-auth/internal/cfg"
+	"github.com/soldatov-s/go-garage-auth/internal/csrf"
+	"github.com/soldatov-s/go-garage-auth/internal/grpcsrv"
+	"github.com/soldatov-s/go-garage-auth/internal/jwt"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
 	"github.com/soldatov-s/go-garage/domains"
 	"github.com/soldatov-s/go-garage/providers/db/pq"
 	"github.com/soldatov-s/go-garage/providers/httpsrv/echo"
 	"github.com/soldatov-s/go-garage/providers/logger"
+	"github.com/soldatov-s/go-garage/providers/stats/garage"
 )
 
 const (
@@ -23,6 +31,13 @@ type AuthV1 struct {
 	db    *pq.Enity
 	cfg   *cfg.Config
 	mutex *pq.Mutex
+	// OAuth2 clients, client_id -> client_secret
+	clients map[string]string
+	// cache of introspection results, nil if it is disabled
+	cache *introspectionCache
+	// SameSite of session cookie
+	sameSite http.SameSite
+	csrf     *csrf.CSRF
 }
 
 func Registrate(ctx context.Context) (context.Context, error) {
@@ -40,6 +55,44 @@ func Registrate(ctx context.Context) (context.Context, error) {
 		return nil, err
 	}
 
+	if a.clients, err = parseOAuth2Clients(a.cfg.OAuth2.Clients); err != nil {
+		return nil, err
+	}
+
+	if a.sameSite, err = parseSameSite(a.cfg.SessionCookie.SameSite); err != nil {
+		return nil, err
+	}
+
+	if a.csrf, err = csrf.Get(ctx); err != nil {
+		return nil, err
+	}
+
+	if cacheCfg := a.cfg.Token.IntrospectionCache; cacheCfg.Size > 0 {
+		a.cache = newIntrospectionCache(cacheCfg.Size, cacheCfg.TTL, cacheCfg.NegativeTTL)
+
+		statsEnity, err := garage.GetEnityTypeCast(ctx, cfg.StatsName)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, metric := range a.cache.Metrics(DomainName) {
+			if err := statsEnity.RegisterMetric(name, metric); err != nil {
+				return nil, err
+			}
+		}
+
+		go a.listenInvalidations()
+	}
+
+	if a.cfg.ExtAuthz.Address != "" {
+		extAuthzServer, err := grpcsrv.GetEnityTypeCast(ctx, cfg.ExtAuthz)
+		if err != nil {
+			return nil, err
+		}
+
+		authv3.RegisterAuthorizationServer(extAuthzServer.Server, &ExtAuthz{introspect: a.Introspect, log: &a.log})
+	}
+
 	go a.ClearOldTokens()
 
 	privateV1, err := echo.GetAPIVersionGroup(ctx, cfg.PrivateHTTP, cfg.V1)
@@ -51,6 +104,43 @@ func Registrate(ctx context.Context) (context.Context, error) {
 	grProtect.Use(echo.HydrationLogger(&a.log))
 	grProtect.POST("/auth/revoke", echo.Handler(a.revokePostHandler))
 	grProtect.GET("/auth/introspect", echo.Handler(a.introspectGetHandler))
+	// Reverse proxies might keep the method of the original request
+	grProtect.Any("/auth/verify", echo.Handler(a.verifyHandler))
+	grProtect.POST("/auth/refresh", echo.Handler(a.refreshPostHandler))
+	grProtect.POST("/oauth2/introspect", echo.Handler(a.oauth2IntrospectPostHandler))
+	grProtect.POST("/oauth2/revoke", echo.Handler(a.oauth2RevokePostHandler))
+	// Every user is allowed to manage the own sessions
+	sessionsReader := a.RequirePermission(goGarageAuthTypes.PermissionSessionsRead, "id")
+	sessionsWriter := a.RequirePermission(goGarageAuthTypes.PermissionSessionsWrite, "id")
+	grProtect.GET("/users/:id/sessions", echo.Handler(a.sessionsGetHandler), sessionsReader)
+	grProtect.DELETE("/users/:id/sessions", echo.Handler(a.sessionsDeleteHandler), sessionsWriter)
+	grProtect.DELETE("/users/:id/sessions/:session_id", echo.Handler(a.sessionDeleteHandler), sessionsWriter)
+
+	publicV1, err := echo.GetAPIVersionGroup(ctx, cfg.PublicHTTP, cfg.V1)
+	if err != nil {
+		return nil, err
+	}
+
+	grPublic := publicV1.Group
+	grPublic.Use(echo.HydrationLogger(&a.log))
+	grPublic.GET("/auth/csrf", echo.Handler(a.csrfGetHandler))
+	grPublic.POST("/auth/logout", echo.Handler(a.logoutPostHandler))
+
+	if a.cfg.Token.Strategy == jwt.DomainName {
+		publicEchoEnity, err := echo.GetEnityTypeCast(ctx, cfg.PublicHTTP)
+		if err != nil {
+			return nil, err
+		}
+
+		publicEchoEnity.Server.GET("/.well-known/jwks.json", echo.Handler(a.jwksGetHandler))
+	}
+
+	grpcServer, err := grpcsrv.GetEnityTypeCast(ctx, cfg.GRPC)
+	if err != nil {
+		return nil, err
+	}
+
+	apiv1.RegisterAuthServiceServer(grpcServer.Server, &GRPCServer{authV1: a})
 
 	return domains.RegistrateByName(ctx, DomainName, a), nil
 }
@@ -0,0 +1,127 @@
+package authv1
+
+import (
+	"context"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/rs/zerolog"
+	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// Envoy passes the headers in lower case
+	extAuthzAuthorizationHeader = "authorization"
+	// Keys of context_extensions of the route with role requirements, the same as query parameters of /auth/verify
+	extAuthzRoleExtension    = "role"
+	extAuthzMinRoleExtension = "min_role"
+)
+
+// ExtAuthz implements envoy.service.auth.v3.Authorization for Envoy ext_authz filter
+type ExtAuthz struct {
+	// introspect returns the session of the token, it is AuthV1.Introspect
+	introspect func(token string) (*models.Token, error)
+	log        *zerolog.Logger
+}
+
+// Check validates the bearer token of the request. The allowed request gets the headers describing
+// the session, the headers from the client with the same names are overwritten or removed.
+func (e *ExtAuthz) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	httpRequest := req.GetAttributes().GetRequest().GetHttp()
+	extensions := req.GetAttributes().GetContextExtensions()
+
+	roles, err := parseRoles(nonEmpty(extensions[extAuthzRoleExtension]))
+	if err != nil {
+		e.log.Err(err).Msgf("invalid context extension %s", extAuthzRoleExtension)
+		return deniedResponse(codes.InvalidArgument, typev3.StatusCode_InternalServerError, ""), nil
+	}
+
+	var minRole *goGarageAuthTypes.Role
+
+	if value := extensions[extAuthzMinRoleExtension]; value != "" {
+		parsed, err := parseRoles([]string{value})
+		if err != nil || len(parsed) != 1 {
+			e.log.Err(err).Msgf("invalid context extension %s", extAuthzMinRoleExtension)
+			return deniedResponse(codes.InvalidArgument, typev3.StatusCode_InternalServerError, ""), nil
+		}
+
+		minRole = &parsed[0]
+	}
+
+	token := parseBearer(httpRequest.GetHeaders()[extAuthzAuthorizationHeader])
+	if token == "" {
+		return deniedResponse(codes.Unauthenticated, typev3.StatusCode_Unauthorized, ""), nil
+	}
+
+	session, err := e.introspect(token)
+	if err != nil {
+		e.log.Debug().Err(err).Msgf("token of %s %s isn't valid", httpRequest.GetMethod(), httpRequest.GetPath())
+		return deniedResponse(codes.Unauthenticated, typev3.StatusCode_Unauthorized, BearerErrorInvalidToken), nil
+	}
+
+	if session.Status == goGarageAuthTypes.Restricted {
+		e.log.Debug().Err(ErrUserRestricted).Msgf("subject %s is denied", session.Subject)
+		return deniedResponse(codes.PermissionDenied, typev3.StatusCode_Forbidden, BearerErrorInsufficientScope), nil
+	}
+
+	if !checkRole(session.Role, roles, minRole) {
+		return deniedResponse(codes.PermissionDenied, typev3.StatusCode_Forbidden, BearerErrorInsufficientScope), nil
+	}
+
+	headers, err := authHeaders(session)
+	if err != nil {
+		e.log.Err(err).Msgf("failed to marshal meta of subject %s", session.Subject)
+		return deniedResponse(codes.Internal, typev3.StatusCode_InternalServerError, ""), nil
+	}
+
+	ok := &authv3.OkHttpResponse{}
+
+	for _, name := range []string{HeaderAuthSubject, HeaderAuthRole, HeaderAuthMeta} {
+		value, found := headers[name]
+		if !found {
+			ok.HeadersToRemove = append(ok.HeadersToRemove, name)
+			continue
+		}
+
+		ok.Headers = append(ok.Headers, &corev3.HeaderValueOption{
+			Header: &corev3.HeaderValue{Key: name, Value: value},
+			Append: wrapperspb.Bool(false),
+		})
+	}
+
+	return &authv3.CheckResponse{
+		Status:       &rpcstatus.Status{Code: int32(codes.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: ok},
+	}, nil
+}
+
+// deniedResponse returns the denied response, 401 and 403 have the bearer challenge with errCode
+func deniedResponse(code codes.Code, httpCode typev3.StatusCode, errCode string) *authv3.CheckResponse {
+	denied := &authv3.DeniedHttpResponse{
+		Status: &typev3.HttpStatus{Code: httpCode},
+	}
+
+	if httpCode == typev3.StatusCode_Unauthorized || httpCode == typev3.StatusCode_Forbidden {
+		denied.Headers = []*corev3.HeaderValueOption{{
+			Header: &corev3.HeaderValue{Key: headerWWWAuthenticate, Value: bearerChallenge(errCode)},
+		}}
+	}
+
+	return &authv3.CheckResponse{
+		Status:       &rpcstatus.Status{Code: int32(code)},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{DeniedResponse: denied},
+	}
+}
+
+func nonEmpty(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	return []string{value}
+}
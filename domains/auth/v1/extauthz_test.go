@@ -0,0 +1,167 @@
+package authv1
+
+import (
+	"context"
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/rs/zerolog"
+	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+	"github.com/soldatov-s/go-garage/types"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	testExtAuthzToken           = "valid-token"
+	testExtAuthzRestrictedToken = "restricted-token"
+)
+
+func testExtAuthz() *ExtAuthz {
+	log := zerolog.Nop()
+
+	return &ExtAuthz{
+		introspect: func(token string) (*models.Token, error) {
+			session := &models.Token{
+				Subject: "1",
+				Role:    goGarageAuthTypes.UserL1,
+				Status:  goGarageAuthTypes.Active,
+				Meta:    types.NullMeta{Map: map[string]interface{}{"tenant": "acme"}, Valid: true},
+			}
+
+			switch token {
+			case testExtAuthzToken:
+				return session, nil
+			case testExtAuthzRestrictedToken:
+				session.Status = goGarageAuthTypes.Restricted
+				return session, nil
+			default:
+				return nil, ErrInvalidToken
+			}
+		},
+		log: &log,
+	}
+}
+
+func testCheckRequest(headers, extensions map[string]string) *authv3.CheckRequest {
+	return &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{
+					Method:  "GET",
+					Path:    "/documents",
+					Headers: headers,
+				},
+			},
+			ContextExtensions: extensions,
+		},
+	}
+}
+
+func TestExtAuthzCheckDenied(t *testing.T) {
+	e := testExtAuthz()
+
+	for name, tc := range map[string]struct {
+		req      *authv3.CheckRequest
+		code     codes.Code
+		httpCode typev3.StatusCode
+	}{
+		"missing token": {
+			req:      testCheckRequest(map[string]string{}, nil),
+			code:     codes.Unauthenticated,
+			httpCode: typev3.StatusCode_Unauthorized,
+		},
+		"invalid token": {
+			req:      testCheckRequest(map[string]string{"authorization": "Bearer invalid-token"}, nil),
+			code:     codes.Unauthenticated,
+			httpCode: typev3.StatusCode_Unauthorized,
+		},
+		"role denied": {
+			req: testCheckRequest(map[string]string{"authorization": "Bearer " + testExtAuthzToken},
+				map[string]string{"min_role": "SUPERUSER"}),
+			code:     codes.PermissionDenied,
+			httpCode: typev3.StatusCode_Forbidden,
+		},
+		"restricted user": {
+			req:      testCheckRequest(map[string]string{"authorization": "Bearer " + testExtAuthzRestrictedToken}, nil),
+			code:     codes.PermissionDenied,
+			httpCode: typev3.StatusCode_Forbidden,
+		},
+	} {
+		resp, err := e.Check(context.Background(), tc.req)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", name, err)
+			continue
+		}
+
+		if code := codes.Code(resp.GetStatus().GetCode()); code != tc.code {
+			t.Errorf("%s: expected code %v, got %v", name, tc.code, code)
+		}
+
+		denied := resp.GetDeniedResponse()
+		if denied == nil {
+			t.Errorf("%s: expected denied response", name)
+			continue
+		}
+
+		if httpCode := denied.GetStatus().GetCode(); httpCode != tc.httpCode {
+			t.Errorf("%s: expected http code %v, got %v", name, tc.httpCode, httpCode)
+		}
+
+		if !hasHeader(denied.GetHeaders(), headerWWWAuthenticate) {
+			t.Errorf("%s: expected %s header", name, headerWWWAuthenticate)
+		}
+	}
+}
+
+func TestExtAuthzCheckAllowed(t *testing.T) {
+	e := testExtAuthz()
+
+	req := testCheckRequest(map[string]string{"authorization": "Bearer " + testExtAuthzToken},
+		map[string]string{"role": "USER_L1,USER_L2"})
+
+	resp, err := e.Check(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if code := codes.Code(resp.GetStatus().GetCode()); code != codes.OK {
+		t.Fatalf("expected code %v, got %v", codes.OK, code)
+	}
+
+	ok := resp.GetOkResponse()
+	if ok == nil {
+		t.Fatal("expected ok response")
+	}
+
+	headers := map[string]string{}
+	for _, header := range ok.GetHeaders() {
+		headers[header.GetHeader().GetKey()] = header.GetHeader().GetValue()
+
+		if header.GetAppend().GetValue() {
+			t.Errorf("expected %s header to overwrite the header of the client", header.GetHeader().GetKey())
+		}
+	}
+
+	for name, expected := range map[string]string{
+		HeaderAuthSubject: "1",
+		HeaderAuthRole:    "USER_L1",
+		HeaderAuthMeta:    `{"tenant":"acme"}`,
+	} {
+		if headers[name] != expected {
+			t.Errorf("%s: expected %q, got %q", name, expected, headers[name])
+		}
+	}
+}
+
+func hasHeader(headers []*corev3.HeaderValueOption, name string) bool {
+	for _, header := range headers {
+		if header.GetHeader().GetKey() == name {
+			return true
+		}
+	}
+
+	return false
+}
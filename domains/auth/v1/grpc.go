@@ -0,0 +1,130 @@
+package authv1
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	apiv1 "github.com/soldatov-s/go-garage-auth/api/v1"
+	"github.com/soldatov-s/go-garage-auth/models"
+	"github.com/soldatov-s/go-garage/providers/db"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// GRPCServer implements AuthService of gRPC API, it mirrors the private REST API
+type GRPCServer struct {
+	apiv1.UnimplementedAuthServiceServer
+	authV1 *AuthV1
+}
+
+func (s *GRPCServer) Introspect(ctx context.Context, req *apiv1.IntrospectRequest) (*apiv1.IntrospectResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, ErrEmptyToken.Error())
+	}
+
+	session, err := s.authV1.Introspect(req.GetToken())
+	if err != nil {
+		if !isInvalidToken(err) {
+			return nil, grpcError(err)
+		}
+
+		return &apiv1.IntrospectResponse{}, nil
+	}
+
+	resp := &apiv1.IntrospectResponse{
+		Active:      true,
+		Subject:     session.Subject,
+		Role:        session.Role.String(),
+		Permissions: session.Permissions,
+		ExpiredAt:   session.ExpiredAt.Time.Unix(),
+	}
+
+	if session.Meta.Valid {
+		if resp.Meta, err = structpb.NewStruct(session.Meta.Map); err != nil {
+			return nil, grpcError(err)
+		}
+	}
+
+	return resp, nil
+}
+
+func (s *GRPCServer) Revoke(ctx context.Context, req *apiv1.RevokeRequest) (*emptypb.Empty, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, ErrEmptyToken.Error())
+	}
+
+	if err := s.authV1.RevokeToken(req.GetToken()); err != nil {
+		s.authV1.log.Err(err).Msg("revoking token failed")
+		return nil, grpcError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// AuthorizeGRPC authenticates the caller of gRPC call by the bearer token in authorization metadata
+// and checks it like Require, ownerID is a user ID of the record or empty if the owner isn't allowed
+func (a *AuthV1) AuthorizeGRPC(ctx context.Context, check Check, ownerID string) (*models.Token, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var token string
+	if values := md.Get(headerAuthorization); len(values) > 0 {
+		token = parseBearer(values[0])
+	}
+
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, ErrEmptyToken.Error())
+	}
+
+	session, err := a.authorize(token, check, ownerID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return session, nil
+}
+
+// grpcError maps errors of the repository to gRPC status codes
+func grpcError(err error) error {
+	code := codes.Internal
+
+	switch {
+	case isInvalidToken(err):
+		code = codes.Unauthenticated
+	case IsForbidden(err):
+		code = codes.PermissionDenied
+	case errors.Is(err, db.ErrDBConnNotEstablished):
+		code = codes.Unavailable
+	}
+
+	return status.Error(code, err.Error())
+}
+
+// SessionClientFromContext returns the description of the client for the new session created by gRPC call,
+// the address of the client is taken from x-real-ip or x-forwarded-for metadata if the call is proxied
+func SessionClientFromContext(ctx context.Context) *models.SessionClient {
+	client := &models.SessionClient{}
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if values := md.Get("user-agent"); len(values) > 0 {
+		client.UserAgent = values[0]
+	}
+
+	if values := md.Get("x-real-ip"); len(values) > 0 {
+		client.ClientIP = values[0]
+	} else if values := md.Get("x-forwarded-for"); len(values) > 0 {
+		client.ClientIP = strings.TrimSpace(strings.Split(values[0], ",")[0])
+	} else if p, ok := peer.FromContext(ctx); ok {
+		client.ClientIP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(client.ClientIP); err == nil {
+			client.ClientIP = host
+		}
+	}
+
+	return client
+}
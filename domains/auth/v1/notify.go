@@ -0,0 +1,64 @@
+package authv1
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// tokenInvalidationChannel is notified with the signature of every deleted unexpired token,
+	// see the trigger in migration 10_token_invalidation.sql
+	tokenInvalidationChannel = "token_invalidation"
+
+	listenerMinReconnectInterval = time.Second
+	listenerMaxReconnectInterval = time.Minute
+)
+
+// listenInvalidations invalidates cached introspection results of tokens revoked on any replica.
+// The cache is enabled only while the listener is connected, otherwise revoked tokens would stay cached.
+func (a *AuthV1) listenInvalidations() {
+	listener := pq.NewListener(a.cfg.DB.ComposeDSN(), listenerMinReconnectInterval, listenerMaxReconnectInterval,
+		func(event pq.ListenerEventType, err error) {
+			if event == pq.ListenerEventDisconnected {
+				a.cache.SetEnabled(false)
+				a.log.Warn().Msg("token invalidation listener is disconnected, introspection cache is disabled")
+			}
+
+			if err != nil {
+				a.log.Err(err).Msg("token invalidation listener failed")
+			}
+		})
+
+	// Listen waits for the connection, so it fails only if the server refuses the channel
+	for interval := listenerMinReconnectInterval; ; {
+		err := listener.Listen(tokenInvalidationChannel)
+		if err == nil {
+			break
+		}
+
+		a.log.Err(err).Msgf("failed to listen %s, retry after %s", tokenInvalidationChannel, interval)
+
+		time.Sleep(interval)
+
+		if interval *= 2; interval > listenerMaxReconnectInterval {
+			interval = listenerMaxReconnectInterval
+		}
+	}
+
+	a.cache.SetEnabled(true)
+
+	for n := range listener.Notify {
+		// Notifications might be lost while the connection was re-established,
+		// the channel is listened again before the reconnection is reported
+		if n == nil {
+			a.cache.Purge()
+			a.cache.SetEnabled(true)
+			a.log.Info().Msg("token invalidation listener is reconnected, introspection cache is enabled")
+
+			continue
+		}
+
+		a.cache.InvalidateSignature(n.Extra)
+	}
+}
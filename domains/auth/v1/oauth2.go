@@ -0,0 +1,217 @@
+package authv1
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/soldatov-s/go-garage-auth/models"
+	"github.com/soldatov-s/go-garage/providers/httpsrv/echo"
+	echoSwagger "github.com/soldatov-s/go-swagger/echo-swagger"
+)
+
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+
+	TokenTypeBearer = "Bearer"
+)
+
+// OAuth2 error codes by RFC 6749 Section 5.2
+const (
+	OAuth2InvalidRequest = "invalid_request"
+	OAuth2InvalidClient  = "invalid_client"
+	OAuth2ServerError    = "server_error"
+)
+
+func parseOAuth2Clients(clients []string) (map[string]string, error) {
+	result := make(map[string]string, len(clients))
+
+	for _, item := range clients {
+		split := strings.SplitN(item, ":", 2)
+		if len(split) != 2 || split[0] == "" || split[1] == "" {
+			return nil, ErrInvalidClientConfig
+		}
+
+		result[split[0]] = split[1]
+	}
+
+	return result, nil
+}
+
+// authenticateClient checks HTTP Basic credentials of the resource server which calls OAuth2 endpoint
+func (a *AuthV1) authenticateClient(ec echo.Context) (string, error) {
+	clientID, clientSecret, ok := ec.Request().BasicAuth()
+	if !ok {
+		return "", ErrInvalidClient
+	}
+
+	secret, ok := a.clients[clientID]
+	if !ok || subtle.ConstantTimeCompare([]byte(secret), []byte(clientSecret)) != 1 {
+		return "", ErrInvalidClient
+	}
+
+	return clientID, nil
+}
+
+func (a *AuthV1) oauth2Error(ec echo.Context, code int, errCode string, err error) error {
+	if code == http.StatusUnauthorized {
+		ec.Response().Header().Set("WWW-Authenticate", `Basic realm="oauth2"`)
+	}
+
+	return ec.JSON(code, models.OAuth2Error{Error: errCode, ErrorDescription: err.Error()})
+}
+
+func (a *AuthV1) oauth2IntrospectPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetConsumes("application/x-www-form-urlencoded").
+			SetProduces("application/json").
+			SetDescription("OAuth2 Token Introspection Handler").
+			SetSummary("This handler for introspection token by RFC 7662. Requires HTTP Basic authentication of client").
+			AddInBodyParameter("request", "Token and token type hint", &models.OAuth2TokenRequest{}, true).
+			AddResponse(http.StatusOK, "OK", &models.OAuth2Introspection{}).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", &models.OAuth2Error{}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", &models.OAuth2Error{})
+
+		return nil
+	}
+	// Main code of handler
+	log := ec.GetLog()
+
+	clientID, err := a.authenticateClient(ec)
+	if err != nil {
+		log.Err(err).Msg("UNAUTHORIZED")
+		return a.oauth2Error(ec, http.StatusUnauthorized, OAuth2InvalidClient, err)
+	}
+
+	var req models.OAuth2TokenRequest
+
+	err = ec.Bind(&req)
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, client %s", clientID)
+		return a.oauth2Error(ec, http.StatusBadRequest, OAuth2InvalidRequest, err)
+	}
+
+	if !req.Validate() {
+		log.Error().Msgf("BAD REQUEST, client %s", clientID)
+		return a.oauth2Error(ec, http.StatusBadRequest, OAuth2InvalidRequest, ErrEmptyToken)
+	}
+
+	// The hint is only an optimization, if the token is not found by hint, the other type is tried
+	lookups := []func(token, clientID string) *models.OAuth2Introspection{a.introspectAccessToken, a.introspectRefreshToken}
+	if req.TokenTypeHint == TokenTypeHintRefreshToken {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+
+	for _, lookup := range lookups {
+		if result := lookup(req.Token, clientID); result != nil {
+			return ec.JSON(http.StatusOK, result)
+		}
+	}
+
+	log.Debug().Msgf("token isn't active, client %s", clientID)
+
+	return ec.JSON(http.StatusOK, &models.OAuth2Introspection{})
+}
+
+// introspectAccessToken returns the introspection of the access token, the scope is a list of
+// the permissions of the subject separated by space
+func (a *AuthV1) introspectAccessToken(token, clientID string) *models.OAuth2Introspection {
+	session, err := a.Introspect(token)
+	if err != nil {
+		return nil
+	}
+
+	result := &models.OAuth2Introspection{
+		Active:    true,
+		Subject:   session.Subject,
+		ExpiredAt: session.ExpiredAt.Time.Unix(),
+		Scope:     strings.Join(session.Permissions, " "),
+		ClientID:  clientID,
+		TokenType: TokenTypeBearer,
+	}
+
+	if session.CreatedAt.Valid {
+		result.IssuedAt = session.CreatedAt.Time.Unix()
+	}
+
+	return result
+}
+
+// introspectRefreshToken returns the introspection of the refresh token, token_type is omitted
+// because the refresh token isn't used to access resources
+func (a *AuthV1) introspectRefreshToken(token, clientID string) *models.OAuth2Introspection {
+	data, err := a.IntrospectRefreshToken(token)
+	if err != nil {
+		return nil
+	}
+
+	return &models.OAuth2Introspection{
+		Active:    true,
+		Subject:   data.Subject,
+		ExpiredAt: data.ExpiredAt.Time.Unix(),
+		IssuedAt:  data.CreatedAt.Time.Unix(),
+		ClientID:  clientID,
+	}
+}
+
+func (a *AuthV1) oauth2RevokePostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetConsumes("application/x-www-form-urlencoded").
+			SetProduces("application/json").
+			SetDescription("OAuth2 Token Revocation Handler").
+			SetSummary("This handler for revoking token by RFC 7009. Revoking of refresh token also revokes linked tokens. "+
+				"Requires HTTP Basic authentication of client").
+			AddInBodyParameter("request", "Token and token type hint", &models.OAuth2TokenRequest{}, true).
+			AddResponse(http.StatusOK, "OK", nil).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", &models.OAuth2Error{}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", &models.OAuth2Error{}).
+			AddResponse(http.StatusInternalServerError, "INTERNAL SERVER ERROR", &models.OAuth2Error{})
+
+		return nil
+	}
+	// Main code of handler
+	log := ec.GetLog()
+
+	clientID, err := a.authenticateClient(ec)
+	if err != nil {
+		log.Err(err).Msg("UNAUTHORIZED")
+		return a.oauth2Error(ec, http.StatusUnauthorized, OAuth2InvalidClient, err)
+	}
+
+	var req models.OAuth2TokenRequest
+
+	err = ec.Bind(&req)
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, client %s", clientID)
+		return a.oauth2Error(ec, http.StatusBadRequest, OAuth2InvalidRequest, err)
+	}
+
+	if !req.Validate() {
+		log.Error().Msgf("BAD REQUEST, client %s", clientID)
+		return a.oauth2Error(ec, http.StatusBadRequest, OAuth2InvalidRequest, ErrEmptyToken)
+	}
+
+	// The hint is only an optimization, the token is revoked whatever type it has.
+	// Unknown tokens are not an error, so the response doesn't tell whether the token existed.
+	revokes := []func(string) error{a.RevokeToken, a.RevokeRefreshToken}
+	if req.TokenTypeHint == TokenTypeHintRefreshToken {
+		revokes[0], revokes[1] = revokes[1], revokes[0]
+	}
+
+	for _, revoke := range revokes {
+		if err = revoke(req.Token); err != nil {
+			log.Err(err).Msgf("revoking token failed, client %s", clientID)
+			return a.oauth2Error(ec, http.StatusInternalServerError, OAuth2ServerError, err)
+		}
+	}
+
+	return ec.NoContent(http.StatusOK)
+}
@@ -1,37 +1,69 @@
 package authv1
 
 import (
+	dbsql "database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"strconv"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/soldatov-s/go-garage-auth/internal/hmac"
 	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthToken "github.com/soldatov-s/go-garage-auth/token"
 	"github.com/soldatov-s/go-garage/providers/db"
+	"github.com/soldatov-s/go-garage/types"
 	"github.com/soldatov-s/go-garage/x/sql"
 )
 
-func (a *AuthV1) CreateToken(id int) (string, error) {
-	var request models.Token
+const (
+	// entropy of the token family id
+	familyEntropy = 16
+)
 
-	strategy, err := hmac.Get(a.ctx)
+func (a *AuthV1) CreateToken(id int) (string, error) {
+	sessionID, err := newSessionID()
 	if err != nil {
 		return "", err
 	}
 
-	token, sign, err := strategy.Generate()
+	return a.createToken(&models.Token{Subject: strconv.Itoa(id), SessionID: sessionID})
+}
+
+// createToken generates a token and stores the session described by request
+func (a *AuthV1) createToken(request *models.Token) (token string, err error) {
+	strategy, err := a.strategy()
 	if err != nil {
 		return "", err
 	}
 
-	request.Signature = sign
-	request.Subject = strconv.Itoa(id)
-	request.ExpiredAt.SetTime(time.Now().Add(a.cfg.Token.HMAC.TTL))
-
 	if a.db.Conn == nil {
 		return "", db.ErrDBConnNotEstablished
 	}
 
-	_, err = sql.InsertInto(a.db.Conn, "production.token", &request)
+	claims := &models.TokenClaims{
+		Subject:   request.Subject,
+		SessionID: request.SessionID,
+		Meta:      request.Meta.Map,
+	}
+
+	// Self-contained tokens carry the role and status of the user
+	err = a.db.Conn.QueryRow("select user_role, user_status from production.user where user_id=$1", request.Subject).
+		Scan(&claims.Role, &claims.Status)
+	if err != nil {
+		return "", err
+	}
+
+	token, request.Signature, err = strategy.Generate(claims)
+	if err != nil {
+		return "", err
+	}
+
+	request.ExpiredAt.SetTime(claims.ExpiredAt)
+	request.CreatedAt.SetTime(claims.IssuedAt)
+
+	_, err = sql.InsertInto(a.db.Conn, "production.token", request)
 	if err != nil {
 		return "", err
 	}
@@ -39,6 +71,362 @@ func (a *AuthV1) CreateToken(id int) (string, error) {
 	return token, nil
 }
 
+// newSessionID returns a random opaque ID, it is used as session ID and as token family
+func newSessionID() (string, error) {
+	id, err := hmac.RandomBytes(familyEntropy)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+// CreateTokenPair creates an access token and a refresh token of a new token family.
+// The token family is also the session ID of the access tokens.
+func (a *AuthV1) CreateTokenPair(id int, client *models.SessionClient) (*models.TokenPair, error) {
+	family, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.createTokenPair(strconv.Itoa(id), family, types.NullMeta{}, client)
+}
+
+// CreateSession creates a browser session. It has no refresh token, the session cookie
+// lives as long as the access token.
+func (a *AuthV1) CreateSession(id int, client *models.SessionClient) (*models.TokenPair, error) {
+	sessionID, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	session := newSession(strconv.Itoa(id), sessionID, types.NullMeta{}, client)
+
+	token, err := a.createToken(session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		Token:     token,
+		ExpiredAt: session.ExpiredAt.Time.Unix(),
+	}, nil
+}
+
+func newSession(subject, sessionID string, meta types.NullMeta, client *models.SessionClient) *models.Token {
+	session := &models.Token{
+		Subject:   subject,
+		Meta:      meta,
+		SessionID: sessionID,
+	}
+
+	if client != nil {
+		session.ClientIP.String, session.ClientIP.Valid = client.ClientIP, client.ClientIP != ""
+		session.UserAgent.String, session.UserAgent.Valid = client.UserAgent, client.UserAgent != ""
+	}
+
+	return session
+}
+
+func (a *AuthV1) createTokenPair(subject, family string, meta types.NullMeta, client *models.SessionClient) (*models.TokenPair, error) {
+	session := newSession(subject, family, meta, client)
+
+	token, err := a.createToken(session)
+	if err != nil {
+		return nil, err
+	}
+
+	strategy, err := a.refreshStrategy()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshSign, err := strategy.Generate(&models.TokenClaims{})
+	if err != nil {
+		return nil, err
+	}
+
+	request := models.RefreshToken{
+		Signature:       refreshSign,
+		Family:          family,
+		Subject:         subject,
+		AccessSignature: session.Signature,
+		Meta:            meta,
+	}
+	request.CreatedAt.SetNow()
+	request.ExpiredAt.SetTime(time.Now().Add(a.cfg.Token.RefreshTTL))
+
+	_, err = sql.InsertInto(a.db.Conn, "production.refresh_token", &request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiredAt:    session.ExpiredAt.Time.Unix(),
+	}, nil
+}
+
+// RefreshToken exchanges the refresh token for a new token pair of the same family.
+// Every refresh token can be used only once, a reuse revokes the whole token family.
+func (a *AuthV1) RefreshToken(refreshToken string, client *models.SessionClient) (*models.TokenPair, error) {
+	strategy, err := a.refreshStrategy()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = strategy.Validate(refreshToken); err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if a.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	sign := strategy.Signature(refreshToken)
+	data := &models.RefreshToken{}
+
+	err = a.db.Conn.Get(data,
+		"UPDATE production.refresh_token SET used_at=$1 WHERE signature=$2 AND used_at IS NULL RETURNING *",
+		time.Now(), sign)
+	if errors.Is(err, dbsql.ErrNoRows) {
+		// Refresh token is unknown or has already been used
+		err = a.db.Conn.Get(data, "select * from production.refresh_token where signature=$1", sign)
+		if errors.Is(err, dbsql.ErrNoRows) {
+			return nil, ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		a.log.Warn().Msgf("refresh token reuse detected, revoking token family %s", data.Family)
+
+		if err = a.DeleteTokenFamily(data.Family); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrRefreshTokenReused
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if data.ExpiredAt.Time.Before(time.Now()) {
+		return nil, ErrRefreshTokenExpired
+	}
+
+	return a.createTokenPair(data.Subject, data.Family, data.Meta, client)
+}
+
+// RevokeToken revokes the access token. Invalid and unknown tokens are ignored.
+func (a *AuthV1) RevokeToken(token string) error {
+	strategy, err := a.strategy()
+	if err != nil {
+		return err
+	}
+
+	if err = strategy.Validate(token); err != nil {
+		return nil
+	}
+
+	return a.DeleteToken(strategy.Signature(token))
+}
+
+// RevokeRefreshToken revokes the refresh token together with its token family and
+// the access tokens issued with them. Invalid and unknown tokens are ignored.
+func (a *AuthV1) RevokeRefreshToken(refreshToken string) error {
+	strategy, err := a.refreshStrategy()
+	if err != nil {
+		return err
+	}
+
+	if err = strategy.Validate(refreshToken); err != nil {
+		return nil
+	}
+
+	if a.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	var family string
+
+	err = a.db.Conn.Get(&family, "select family from production.refresh_token where signature=$1", strategy.Signature(refreshToken))
+	if errors.Is(err, dbsql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return a.DeleteTokenFamily(family)
+}
+
+// GetSessions returns the active sessions of the user
+func (a *AuthV1) GetSessions(id int) ([]models.Session, error) {
+	if a.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	sessions := []models.Session{}
+
+	// Every refresh creates a new token in the session, the latest token describes the session
+	err := a.db.Conn.Select(&sessions,
+		`SELECT DISTINCT ON (session_id) session_id, created_at, expired_at, last_used_at, client_ip, user_agent
+		FROM production.token WHERE subject=$1 AND expired_at>$2 ORDER BY session_id, created_at DESC`,
+		strconv.Itoa(id), time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession revokes all tokens of the user session, including the refresh tokens of the session
+func (a *AuthV1) RevokeSession(id int, sessionID string) (err error) {
+	if a.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	tx, err := a.db.Conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	subject := strconv.Itoa(id)
+
+	var countRow int64
+
+	for _, query := range []string{
+		"DELETE FROM production.refresh_token WHERE subject=$1 AND family=$2",
+		"DELETE FROM production.token WHERE subject=$1 AND session_id=$2",
+	} {
+		result, err := tx.Exec(tx.Rebind(query), subject, sessionID)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		count, err := result.RowsAffected()
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		countRow += count
+	}
+
+	if countRow == 0 {
+		_ = tx.Rollback()
+		return ErrSessionNotFound
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	a.invalidateSubject(subject)
+
+	return nil
+}
+
+// RevokeAllTokens revokes all access and refresh tokens of the user. If keepToken is not empty,
+// the session of this token stays valid.
+func (a *AuthV1) RevokeAllTokens(id int, keepToken string) (err error) {
+	var keepSignature string
+
+	if keepToken != "" {
+		strategy, err := a.strategy()
+		if err != nil {
+			return err
+		}
+
+		if err = strategy.Validate(keepToken); err == nil {
+			keepSignature = strategy.Signature(keepToken)
+		}
+	}
+
+	if a.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	tx, err := a.db.Conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	subject := strconv.Itoa(id)
+
+	if err = deleteAllTokens(tx, subject, keepSignature); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	a.invalidateSubject(subject)
+
+	return nil
+}
+
+// RevokeAllTokensTx revokes all access and refresh tokens of the user in the transaction of the caller,
+// the cached introspections are invalidated by the trigger on production.token after the commit
+func (a *AuthV1) RevokeAllTokensTx(tx *sqlx.Tx, id int) error {
+	return deleteAllTokens(tx, strconv.Itoa(id), "")
+}
+
+// deleteAllTokens deletes the tokens of the subject except the session of the access token with keepSignature
+func deleteAllTokens(tx *sqlx.Tx, subject, keepSignature string) error {
+	_, err := tx.Exec(tx.Rebind(`DELETE FROM production.refresh_token WHERE subject=$1 AND family NOT IN
+		(SELECT family FROM production.refresh_token WHERE access_signature=$2)`), subject, keepSignature)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(tx.Rebind("DELETE FROM production.token WHERE subject=$1 AND signature<>$2"), subject, keepSignature)
+
+	return err
+}
+
+// DeleteTokenFamily deletes all refresh tokens of the family and the access tokens issued with them
+func (a *AuthV1) DeleteTokenFamily(family string) (err error) {
+	if a.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	tx, err := a.db.Conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	var signatures []string
+
+	err = tx.Select(&signatures, tx.Rebind(`DELETE FROM production.token WHERE signature IN
+		(SELECT access_signature FROM production.refresh_token WHERE family=$1) RETURNING signature`), family)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(tx.Rebind("DELETE FROM production.refresh_token WHERE family=$1"), family)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, signature := range signatures {
+		a.invalidateSignature(signature)
+	}
+
+	return nil
+}
+
 func (a *AuthV1) GetToken(id string) (data *models.Token, err error) {
 	data = &models.Token{}
 
@@ -56,6 +444,174 @@ func (a *AuthV1) GetToken(id string) (data *models.Token, err error) {
 	return
 }
 
+// Introspect validates the token and returns its active session. The results are cached,
+// so the last usage of the session is updated only on cache misses.
+func (a *AuthV1) Introspect(token string) (*models.Token, error) {
+	if a.cache == nil {
+		return a.introspect(token)
+	}
+
+	if session, ok, err := a.cache.Get(token); ok {
+		return session, err
+	}
+
+	epoch := a.cache.Epoch()
+
+	session, err := a.introspect(token)
+	// Errors of the storage are not cached
+	if err == nil || isInvalidToken(err) {
+		a.cache.Set(token, session, err, epoch)
+	}
+
+	return session, err
+}
+
+// isInvalidToken returns true if the error means that the token isn't active
+func isInvalidToken(err error) bool {
+	return errors.Is(err, ErrInvalidToken) ||
+		errors.Is(err, dbsql.ErrNoRows) ||
+		errors.Is(err, ErrTokenExpired) ||
+		errors.Is(err, ErrTokenRevoked)
+}
+
+func (a *AuthV1) introspect(token string) (*models.Token, error) {
+	strategy, err := a.strategy()
+	if err != nil {
+		return nil, err
+	}
+
+	var session *models.Token
+
+	if selfContained, ok := strategy.(goGarageAuthToken.SelfContainedStrategy); ok {
+		session, err = a.introspectSelfContained(selfContained, token)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if err = strategy.Validate(token); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+		}
+
+		session, err = a.GetToken(strategy.Signature(token))
+		if err != nil {
+			return nil, err
+		}
+
+		err = a.db.Conn.QueryRow("select user_role, user_status from production.user where user_id=$1", session.Subject).
+			Scan(&session.Role, &session.Status)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if session.ExpiredAt.Time.Before(time.Now().UTC()) {
+		return nil, ErrTokenExpired
+	}
+
+	if session.Permissions, err = a.GetPermissions(session.Subject); err != nil {
+		return nil, err
+	}
+
+	_, err = a.db.Conn.Exec(a.db.Conn.Rebind("UPDATE production.token SET last_used_at=$1 WHERE signature=$2"),
+		time.Now().UTC(), session.Signature)
+	if err != nil {
+		a.log.Err(err).Msgf("failed to update last usage of session %s", session.SessionID)
+	}
+
+	return session, nil
+}
+
+// GetPermissions returns the permissions granted to the user by the assigned roles
+func (a *AuthV1) GetPermissions(subject string) ([]string, error) {
+	if a.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	permissions := []string{}
+
+	err := a.db.Conn.Select(&permissions, `select distinct rp.permission_name from production.role_assignment ra
+		join production.role_permission rp on rp.role_id = ra.role_id where ra.user_id=$1
+		order by rp.permission_name`, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// introspectSelfContained validates the token without the storage, only the denylist of revoked tokens is checked
+func (a *AuthV1) introspectSelfContained(strategy goGarageAuthToken.SelfContainedStrategy, token string) (*models.Token, error) {
+	claims, err := strategy.Claims(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if a.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	var revoked bool
+
+	err = a.db.Conn.Get(&revoked, "select exists(select 1 from production.token_denylist where signature=$1)", claims.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	session := &models.Token{
+		Signature: claims.ID,
+		Subject:   claims.Subject,
+		SessionID: claims.SessionID,
+		Role:      claims.Role,
+		Status:    claims.Status,
+	}
+	session.ExpiredAt.SetTime(claims.ExpiredAt)
+	session.CreatedAt.SetTime(claims.IssuedAt)
+
+	if claims.Meta != nil {
+		session.Meta.Map = claims.Meta
+		session.Meta.Valid = true
+	}
+
+	return session, nil
+}
+
+// IntrospectRefreshToken validates the refresh token and returns it if it has not been used or expired
+func (a *AuthV1) IntrospectRefreshToken(refreshToken string) (*models.RefreshToken, error) {
+	strategy, err := a.refreshStrategy()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = strategy.Validate(refreshToken); err != nil {
+		return nil, err
+	}
+
+	if a.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	data := &models.RefreshToken{}
+
+	err = a.db.Conn.Get(data, "select * from production.refresh_token where signature=$1", strategy.Signature(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	if data.UsedAt.Valid {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if data.ExpiredAt.Time.Before(time.Now().UTC()) {
+		return nil, ErrRefreshTokenExpired
+	}
+
+	return data, nil
+}
+
 func (a *AuthV1) DeleteToken(id string) (err error) {
 	if a.db.Conn == nil {
 		return db.ErrDBConnNotEstablished
@@ -67,9 +623,56 @@ func (a *AuthV1) DeleteToken(id string) (err error) {
 		return err
 	}
 
+	a.invalidateSignature(id)
+
 	return nil
 }
 
+// invalidateSignature drops the cached introspection of the token at once,
+// other replicas are notified by the trigger on production.token
+func (a *AuthV1) invalidateSignature(signature string) {
+	if a.cache != nil {
+		a.cache.InvalidateSignature(signature)
+	}
+}
+
+// InvalidatePermissions drops the cached introspections after the change of roles,
+// subject is empty if permissions of any user might be changed. Other replicas see the change after the cache TTL.
+func (a *AuthV1) InvalidatePermissions(subject string) {
+	if a.cache == nil {
+		return
+	}
+
+	if subject == "" {
+		a.cache.Purge()
+		return
+	}
+
+	a.cache.InvalidateSubject(subject)
+}
+
+// invalidateSubject drops the cached introspections of all tokens of the subject at once,
+// other replicas are notified by the trigger on production.token
+func (a *AuthV1) invalidateSubject(subject string) {
+	if a.cache != nil {
+		a.cache.InvalidateSubject(subject)
+	}
+}
+
+// expiringTables are cleared by ClearOldTokens. Tokens and refresh tokens are kept
+// for ClearOldTokensPeriod after the expiration, other rows are deleted once they expire.
+var expiringTables = []struct {
+	name        string
+	keepExpired bool
+}{
+	{name: "production.token", keepExpired: true},
+	{name: "production.refresh_token", keepExpired: true},
+	{name: "production.token_denylist"},
+	{name: "production.password_reset"},
+	{name: "production.mfa_challenge"},
+	{name: "production.webauthn_ceremony"},
+}
+
 func (a *AuthV1) ClearOldTokens() {
 	for {
 		time.Sleep(a.cfg.Token.ClearOldTokensPeriod)
@@ -94,11 +697,16 @@ func (a *AuthV1) ClearOldTokens() {
 				}
 			}()
 
-			_, err = a.db.Conn.Exec(a.db.Conn.Rebind("DELETE FROM production.token WHERE expired_at<=$1"),
-				time.Now().Add(-a.cfg.Token.ClearOldTokensPeriod))
+			for _, table := range expiringTables {
+				expiredAt := time.Now()
+				if table.keepExpired {
+					expiredAt = expiredAt.Add(-a.cfg.Token.ClearOldTokensPeriod)
+				}
 
-			if err != nil {
-				a.log.Err(err).Msg("failed to clear old tokens")
+				_, err = a.db.Conn.Exec(a.db.Conn.Rebind("DELETE FROM "+table.name+" WHERE expired_at<=$1"), expiredAt)
+				if err != nil {
+					a.log.Err(err).Msgf("failed to clear %s", table.name)
+				}
 			}
 		}()
 	}
@@ -0,0 +1,17 @@
+package authv1
+
+import (
+	"github.com/soldatov-s/go-garage-auth/internal/hmac"
+	"github.com/soldatov-s/go-garage-auth/token"
+)
+
+// strategy returns the access token strategy selected in config
+func (a *AuthV1) strategy() (token.Strategy, error) {
+	return token.Get(a.ctx, a.cfg.Token.Strategy)
+}
+
+// refreshStrategy returns the strategy of refresh tokens. Refresh tokens are always opaque HMAC tokens,
+// they are validated by the storage.
+func (a *AuthV1) refreshStrategy() (token.Strategy, error) {
+	return token.Get(a.ctx, hmac.DomainName)
+}
@@ -0,0 +1,162 @@
+package authv1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+	"github.com/soldatov-s/go-garage/providers/httpsrv"
+	"github.com/soldatov-s/go-garage/providers/httpsrv/echo"
+	echoSwagger "github.com/soldatov-s/go-swagger/echo-swagger"
+)
+
+const (
+	HeaderAuthSubject = "X-Auth-Subject"
+	HeaderAuthRole    = "X-Auth-Role"
+	HeaderAuthMeta    = "X-Auth-Meta"
+)
+
+// parseRoles parses role names separated by comma
+func parseRoles(values []string) ([]goGarageAuthTypes.Role, error) {
+	var roles []goGarageAuthTypes.Role
+
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			role, ok := goGarageAuthTypes.StringToRole()[strings.TrimSpace(name)]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", goGarageAuthTypes.ErrBadRole, name)
+			}
+
+			roles = append(roles, role)
+		}
+	}
+
+	return roles, nil
+}
+
+// checkRole checks that the role is one of the roles and is not lower than the minimal role
+func checkRole(role goGarageAuthTypes.Role, roles []goGarageAuthTypes.Role, minRole *goGarageAuthTypes.Role) bool {
+	if minRole != nil && role < *minRole {
+		return false
+	}
+
+	if len(roles) == 0 {
+		return true
+	}
+
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// authHeaders returns the headers describing the session for upstream services
+func authHeaders(session *models.Token) (map[string]string, error) {
+	headers := map[string]string{
+		HeaderAuthSubject: session.Subject,
+		HeaderAuthRole:    session.Role.String(),
+	}
+
+	if session.Meta.Valid {
+		meta, err := json.Marshal(session.Meta.Map)
+		if err != nil {
+			return nil, err
+		}
+
+		headers[HeaderAuthMeta] = string(meta)
+	}
+
+	return headers, nil
+}
+
+// verifyHandler is a forward-auth endpoint for reverse proxies, e.g. nginx auth_request or Traefik ForwardAuth.
+// The answer is in status code and headers, the proxy copies the headers into the upstream request.
+func (a *AuthV1) verifyHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Verify token Handler").
+			SetSummary("This handler verifies the token from Authorization header, query or cookie for reverse proxies. "+
+				"The subject, role and meta are returned in X-Auth-Subject, X-Auth-Role and X-Auth-Meta headers").
+			AddInQueryParameter("token", "Verified token", reflect.String, false).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, false).
+			AddInQueryParameter("role", "Allowed roles separated by comma", reflect.String, false).
+			AddInQueryParameter("min_role", "Minimal role", reflect.String, false).
+			AddResponse(http.StatusOK, "OK", &TokenDataResult{Body: models.TokenIntrospection{}}).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err))
+
+		return nil
+	}
+	// Main code of handler
+	log := ec.GetLog()
+
+	roles, err := parseRoles(ec.QueryParams()["role"])
+	if err != nil {
+		log.Err(err).Msg("BAD REQUEST")
+		return ec.BadRequest(err)
+	}
+
+	var minRole *goGarageAuthTypes.Role
+
+	if value := ec.QueryParam("min_role"); value != "" {
+		parsed, err := parseRoles([]string{value})
+		if err != nil || len(parsed) != 1 {
+			log.Err(err).Msg("BAD REQUEST")
+			return ec.BadRequest(goGarageAuthTypes.ErrBadRole)
+		}
+
+		minRole = &parsed[0]
+	}
+
+	token, err := a.getTokenFromRequest(ec)
+	if err != nil {
+		log.Debug().Msg("UNAUTHORIZED, token isn't found")
+		return a.bearerError(ec, http.StatusUnauthorized, "", err)
+	}
+
+	session, err := a.Introspect(token)
+	if err != nil {
+		log.Err(err).Msg("UNAUTHORIZED")
+		return a.bearerError(ec, http.StatusUnauthorized, BearerErrorInvalidToken, err)
+	}
+
+	if session.Status == goGarageAuthTypes.Restricted {
+		log.Debug().Msgf("FORBIDDEN, subject %s is restricted", session.Subject)
+		return a.bearerError(ec, http.StatusForbidden, BearerErrorInsufficientScope, ErrUserRestricted)
+	}
+
+	if !checkRole(session.Role, roles, minRole) {
+		log.Debug().Msgf("FORBIDDEN, subject %s has role %s", session.Subject, session.Role)
+		return a.bearerError(ec, http.StatusForbidden, BearerErrorInsufficientScope, ErrRoleNotAllowed)
+	}
+
+	headers, err := authHeaders(session)
+	if err != nil {
+		log.Err(err).Msgf("failed to marshal meta of subject %s", session.Subject)
+		return ec.InternalServerError(err)
+	}
+
+	for name, value := range headers {
+		ec.Response().Header().Set(name, value)
+	}
+
+	return ec.OK(TokenDataResult{Body: &models.TokenIntrospection{
+		Active:      true,
+		Subject:     session.Subject,
+		Role:        session.Role.String(),
+		Permissions: session.Permissions,
+		Meta:        session.Meta.Map,
+		ExpiredAt:   session.ExpiredAt.Time.Unix(),
+	}})
+}
@@ -0,0 +1,15 @@
+package policyv1
+
+import (
+	"github.com/soldatov-s/go-garage-auth/models"
+	"github.com/soldatov-s/go-garage/providers/httpsrv"
+)
+
+// Return separated items
+type PolicyDataResult httpsrv.ResultAnsw
+
+type AuthorizeDataResult httpsrv.ResultAnsw
+
+// Return array of items
+type PoliciesDataResult httpsrv.ResultAnsw
+type ArrayOfPolicy []models.Policy
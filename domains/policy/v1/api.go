@@ -0,0 +1,292 @@
+package policyv1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/soldatov-s/go-garage-auth/models"
+	"github.com/soldatov-s/go-garage/providers/httpsrv"
+	"github.com/soldatov-s/go-garage/providers/httpsrv/echo"
+	echoSwagger "github.com/soldatov-s/go-swagger/echo-swagger"
+)
+
+func (p *PolicyV1) policiesGetHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Get Policies Handler").
+			SetSummary("This handler returns all policies").
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "Policies", &PoliciesDataResult{Body: ArrayOfPolicy{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	policies, err := p.GetPolicies()
+	if err != nil {
+		log.Err(err).Msg("NOT FOUND DATA")
+		return ec.NotFound(err)
+	}
+
+	return ec.OK(PoliciesDataResult{Body: policies})
+}
+
+func (p *PolicyV1) policyPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Create Policy Handler").
+			SetSummary("This handler creates a new policy, the condition is checked by compiling").
+			AddInBodyParameter("policy", "Policy", &models.Policy{}, true).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "Policy", &PolicyDataResult{Body: models.Policy{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusConflict, "CREATE POLICY FAILED", httpsrv.CreateFailed(err)).
+			AddResponse(http.StatusNotAcceptable, "POLICY NAME IS OCCUPIED", PolicyNameIsOccupied())
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	var data models.Policy
+
+	err = ec.Bind(&data)
+	if err != nil {
+		log.Err(err).Msg("BAD REQUEST")
+		return ec.BadRequest(err)
+	}
+
+	if !data.Validate() {
+		log.Err(err).Msgf("BAD REQUEST, policy %+v", &data)
+		return ec.BadRequest(err)
+	}
+
+	if _, err = compile(&data); err != nil {
+		log.Err(err).Msgf("BAD REQUEST, policy %+v", &data)
+		return ec.BadRequest(err)
+	}
+
+	policyData, err := p.createPolicy(&data)
+	if err != nil {
+		if errors.Is(err, ErrPolicyNameIsOccupied) {
+			log.Err(err).Msgf("POLICY NAME IS OCCUPIED %s", data.Name)
+			return ec.JSON(http.StatusNotAcceptable, PolicyNameIsOccupied())
+		}
+
+		log.Err(err).Msgf("CREATE POLICY FAILED %+v", &data)
+
+		return ec.CreateFailed(err)
+	}
+
+	return ec.OK(PolicyDataResult{Body: policyData})
+}
+
+func (p *PolicyV1) policyGetHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Get Policy Handler").
+			SetSummary("This handler returns the policy by policy_id").
+			AddInPathParameter("policy_id", "Policy id", reflect.Int64).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "Policy", &PolicyDataResult{Body: models.Policy{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	policyID, err := ec.GetInt64Param("policy_id")
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, policy id %s", ec.Param("policy_id"))
+		return ec.BadRequest(err)
+	}
+
+	policyData, err := p.GetPolicyByID(policyID)
+	if err != nil {
+		log.Err(err).Msgf("NOT FOUND, policy id %d", policyID)
+		return ec.NotFound(err)
+	}
+
+	return ec.OK(PolicyDataResult{Body: policyData})
+}
+
+func (p *PolicyV1) policyPutHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Update Policy Handler").
+			SetSummary("This handler replaces the policy by policy_id, the condition is checked by compiling").
+			AddInBodyParameter("policy", "Policy", &models.Policy{}, true).
+			AddInPathParameter("policy_id", "Policy id", reflect.Int64).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "Policy", &PolicyDataResult{Body: models.Policy{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err)).
+			AddResponse(http.StatusConflict, "DATA NOT UPDATED", httpsrv.NotUpdated(err)).
+			AddResponse(http.StatusNotAcceptable, "POLICY NAME IS OCCUPIED", PolicyNameIsOccupied())
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	policyID, err := ec.GetInt64Param("policy_id")
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, policy id %s", ec.Param("policy_id"))
+		return ec.BadRequest(err)
+	}
+
+	var data models.Policy
+
+	err = ec.Bind(&data)
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, policy id %d", policyID)
+		return ec.BadRequest(err)
+	}
+
+	if !data.Validate() {
+		log.Err(err).Msgf("BAD REQUEST, policy id %d, policy %+v", policyID, &data)
+		return ec.BadRequest(err)
+	}
+
+	if _, err = compile(&data); err != nil {
+		log.Err(err).Msgf("BAD REQUEST, policy id %d, policy %+v", policyID, &data)
+		return ec.BadRequest(err)
+	}
+
+	policyData, err := p.updatePolicy(policyID, &data)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPolicyNotFound):
+			log.Err(err).Msgf("NOT FOUND, policy id %d", policyID)
+			return ec.NotFound(err)
+		case errors.Is(err, ErrPolicyNameIsOccupied):
+			log.Err(err).Msgf("POLICY NAME IS OCCUPIED %s", data.Name)
+			return ec.JSON(http.StatusNotAcceptable, PolicyNameIsOccupied())
+		}
+
+		log.Err(err).Msgf("DATA NOT UPDATED, policy id %d", policyID)
+
+		return ec.NotUpdated(err)
+	}
+
+	return ec.OK(PolicyDataResult{Body: policyData})
+}
+
+func (p *PolicyV1) policyDeleteHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Delete Policy Handler").
+			SetSummary("This handler deletes the policy by policy_id").
+			AddInPathParameter("policy_id", "Policy id", reflect.Int64).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "OK", httpsrv.OkResult()).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err)).
+			AddResponse(http.StatusConflict, "DATA NOT DELETED", httpsrv.NotDeleted(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	policyID, err := ec.GetInt64Param("policy_id")
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, policy id %s", ec.Param("policy_id"))
+		return ec.BadRequest(err)
+	}
+
+	err = p.deletePolicy(policyID)
+	if err != nil {
+		if errors.Is(err, ErrPolicyNotFound) {
+			log.Err(err).Msgf("NOT FOUND, policy id %d", policyID)
+			return ec.NotFound(err)
+		}
+
+		log.Err(err).Msgf("DATA NOT DELETED, policy id %d", policyID)
+
+		return ec.NotDeleted(err)
+	}
+
+	return ec.OkResult()
+}
+
+func (p *PolicyV1) authorizePostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Authorize Handler").
+			SetSummary("This handler decides whether the subject may perform the action on the resource").
+			AddInBodyParameter("request", "Authorization request", &models.AuthorizeRequest{}, true).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "Decision", &AuthorizeDataResult{Body: models.AuthorizeResult{}}).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusInternalServerError, "INTERNAL SERVER ERROR", httpsrv.InternalServerError(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	var request models.AuthorizeRequest
+
+	err = ec.Bind(&request)
+	if err != nil {
+		log.Err(err).Msg("BAD REQUEST")
+		return ec.BadRequest(err)
+	}
+
+	if !request.Validate() {
+		log.Err(err).Msgf("BAD REQUEST, request %+v", &request)
+		return ec.BadRequest(err)
+	}
+
+	// Unknown subjects are denied by the decision, so the error is always internal
+	result, err := p.Authorize(&request)
+	if err != nil {
+		log.Err(err).Msgf("AUTHORIZATION FAILED, request %+v", &request)
+		return ec.InternalServerError(err)
+	}
+
+	return ec.OK(AuthorizeDataResult{Body: result})
+}
@@ -0,0 +1,18 @@
+package policyv1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/soldatov-s/go-garage/providers/httpsrv"
+)
+
+var (
+	ErrPolicyNameIsOccupied = errors.New("policy name is occupied")
+	ErrPolicyNotFound       = errors.New("policy not found")
+	ErrUnknownSubject       = errors.New("unknown subject")
+)
+
+func PolicyNameIsOccupied() httpsrv.ErrorAnsw {
+	return httpsrv.NewErrorAnsw(http.StatusNotAcceptable, "policy name is occupied", ErrPolicyNameIsOccupied)
+}
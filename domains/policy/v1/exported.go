@@ -0,0 +1,81 @@
+package policyv1
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rs/zerolog"
+	authv1 "github.com/soldatov-s/go-garage-auth/domains/auth/v1"
+	"github.com/soldatov-s/go-garage-auth/internal/cfg"
+	"github.com/soldatov-s/go-garage-auth/internal/policy"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+	"github.com/soldatov-s/go-garage/domains"
+	"github.com/soldatov-s/go-garage/providers/db/pq"
+	"github.com/soldatov-s/go-garage/providers/httpsrv/echo"
+	"github.com/soldatov-s/go-garage/providers/logger"
+)
+
+const (
+	DomainName = "policyv1"
+)
+
+type empty struct{}
+
+// PolicyV1 stores attribute-based policies and makes authorization decisions by them
+type PolicyV1 struct {
+	log zerolog.Logger
+	ctx context.Context
+	db  *pq.Enity
+	cfg *cfg.Config
+	// compiled policies by ID, a policy is compiled again after it is updated
+	mu       sync.Mutex
+	compiled map[int64]*compiledPolicy
+	// policies are the compiled policies of the version, they are loaded again only if the version changed
+	policies []*policy.Policy
+	version  policiesVersion
+}
+
+func Registrate(ctx context.Context) (context.Context, error) {
+	p := &PolicyV1{
+		ctx:      ctx,
+		log:      logger.GetPackageLogger(ctx, empty{}),
+		cfg:      cfg.Get(ctx),
+		compiled: make(map[int64]*compiledPolicy),
+	}
+	var err error
+	if p.db, err = pq.GetEnityTypeCast(ctx, cfg.DBName); err != nil {
+		return nil, err
+	}
+
+	privateV1, err := echo.GetAPIVersionGroup(ctx, cfg.PrivateHTTP, cfg.V1)
+	if err != nil {
+		return nil, err
+	}
+
+	authV1, err := authv1.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := authV1.RequirePermission(goGarageAuthTypes.PermissionPoliciesRead, "")
+	writer := authV1.RequirePermission(goGarageAuthTypes.PermissionPoliciesWrite, "")
+
+	grProtect := privateV1.Group
+	grProtect.Use(echo.HydrationLogger(&p.log))
+	grProtect.GET("/policies", echo.Handler(p.policiesGetHandler), reader)
+	grProtect.POST("/policies", echo.Handler(p.policyPostHandler), writer)
+	grProtect.GET("/policies/:policy_id", echo.Handler(p.policyGetHandler), reader)
+	grProtect.PUT("/policies/:policy_id", echo.Handler(p.policyPutHandler), writer)
+	grProtect.DELETE("/policies/:policy_id", echo.Handler(p.policyDeleteHandler), writer)
+	// Decisions are made for services like the token introspection, the service needs to read policies
+	grProtect.POST("/authorize", echo.Handler(p.authorizePostHandler), reader)
+
+	return domains.RegistrateByName(ctx, DomainName, p), nil
+}
+
+func Get(ctx context.Context) (*PolicyV1, error) {
+	if v, ok := domains.GetByName(ctx, DomainName).(*PolicyV1); ok {
+		return v, nil
+	}
+	return nil, domains.ErrInvalidDomainType
+}
@@ -0,0 +1,299 @@
+package policyv1
+
+import (
+	dbsql "database/sql"
+	"errors"
+	"strconv"
+
+	libpq "github.com/lib/pq"
+	authv1 "github.com/soldatov-s/go-garage-auth/domains/auth/v1"
+	"github.com/soldatov-s/go-garage-auth/internal/policy"
+	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+	"github.com/soldatov-s/go-garage/providers/db"
+	"github.com/soldatov-s/go-garage/types"
+)
+
+// Code of PostgreSQL unique violation error
+const uniqueViolation = "23505"
+
+// Decisions of authorization
+const (
+	decisionAllow = "allow"
+	decisionDeny  = "deny"
+)
+
+// compiledPolicy is a policy compiled at the time of the last update
+type compiledPolicy struct {
+	updatedAt types.NullTime
+	policy    *policy.Policy
+}
+
+// compile checks the effect, the actions and the condition of the policy
+func compile(p *models.Policy) (*policy.Policy, error) {
+	return policy.NewPolicy(p.Name, policy.Effect(p.Effect), p.Actions, p.Condition)
+}
+
+func (p *PolicyV1) GetPolicies() (ArrayOfPolicy, error) {
+	if p.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	policies := ArrayOfPolicy{}
+	if err := p.db.Conn.Select(&policies, "select * from production.policy order by policy_id"); err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+func (p *PolicyV1) GetPolicyByID(id int64) (*models.Policy, error) {
+	if p.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	var data models.Policy
+
+	err := p.db.Conn.Get(&data, "select * from production.policy where policy_id=$1", id)
+	if errors.Is(err, dbsql.ErrNoRows) {
+		return nil, ErrPolicyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+func (p *PolicyV1) createPolicy(data *models.Policy) (*models.Policy, error) {
+	data.CreatedAt.SetNow()
+	data.UpdatedAt.SetNow()
+
+	if p.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	err := p.db.Conn.Get(data, `INSERT INTO production.policy (policy_name, policy_description, policy_effect,
+		policy_actions, policy_condition, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *`,
+		data.Name, data.Description, data.Effect, data.Actions, data.Condition, data.CreatedAt, data.UpdatedAt)
+	if isUniqueViolation(err) {
+		return nil, ErrPolicyNameIsOccupied
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// updatePolicy replaces all fields of the policy
+func (p *PolicyV1) updatePolicy(id int64, data *models.Policy) (*models.Policy, error) {
+	data.UpdatedAt.SetNow()
+
+	if p.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	err := p.db.Conn.Get(data, `UPDATE production.policy SET policy_name=$1, policy_description=$2, policy_effect=$3,
+		policy_actions=$4, policy_condition=$5, updated_at=$6 WHERE policy_id=$7 RETURNING *`,
+		data.Name, data.Description, data.Effect, data.Actions, data.Condition, data.UpdatedAt, id)
+
+	switch {
+	case errors.Is(err, dbsql.ErrNoRows):
+		return nil, ErrPolicyNotFound
+	case isUniqueViolation(err):
+		return nil, ErrPolicyNameIsOccupied
+	case err != nil:
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (p *PolicyV1) deletePolicy(id int64) error {
+	if p.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	result, err := p.db.Conn.Exec("DELETE FROM production.policy WHERE policy_id=$1", id)
+	if err != nil {
+		return err
+	}
+
+	countRow, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if countRow == 0 {
+		return ErrPolicyNotFound
+	}
+
+	p.mu.Lock()
+	delete(p.compiled, id)
+	p.mu.Unlock()
+
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *libpq.Error
+	return errors.As(err, &pqErr) && string(pqErr.Code) == uniqueViolation
+}
+
+// policiesVersion changes on every insert, update and delete of policies
+type policiesVersion struct {
+	Count     int64          `db:"count"`
+	UpdatedAt types.NullTime `db:"updated_at"`
+}
+
+func (v policiesVersion) equal(other policiesVersion) bool {
+	return v.Count == other.Count && v.UpdatedAt.Valid == other.UpdatedAt.Valid &&
+		v.UpdatedAt.Time.Equal(other.UpdatedAt.Time)
+}
+
+// loadPolicies returns all policies. Only the version of the policies is read on every decision,
+// the policies are loaded again if the version changed and compiled again only if they were updated.
+func (p *PolicyV1) loadPolicies() ([]*policy.Policy, error) {
+	if p.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	var version policiesVersion
+
+	err := p.db.Conn.Get(&version, "select count(*) as count, max(updated_at) as updated_at from production.policy")
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	if p.policies != nil && p.version.equal(version) {
+		policies := p.policies
+		p.mu.Unlock()
+
+		return policies, nil
+	}
+	p.mu.Unlock()
+
+	policies, err := p.GetPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result := make([]*policy.Policy, 0, len(policies))
+	actual := make(map[int64]*compiledPolicy, len(policies))
+
+	for i := range policies {
+		data := &policies[i]
+
+		cached, ok := p.compiled[data.ID]
+		if !ok || !cached.updatedAt.Time.Equal(data.UpdatedAt.Time) {
+			compiled, err := compile(data)
+			if err != nil {
+				// The policies are checked on saving, so it can only be a policy changed in the database by hand
+				p.log.Error().Err(err).Msgf("failed to compile policy %s", data.Name)
+				return nil, err
+			}
+
+			cached = &compiledPolicy{updatedAt: data.UpdatedAt, policy: compiled}
+		}
+
+		actual[data.ID] = cached
+		result = append(result, cached.policy)
+	}
+
+	// The version is read before the policies, so a concurrent change only causes one more load
+	p.compiled = actual
+	p.policies = result
+	p.version = version
+
+	return result, nil
+}
+
+// loadSubject returns the attributes of the user
+func (p *PolicyV1) loadSubject(subject string) (map[string]interface{}, error) {
+	userID, err := strconv.ParseInt(subject, 10, 64)
+	if err != nil {
+		return nil, ErrUnknownSubject
+	}
+
+	if p.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	var (
+		role   goGarageAuthTypes.Role
+		status goGarageAuthTypes.Status
+		meta   types.NullMeta
+	)
+
+	err = p.db.Conn.QueryRow("select user_role, user_status, user_meta from production.user where user_id=$1", userID).
+		Scan(&role, &status, &meta)
+	if errors.Is(err, dbsql.ErrNoRows) {
+		return nil, ErrUnknownSubject
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	authV1, err := authv1.Get(p.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := authV1.GetPermissions(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"id":          subject,
+		"role":        role.String(),
+		"status":      status.String(),
+		"meta":        meta.Map,
+		"permissions": permissions,
+	}, nil
+}
+
+// Authorize makes the authorization decision by the policies
+func (p *PolicyV1) Authorize(request *models.AuthorizeRequest) (*models.AuthorizeResult, error) {
+	subject, err := p.loadSubject(request.Subject)
+	if errors.Is(err, ErrUnknownSubject) {
+		return &models.AuthorizeResult{Decision: decisionDeny, Reason: err.Error()}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	policies, err := p.loadPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	decision := policy.Evaluate(policies, &policy.Input{
+		Subject:  subject,
+		Action:   request.Action,
+		Resource: request.Resource,
+		Context:  request.Context,
+	})
+
+	result := &models.AuthorizeResult{
+		Allowed:  decision.Allowed,
+		Decision: decisionDeny,
+		Reason:   decision.Reason,
+		Policy:   decision.Policy,
+	}
+
+	if decision.Allowed {
+		result.Decision = decisionAllow
+	}
+
+	return result, nil
+}
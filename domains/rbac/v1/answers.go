@@ -0,0 +1,18 @@
+package rbacv1
+
+import (
+	"github.com/soldatov-s/go-garage-auth/models"
+	"github.com/soldatov-s/go-garage/providers/httpsrv"
+)
+
+// Return separated items
+type RoleDataResult httpsrv.ResultAnsw
+
+type PermissionDataResult httpsrv.ResultAnsw
+
+// Return array of items
+type RolesDataResult httpsrv.ResultAnsw
+type ArrayOfRole []models.Role
+
+type PermissionsDataResult httpsrv.ResultAnsw
+type ArrayOfPermission []models.Permission
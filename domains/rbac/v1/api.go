@@ -0,0 +1,503 @@
+package rbacv1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/soldatov-s/go-garage-auth/models"
+	"github.com/soldatov-s/go-garage/providers/httpsrv"
+	"github.com/soldatov-s/go-garage/providers/httpsrv/echo"
+	echoSwagger "github.com/soldatov-s/go-swagger/echo-swagger"
+)
+
+func (r *RBACV1) rolesGetHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Get Roles Handler").
+			SetSummary("This handler returns all roles with the granted permissions").
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "Roles", &RolesDataResult{Body: ArrayOfRole{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	roles, err := r.GetRoles()
+	if err != nil {
+		log.Err(err).Msg("NOT FOUND DATA")
+		return ec.NotFound(err)
+	}
+
+	return ec.OK(RolesDataResult{Body: roles})
+}
+
+func (r *RBACV1) rolePostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Create Role Handler").
+			SetSummary("This handler creates a new role with the permissions").
+			AddInBodyParameter("role", "Role", &models.Role{}, true).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "Role", &RoleDataResult{Body: models.Role{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusConflict, "CREATE ROLE FAILED", httpsrv.CreateFailed(err)).
+			AddResponse(http.StatusNotAcceptable, "ROLE NAME IS OCCUPIED", RoleNameIsOccupied())
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	var role models.Role
+
+	err = ec.Bind(&role)
+	if err != nil {
+		log.Err(err).Msg("BAD REQUEST")
+		return ec.BadRequest(err)
+	}
+
+	if !role.Validate() {
+		log.Err(err).Msgf("BAD REQUEST, role %+v", &role)
+		return ec.BadRequest(err)
+	}
+
+	roleData, err := r.createRole(&role)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRoleNameIsOccupied):
+			log.Err(err).Msgf("ROLE NAME IS OCCUPIED %s", role.Name)
+			return ec.JSON(http.StatusNotAcceptable, RoleNameIsOccupied())
+		case errors.Is(err, ErrUnknownPermission):
+			log.Err(err).Msgf("BAD REQUEST, role %+v", &role)
+			return ec.BadRequest(err)
+		}
+
+		log.Err(err).Msgf("CREATE ROLE FAILED %+v", &role)
+
+		return ec.CreateFailed(err)
+	}
+
+	return ec.OK(RoleDataResult{Body: roleData})
+}
+
+func (r *RBACV1) roleGetHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Get Role Handler").
+			SetSummary("This handler returns the role with the granted permissions by role_id").
+			AddInPathParameter("role_id", "Role id", reflect.Int64).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "Role", &RoleDataResult{Body: models.Role{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	roleID, err := ec.GetInt64Param("role_id")
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, role id %s", ec.Param("role_id"))
+		return ec.BadRequest(err)
+	}
+
+	roleData, err := r.GetRoleByID(roleID)
+	if err != nil {
+		log.Err(err).Msgf("NOT FOUND, role id %d", roleID)
+		return ec.NotFound(err)
+	}
+
+	return ec.OK(RoleDataResult{Body: roleData})
+}
+
+func (r *RBACV1) rolePutHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Update Role Handler").
+			SetSummary("This handler replaces the name, the description and the permissions of the role by role_id").
+			AddInBodyParameter("role", "Role", &models.Role{}, true).
+			AddInPathParameter("role_id", "Role id", reflect.Int64).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "Role", &RoleDataResult{Body: models.Role{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err)).
+			AddResponse(http.StatusConflict, "DATA NOT UPDATED", httpsrv.NotUpdated(err)).
+			AddResponse(http.StatusNotAcceptable, "ROLE NAME IS OCCUPIED", RoleNameIsOccupied())
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	roleID, err := ec.GetInt64Param("role_id")
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, role id %s", ec.Param("role_id"))
+		return ec.BadRequest(err)
+	}
+
+	var role models.Role
+
+	err = ec.Bind(&role)
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, role id %d", roleID)
+		return ec.BadRequest(err)
+	}
+
+	if !role.Validate() {
+		log.Err(err).Msgf("BAD REQUEST, role id %d, role %+v", roleID, &role)
+		return ec.BadRequest(err)
+	}
+
+	roleData, err := r.updateRole(roleID, &role)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRoleNotFound):
+			log.Err(err).Msgf("NOT FOUND, role id %d", roleID)
+			return ec.NotFound(err)
+		case errors.Is(err, ErrRoleNameIsOccupied):
+			log.Err(err).Msgf("ROLE NAME IS OCCUPIED %s", role.Name)
+			return ec.JSON(http.StatusNotAcceptable, RoleNameIsOccupied())
+		case errors.Is(err, ErrUnknownPermission):
+			log.Err(err).Msgf("BAD REQUEST, role id %d, role %+v", roleID, &role)
+			return ec.BadRequest(err)
+		}
+
+		log.Err(err).Msgf("DATA NOT UPDATED, role id %d", roleID)
+
+		return ec.NotUpdated(err)
+	}
+
+	return ec.OK(RoleDataResult{Body: roleData})
+}
+
+func (r *RBACV1) roleDeleteHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Delete Role Handler").
+			SetSummary("This handler deletes the role by role_id, the role is unassigned from all users").
+			AddInPathParameter("role_id", "Role id", reflect.Int64).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "OK", httpsrv.OkResult()).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err)).
+			AddResponse(http.StatusConflict, "DATA NOT DELETED", httpsrv.NotDeleted(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	roleID, err := ec.GetInt64Param("role_id")
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, role id %s", ec.Param("role_id"))
+		return ec.BadRequest(err)
+	}
+
+	err = r.deleteRole(roleID)
+	if err != nil {
+		if errors.Is(err, ErrRoleNotFound) {
+			log.Err(err).Msgf("NOT FOUND, role id %d", roleID)
+			return ec.NotFound(err)
+		}
+
+		log.Err(err).Msgf("DATA NOT DELETED, role id %d", roleID)
+
+		return ec.NotDeleted(err)
+	}
+
+	return ec.OkResult()
+}
+
+func (r *RBACV1) permissionsGetHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Get Permissions Handler").
+			SetSummary("This handler returns all permissions").
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "Permissions", &PermissionsDataResult{Body: ArrayOfPermission{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	permissions, err := r.GetPermissions()
+	if err != nil {
+		log.Err(err).Msg("NOT FOUND DATA")
+		return ec.NotFound(err)
+	}
+
+	return ec.OK(PermissionsDataResult{Body: permissions})
+}
+
+func (r *RBACV1) permissionPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Create Permission Handler").
+			SetSummary("This handler creates a new permission, the name of permission is resource:action").
+			AddInBodyParameter("permission", "Permission", &models.Permission{}, true).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "Permission", &PermissionDataResult{Body: models.Permission{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusConflict, "CREATE PERMISSION FAILED", httpsrv.CreateFailed(err)).
+			AddResponse(http.StatusNotAcceptable, "PERMISSION ALREADY EXISTS", PermissionIsOccupied())
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	var permission models.Permission
+
+	err = ec.Bind(&permission)
+	if err != nil {
+		log.Err(err).Msg("BAD REQUEST")
+		return ec.BadRequest(err)
+	}
+
+	if !permission.Validate() {
+		log.Err(err).Msgf("BAD REQUEST, permission %s", permission.Name)
+		return ec.BadRequest(err)
+	}
+
+	permissionData, err := r.createPermission(&permission)
+	if err != nil {
+		if errors.Is(err, ErrPermissionIsOccupied) {
+			log.Err(err).Msgf("PERMISSION ALREADY EXISTS %s", permission.Name)
+			return ec.JSON(http.StatusNotAcceptable, PermissionIsOccupied())
+		}
+
+		log.Err(err).Msgf("CREATE PERMISSION FAILED %s", permission.Name)
+
+		return ec.CreateFailed(err)
+	}
+
+	return ec.OK(PermissionDataResult{Body: permissionData})
+}
+
+func (r *RBACV1) permissionDeleteHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Delete Permission Handler").
+			SetSummary("This handler deletes the permission by name, the permission is revoked from all roles").
+			AddInPathParameter("name", "Permission name", reflect.String).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "OK", httpsrv.OkResult()).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err)).
+			AddResponse(http.StatusConflict, "DATA NOT DELETED", httpsrv.NotDeleted(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	name := ec.Param("name")
+
+	err = r.deletePermission(name)
+	if err != nil {
+		if errors.Is(err, ErrPermissionNotFound) {
+			log.Err(err).Msgf("NOT FOUND, permission %s", name)
+			return ec.NotFound(err)
+		}
+
+		log.Err(err).Msgf("DATA NOT DELETED, permission %s", name)
+
+		return ec.NotDeleted(err)
+	}
+
+	return ec.OkResult()
+}
+
+func (r *RBACV1) userRolesGetHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Get User Roles Handler").
+			SetSummary("This handler returns the roles assigned to the user by user_id").
+			AddInPathParameter("id", "User id", reflect.Int64).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "Roles", &RolesDataResult{Body: ArrayOfRole{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	userID, err := ec.GetInt64Param("id")
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, id %s", ec.Param("id"))
+		return ec.BadRequest(err)
+	}
+
+	roles, err := r.GetUserRoles(userID)
+	if err != nil {
+		log.Err(err).Msgf("NOT FOUND, id %d", userID)
+		return ec.NotFound(err)
+	}
+
+	return ec.OK(RolesDataResult{Body: roles})
+}
+
+func (r *RBACV1) userRolePutHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Assign Role Handler").
+			SetSummary("This handler assigns the role to the user by user_id and role_id").
+			AddInPathParameter("id", "User id", reflect.Int64).
+			AddInPathParameter("role_id", "Role id", reflect.Int64).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "OK", httpsrv.OkResult()).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err)).
+			AddResponse(http.StatusConflict, "DATA NOT UPDATED", httpsrv.NotUpdated(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	userID, roleID, err := assignmentParams(ec)
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, id %s, role id %s", ec.Param("id"), ec.Param("role_id"))
+		return ec.BadRequest(err)
+	}
+
+	err = r.assignRole(userID, roleID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrRoleNotFound) {
+			log.Err(err).Msgf("NOT FOUND, id %d, role id %d", userID, roleID)
+			return ec.NotFound(err)
+		}
+
+		log.Err(err).Msgf("DATA NOT UPDATED, id %d, role id %d", userID, roleID)
+
+		return ec.NotUpdated(err)
+	}
+
+	return ec.OkResult()
+}
+
+func (r *RBACV1) userRoleDeleteHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Unassign Role Handler").
+			SetSummary("This handler unassigns the role from the user by user_id and role_id").
+			AddInPathParameter("id", "User id", reflect.Int64).
+			AddInPathParameter("role_id", "Role id", reflect.Int64).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "OK", httpsrv.OkResult()).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err)).
+			AddResponse(http.StatusConflict, "DATA NOT DELETED", httpsrv.NotDeleted(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	userID, roleID, err := assignmentParams(ec)
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, id %s, role id %s", ec.Param("id"), ec.Param("role_id"))
+		return ec.BadRequest(err)
+	}
+
+	err = r.unassignRole(userID, roleID)
+	if err != nil {
+		if errors.Is(err, ErrRoleNotFound) {
+			log.Err(err).Msgf("NOT FOUND, id %d, role id %d", userID, roleID)
+			return ec.NotFound(err)
+		}
+
+		log.Err(err).Msgf("DATA NOT DELETED, id %d, role id %d", userID, roleID)
+
+		return ec.NotDeleted(err)
+	}
+
+	return ec.OkResult()
+}
+
+// assignmentParams returns the user ID and the role ID of the assignment
+func assignmentParams(ec echo.Context) (userID, roleID int64, err error) {
+	if userID, err = ec.GetInt64Param("id"); err != nil {
+		return 0, 0, err
+	}
+
+	if roleID, err = ec.GetInt64Param("role_id"); err != nil {
+		return 0, 0, err
+	}
+
+	return userID, roleID, nil
+}
@@ -0,0 +1,25 @@
+package rbacv1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/soldatov-s/go-garage/providers/httpsrv"
+)
+
+var (
+	ErrRoleNameIsOccupied   = errors.New("role name is occupied")
+	ErrPermissionIsOccupied = errors.New("permission already exists")
+	ErrUnknownPermission    = errors.New("unknown permission")
+	ErrRoleNotFound         = errors.New("role not found")
+	ErrPermissionNotFound   = errors.New("permission not found")
+	ErrUserNotFound         = errors.New("user not found")
+)
+
+func RoleNameIsOccupied() httpsrv.ErrorAnsw {
+	return httpsrv.NewErrorAnsw(http.StatusNotAcceptable, "role name is occupied", ErrRoleNameIsOccupied)
+}
+
+func PermissionIsOccupied() httpsrv.ErrorAnsw {
+	return httpsrv.NewErrorAnsw(http.StatusNotAcceptable, "permission already exists", ErrPermissionIsOccupied)
+}
@@ -0,0 +1,78 @@
+package rbacv1
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	authv1 "github.com/soldatov-s/go-garage-auth/domains/auth/v1"
+	"github.com/soldatov-s/go-garage-auth/internal/cfg"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+	"github.com/soldatov-s/go-garage/domains"
+	"github.com/soldatov-s/go-garage/providers/db/pq"
+	"github.com/soldatov-s/go-garage/providers/httpsrv/echo"
+	"github.com/soldatov-s/go-garage/providers/logger"
+)
+
+const (
+	DomainName = "rbacv1"
+)
+
+type empty struct{}
+
+// RBACV1 manages roles, permissions granted to roles and assignments of roles to users
+type RBACV1 struct {
+	log zerolog.Logger
+	ctx context.Context
+	db  *pq.Enity
+	cfg *cfg.Config
+}
+
+func Registrate(ctx context.Context) (context.Context, error) {
+	r := &RBACV1{
+		ctx: ctx,
+		log: logger.GetPackageLogger(ctx, empty{}),
+		cfg: cfg.Get(ctx),
+	}
+	var err error
+	if r.db, err = pq.GetEnityTypeCast(ctx, cfg.DBName); err != nil {
+		return nil, err
+	}
+
+	privateV1, err := echo.GetAPIVersionGroup(ctx, cfg.PrivateHTTP, cfg.V1)
+	if err != nil {
+		return nil, err
+	}
+
+	authV1, err := authv1.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := authV1.RequirePermission(goGarageAuthTypes.PermissionRolesRead, "")
+	writer := authV1.RequirePermission(goGarageAuthTypes.PermissionRolesWrite, "")
+
+	grProtect := privateV1.Group
+	grProtect.Use(echo.HydrationLogger(&r.log))
+	grProtect.GET("/roles", echo.Handler(r.rolesGetHandler), reader)
+	grProtect.POST("/roles", echo.Handler(r.rolePostHandler), writer)
+	grProtect.GET("/roles/:role_id", echo.Handler(r.roleGetHandler), reader)
+	grProtect.PUT("/roles/:role_id", echo.Handler(r.rolePutHandler), writer)
+	grProtect.DELETE("/roles/:role_id", echo.Handler(r.roleDeleteHandler), writer)
+	grProtect.GET("/permissions", echo.Handler(r.permissionsGetHandler), reader)
+	grProtect.POST("/permissions", echo.Handler(r.permissionPostHandler), writer)
+	grProtect.DELETE("/permissions/:name", echo.Handler(r.permissionDeleteHandler), writer)
+	// Every user is allowed to read the own roles
+	grProtect.GET("/users/:id/roles", echo.Handler(r.userRolesGetHandler),
+		authV1.RequirePermission(goGarageAuthTypes.PermissionRolesRead, "id"))
+	grProtect.PUT("/users/:id/roles/:role_id", echo.Handler(r.userRolePutHandler), writer)
+	grProtect.DELETE("/users/:id/roles/:role_id", echo.Handler(r.userRoleDeleteHandler), writer)
+
+	return domains.RegistrateByName(ctx, DomainName, r), nil
+}
+
+func Get(ctx context.Context) (*RBACV1, error) {
+	if v, ok := domains.GetByName(ctx, DomainName).(*RBACV1); ok {
+		return v, nil
+	}
+	return nil, domains.ErrInvalidDomainType
+}
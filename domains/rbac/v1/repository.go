@@ -0,0 +1,366 @@
+package rbacv1
+
+import (
+	dbsql "database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	libpq "github.com/lib/pq"
+	authv1 "github.com/soldatov-s/go-garage-auth/domains/auth/v1"
+	"github.com/soldatov-s/go-garage-auth/models"
+	"github.com/soldatov-s/go-garage/providers/db"
+	"github.com/soldatov-s/go-garage/x/sql"
+)
+
+// Codes of PostgreSQL errors
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+// isPQError checks that the error is PostgreSQL error with the code
+func isPQError(err error, code string) bool {
+	var pqErr *libpq.Error
+	return errors.As(err, &pqErr) && string(pqErr.Code) == code
+}
+
+func (r *RBACV1) GetRoles() (ArrayOfRole, error) {
+	if r.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	roles := ArrayOfRole{}
+	if err := r.db.Conn.Select(&roles, "select * from production.role order by role_id"); err != nil {
+		return nil, err
+	}
+
+	if err := r.fillPermissions(roles); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+func (r *RBACV1) GetRoleByID(id int64) (*models.Role, error) {
+	if r.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	roles := ArrayOfRole{{}}
+
+	err := r.db.Conn.Get(&roles[0], "select * from production.role where role_id=$1", id)
+	if errors.Is(err, dbsql.ErrNoRows) {
+		return nil, ErrRoleNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.fillPermissions(roles); err != nil {
+		return nil, err
+	}
+
+	return &roles[0], nil
+}
+
+// GetUserRoles returns the roles assigned to the user
+func (r *RBACV1) GetUserRoles(userID int64) (ArrayOfRole, error) {
+	if r.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	roles := ArrayOfRole{}
+
+	err := r.db.Conn.Select(&roles, `select r.* from production.role r
+		join production.role_assignment ra on ra.role_id = r.role_id where ra.user_id=$1 order by r.role_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.fillPermissions(roles); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// fillPermissions loads the permissions of the roles
+func (r *RBACV1) fillPermissions(roles ArrayOfRole) error {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(roles))
+	byID := make(map[int64]*models.Role, len(roles))
+
+	for i := range roles {
+		roles[i].Permissions = []string{}
+		ids = append(ids, roles[i].ID)
+		byID[roles[i].ID] = &roles[i]
+	}
+
+	var grants []struct {
+		RoleID     int64  `db:"role_id"`
+		Permission string `db:"permission_name"`
+	}
+
+	err := r.db.Conn.Select(&grants, `select role_id, permission_name from production.role_permission
+		where role_id = any($1) order by permission_name`, libpq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	for _, grant := range grants {
+		role := byID[grant.RoleID]
+		role.Permissions = append(role.Permissions, grant.Permission)
+	}
+
+	return nil
+}
+
+func (r *RBACV1) createRole(role *models.Role) (*models.Role, error) {
+	role.CreatedAt.SetNow()
+	role.UpdatedAt.SetNow()
+
+	if r.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	tx, err := r.db.Conn.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Get(role, `INSERT INTO production.role (role_name, role_description, created_at, updated_at)
+		VALUES ($1, $2, $3, $4) RETURNING *`, role.Name, role.Description, role.CreatedAt, role.UpdatedAt)
+	if err != nil {
+		_ = tx.Rollback()
+
+		if isPQError(err, uniqueViolation) {
+			return nil, ErrRoleNameIsOccupied
+		}
+
+		return nil, err
+	}
+
+	if err = setPermissions(tx, role.ID, role.Permissions); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// updateRole replaces the name, the description and the permissions of the role
+func (r *RBACV1) updateRole(id int64, role *models.Role) (*models.Role, error) {
+	role.UpdatedAt.SetNow()
+
+	if r.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	tx, err := r.db.Conn.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Get(role, `UPDATE production.role SET role_name=$1, role_description=$2, updated_at=$3
+		WHERE role_id=$4 RETURNING *`, role.Name, role.Description, role.UpdatedAt, id)
+	if err != nil {
+		_ = tx.Rollback()
+
+		switch {
+		case errors.Is(err, dbsql.ErrNoRows):
+			return nil, ErrRoleNotFound
+		case isPQError(err, uniqueViolation):
+			return nil, ErrRoleNameIsOccupied
+		}
+
+		return nil, err
+	}
+
+	if err = setPermissions(tx, role.ID, role.Permissions); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	r.invalidatePermissions("")
+
+	return role, nil
+}
+
+// setPermissions replaces the permissions granted to the role
+func setPermissions(tx *sqlx.Tx, roleID int64, permissions []string) error {
+	if _, err := tx.Exec("DELETE FROM production.role_permission WHERE role_id=$1", roleID); err != nil {
+		return err
+	}
+
+	for _, permission := range permissions {
+		_, err := tx.Exec(`INSERT INTO production.role_permission (role_id, permission_name) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING`, roleID, permission)
+		if isPQError(err, foreignKeyViolation) {
+			return ErrUnknownPermission
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *RBACV1) deleteRole(id int64) error {
+	if r.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	result, err := r.db.Conn.Exec("DELETE FROM production.role WHERE role_id=$1", id)
+	if err != nil {
+		return err
+	}
+
+	countRow, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if countRow == 0 {
+		return ErrRoleNotFound
+	}
+
+	r.invalidatePermissions("")
+
+	return nil
+}
+
+func (r *RBACV1) GetPermissions() (ArrayOfPermission, error) {
+	if r.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	permissions := ArrayOfPermission{}
+
+	err := r.db.Conn.Select(&permissions, "select * from production.permission order by permission_name")
+	if err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func (r *RBACV1) createPermission(permission *models.Permission) (*models.Permission, error) {
+	permission.CreatedAt.SetNow()
+
+	_, err := sql.InsertInto(r.db.Conn, "production.permission", permission)
+	if isPQError(err, uniqueViolation) {
+		return nil, ErrPermissionIsOccupied
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return permission, nil
+}
+
+// deletePermission deletes the permission and revokes it from all roles
+func (r *RBACV1) deletePermission(name string) error {
+	if r.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	result, err := r.db.Conn.Exec("DELETE FROM production.permission WHERE permission_name=$1", name)
+	if err != nil {
+		return err
+	}
+
+	countRow, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if countRow == 0 {
+		return ErrPermissionNotFound
+	}
+
+	r.invalidatePermissions("")
+
+	return nil
+}
+
+func (r *RBACV1) assignRole(userID, roleID int64) error {
+	if r.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	var exists bool
+
+	err := r.db.Conn.Get(&exists, "select exists(select 1 from production.user where user_id=$1)", userID)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	_, err = r.db.Conn.Exec(`INSERT INTO production.role_assignment (user_id, role_id, created_at)
+		VALUES ($1, $2, now()) ON CONFLICT DO NOTHING`, userID, roleID)
+	if isPQError(err, foreignKeyViolation) {
+		return ErrRoleNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	r.invalidatePermissions(strconv.FormatInt(userID, 10))
+
+	return nil
+}
+
+func (r *RBACV1) unassignRole(userID, roleID int64) error {
+	if r.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	result, err := r.db.Conn.Exec("DELETE FROM production.role_assignment WHERE user_id=$1 AND role_id=$2", userID, roleID)
+	if err != nil {
+		return err
+	}
+
+	countRow, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if countRow == 0 {
+		return ErrRoleNotFound
+	}
+
+	r.invalidatePermissions(strconv.FormatInt(userID, 10))
+
+	return nil
+}
+
+// invalidatePermissions drops the cached introspections, so the changed permissions are applied at once
+func (r *RBACV1) invalidatePermissions(subject string) {
+	authV1, err := authv1.Get(r.ctx)
+	if err != nil {
+		r.log.Err(err).Msg("failed to get authv1 domain")
+		return
+	}
+
+	authV1.InvalidatePermissions(subject)
+}
@@ -0,0 +1,181 @@
+package userv1
+
+import (
+	"crypto/sha256"
+	dbsql "database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/soldatov-s/go-garage-auth/internal/hmac"
+	"github.com/soldatov-s/go-garage-auth/internal/mailer"
+	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+	"github.com/soldatov-s/go-garage/providers/db"
+	"github.com/soldatov-s/go-garage/types"
+	"github.com/soldatov-s/go-garage/utils/email"
+)
+
+const (
+	oneTimeTokenSize = 32
+)
+
+// newOneTimeToken returns a random token for activation or password reset
+func newOneTimeToken() (string, error) {
+	token, err := hmac.RandomBytes(oneTimeTokenSize)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(token), nil
+}
+
+// hashToken returns the hash of the one-time token, the database keeps only hashes
+func hashToken(token string) types.NullString {
+	hash := sha256.Sum256([]byte(token))
+
+	return types.NullString{NullString: dbsql.NullString{String: hex.EncodeToString(hash[:]), Valid: true}}
+}
+
+// newActivation generates a new activation token of the user, the previous token becomes invalid
+func (u *UserV1) newActivation(data *models.User) error {
+	token, err := newOneTimeToken()
+	if err != nil {
+		return err
+	}
+
+	data.ActivationToken = token
+	data.ActivationHash = hashToken(token)
+	data.ActivationSentAt.SetNow()
+	data.ActivationExpiredAt.SetTime(data.ActivationSentAt.Time.Add(u.cfg.Activation.TTL))
+
+	return nil
+}
+
+// sendMail mails the message to the user, the locale of the message is taken from user_meta
+func (u *UserV1) sendMail(mailType string, data *models.User, values map[string]interface{}) {
+	m, err := mailer.Get(u.ctx)
+	if err != nil {
+		u.log.Err(err).Msg("failed to get mailer domain")
+		return
+	}
+
+	locale, _ := data.Meta.Map["locale"].(string)
+	values["User"] = data
+
+	if err = m.Send(mailType, locale, data.Email, values); err != nil {
+		u.log.Err(err).Msgf("failed to send %s, id %d", mailType, data.ID)
+	}
+}
+
+// sendActivation mails the activation token to the user
+func (u *UserV1) sendActivation(data *models.User) {
+	u.sendMail(mailer.TypeActivation, data, map[string]interface{}{
+		"Token":     data.ActivationToken,
+		"ExpiredAt": data.ActivationExpiredAt.Time,
+	})
+}
+
+// activateUser moves the user from NEW to ACTIVE by the activation token, the token can be used once
+func (u *UserV1) activateUser(token string) (*models.User, error) {
+	if u.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	data := &models.User{}
+
+	err := u.db.Conn.Get(data, `UPDATE production.user SET user_status=$1, user_activation_hash=NULL,
+		user_activation_expired_at=NULL, updated_at=$2 WHERE user_activation_hash=$3 AND user_status=$4
+		AND user_activation_expired_at > $2 AND deleted_at IS NULL RETURNING *`,
+		goGarageAuthTypes.Active, time.Now(), hashToken(token), goGarageAuthTypes.New)
+	if errors.Is(err, dbsql.ErrNoRows) {
+		return nil, ErrInvalidActivation
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// resendActivation generates a new activation token of the user, it isn't allowed more often than ResendInterval
+func (u *UserV1) resendActivation(id int64) (*models.User, error) {
+	data, err := u.GetUserDataByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = u.renewActivation(data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// resendActivationByEmail generates a new activation token of the user with the email,
+// the rate is limited by ResendInterval of the user
+func (u *UserV1) resendActivationByEmail(address string) error {
+	normalEmail, err := email.Normilize(address)
+	if err != nil {
+		return err
+	}
+
+	if u.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	data := &models.User{}
+	if err = u.db.Conn.Get(data, "select * from production.emailFastSearch($1)", normalEmail); err != nil {
+		return err
+	}
+
+	return u.renewActivation(data)
+}
+
+// renewActivation replaces the activation token of NEW user and mails it
+func (u *UserV1) renewActivation(data *models.User) error {
+	if data.DeletedAt.Valid {
+		return dbsql.ErrNoRows
+	}
+
+	if data.Status != goGarageAuthTypes.New {
+		return ErrUserIsActivated
+	}
+
+	// Tokens generated after this time limit the rate
+	limit := time.Now().Add(-u.cfg.Activation.ResendInterval)
+	if data.ActivationSentAt.Valid && data.ActivationSentAt.Time.After(limit) {
+		return ErrActivationRateLimited
+	}
+
+	if err := u.newActivation(data); err != nil {
+		return err
+	}
+
+	if u.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	// The condition on user_activation_sent_at protects against concurrent requests
+	result, err := u.db.Conn.Exec(`UPDATE production.user SET user_activation_hash=$1, user_activation_expired_at=$2,
+		user_activation_sent_at=$3 WHERE user_id=$4 AND user_status=$5
+		AND (user_activation_sent_at IS NULL OR user_activation_sent_at <= $6)`,
+		data.ActivationHash, data.ActivationExpiredAt, data.ActivationSentAt, data.ID, goGarageAuthTypes.New, limit)
+	if err != nil {
+		return err
+	}
+
+	countRow, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if countRow == 0 {
+		return ErrActivationRateLimited
+	}
+
+	u.sendActivation(data)
+
+	return nil
+}
@@ -10,6 +10,16 @@ type UserDataResult httpsrv.ResultAnsw
 
 type TokenAndUserResult httpsrv.ResultAnsw
 
+type MFAChallengeResult httpsrv.ResultAnsw
+
+type MFAEnrollmentResult httpsrv.ResultAnsw
+
+type WebAuthnOptionsResult httpsrv.ResultAnsw
+
+type WebAuthnCredentialResult httpsrv.ResultAnsw
+
+type WebAuthnCredentialsResult httpsrv.ResultAnsw
+
 // Return array of items
 type UsersDataResult httpsrv.ResultAnsw
 type ArrayOfUserData []models.User
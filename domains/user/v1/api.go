@@ -1,6 +1,7 @@
 package userv1
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,7 +12,9 @@ import (
 	"time"
 
 	authv1 "github.com/soldatov-s/go-garage-auth/domains/auth/v1"
+	"github.com/soldatov-s/go-garage-auth/internal/webauthn"
 	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
 	"github.com/soldatov-s/go-garage/providers/httpsrv"
 	"github.com/soldatov-s/go-garage/providers/httpsrv/echo"
 	echoSwagger "github.com/soldatov-s/go-swagger/echo-swagger"
@@ -26,7 +29,10 @@ func (u *UserV1) userPostHandler(ec echo.Context) (err error) {
 			SetDescription("Create User Handler").
 			SetSummary("This handler create new user").
 			AddInBodyParameter("user_creds", "User creds", models.NewCredentials{}, true).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
 			AddResponse(http.StatusOK, "User Data", &UserDataResult{Body: models.User{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
 			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
 			AddResponse(http.StatusConflict, "CREATE USER FAILED", httpsrv.CreateFailed(err)).
 			AddResponse(http.StatusNotAcceptable, "EMAIL IS OCCUPIED", EmailIsOccupied())
@@ -65,6 +71,17 @@ func (u *UserV1) userPostHandler(ec echo.Context) (err error) {
 		return ec.BadRequest(err)
 	}
 
+	caller, err := callerOf(ec)
+	if err == nil {
+		err = checkAssignment(caller, &userCreds.Role, true)
+	}
+
+	if err != nil {
+		log.Err(err).Msgf("FORBIDDEN %s", &userCreds)
+
+		return ec.Forbidden(err)
+	}
+
 	userData, err := u.createUser(&userCreds)
 	if err != nil {
 		if err == ErrLoginOrEmailIsOccupied {
@@ -92,7 +109,10 @@ func (u *UserV1) userGetHandler(ec echo.Context) (err error) {
 			SetDescription("Get User Handler").
 			SetSummary("This handler get user data by user_id").
 			AddInPathParameter("id", "User id", reflect.Int64).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
 			AddResponse(http.StatusOK, "User Data", &UserDataResult{Body: models.User{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
 			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
 			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err))
 
@@ -129,7 +149,10 @@ func (u *UserV1) userPutHandler(ec echo.Context) (err error) {
 			SetSummary("This handler update user data by user_id").
 			AddInBodyParameter("user_data", "User data", &models.User{}, true).
 			AddInPathParameter("id", "User id", reflect.Int64).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
 			AddResponse(http.StatusOK, "User Data", &UserDataResult{Body: models.User{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
 			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
 			AddResponse(http.StatusConflict, "DATA NOT UPDATED", httpsrv.NotUpdated(err)).
 			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err)).
@@ -160,6 +183,23 @@ func (u *UserV1) userPutHandler(ec echo.Context) (err error) {
 		}
 	}
 
+	caller, err := callerOf(ec)
+	if err == nil {
+		err = u.checkChange(caller, userID, bodyBytes)
+	}
+
+	if err != nil {
+		if isForbidden(err) {
+			log.Err(err).Msgf("FORBIDDEN, id %d, body %s", userID, string(bodyBytes))
+
+			return ec.Forbidden(err)
+		}
+
+		log.Err(err).Msgf("BAD REQUEST, id %d, body %s", userID, string(bodyBytes))
+
+		return ec.BadRequest(err)
+	}
+
 	userData, err := u.updateUserByID(userID, &bodyBytes)
 	if err != nil {
 		if err == ErrLoginOrEmailIsOccupied {
@@ -189,7 +229,10 @@ func (u *UserV1) credsPutHandler(ec echo.Context) (err error) {
 			SetSummary("This handler update user credentials data by user_id").
 			AddInBodyParameter("user_creds", "User creds", &models.UpdateCredentials{}, true).
 			AddInPathParameter("id", "User id", reflect.Int64).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
 			AddResponse(http.StatusOK, "User Data", &UserDataResult{Body: models.User{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
 			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
 			AddResponse(http.StatusConflict, "DATA NOT UPDATED", httpsrv.NotUpdated(err)).
 			AddResponse(http.StatusConflict, "NEW PASSWORD SAME AS OLD", NewPasswordIsSameAsOld())
@@ -222,6 +265,23 @@ func (u *UserV1) credsPutHandler(ec echo.Context) (err error) {
 		return ec.BadRequest(err)
 	}
 
+	caller, err := callerOf(ec)
+	if err == nil {
+		err = u.checkChange(caller, userID, nil)
+	}
+
+	if err != nil {
+		if isForbidden(err) {
+			log.Err(err).Msgf("FORBIDDEN, id %d", userID)
+
+			return ec.Forbidden(err)
+		}
+
+		log.Err(err).Msgf("BAD REQUEST, id %d", userID)
+
+		return ec.BadRequest(err)
+	}
+
 	userData, err := u.updateUserCredsByID(userID, &userCreds)
 	if err != nil {
 		if err == ErrNewPasswordIsSameAsOld {
@@ -250,19 +310,37 @@ func (u *UserV1) credsPostHandler(ec echo.Context) (err error) {
 			SetDescription("Check User Handler").
 			SetSummary("This handler check user credentials. If there is a login, a login is taken; if there is no login, an email is taken").
 			AddInBodyParameter("user_creds", "User creds", &models.Credentials{}, true).
-			AddResponse(http.StatusOK, "User Data", &UserDataResult{Body: models.User{}}).
+			AddResponse(http.StatusOK, "Tokens and User Data or MFA token if the user has to send TOTP code to /credentials/mfa", &TokenAndUserResult{Body: models.TokenAndUser{}}).
 			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
-			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err))
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "USER IS NOT ACTIVATED", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusTooManyRequests, "TOO MANY FAILED MFA CODES", MFALocked())
 
 		return nil
 	}
 
 	// Main code of handler
+	// The tokens are issued after the check of TOTP code
+	return u.credentialsLogin(ec, func(ec echo.Context, challenge *models.MFAChallenge) error {
+		return ec.OK(TokenAndUserResult{Body: models.TokenAndUser{MFAToken: challenge.Token}})
+	}, u.tokenPairAnswer)
+}
+
+// userAnswer answers to the request of the user who passed the login check
+type userAnswer func(ec echo.Context, userData *models.User) error
+
+// credentialsLogin checks the user credentials of the request, answers by challengeAnswer
+// if MFA of the user is enabled, otherwise by answer
+func (u *UserV1) credentialsLogin(
+	ec echo.Context,
+	challengeAnswer func(ec echo.Context, challenge *models.MFAChallenge) error,
+	answer userAnswer,
+) error {
 	log := ec.GetLog()
 
 	var userCreds models.Credentials
 
-	err = ec.Bind(&userCreds)
+	err := ec.Bind(&userCreds)
 	if err != nil {
 		log.Err(err).Msg("BAD REQUEST")
 		return ec.BadRequest(err)
@@ -275,33 +353,54 @@ func (u *UserV1) credsPostHandler(ec echo.Context) (err error) {
 
 	userData, err := u.GetUserDataByCreds(&userCreds)
 	if err != nil {
+		if errors.Is(err, ErrUserNotActivated) {
+			log.Err(err).Msgf("FORBIDDEN, userCreds %s", &userCreds)
+			return ec.Forbidden(err)
+		}
+
 		log.Err(err).Msgf("UNAUTHORIZED, userCreds %s", &userCreds)
 		return ec.Unauthorized(err)
 	}
 
-	authV1, err := authv1.Get(u.ctx)
+	challenge, err := u.newMFAChallenge(userData.ID)
 	if err != nil {
-		log.Err(err).Msg("failed to get authv1 domain")
+		if errors.Is(err, ErrMFALocked) {
+			log.Err(err).Msgf("TOO MANY REQUESTS, userCreds %s", &userCreds)
+			return ec.JSON(http.StatusTooManyRequests, MFALocked())
+		}
+
+		log.Err(err).Msgf("CREATE MFA CHALLENGE FAILED %+v", &userCreds)
 		return ec.InternalServerError(err)
 	}
 
-	if !userCreds.Validate() {
-		log.Err(err).Msgf("failed to create token, userCreds %s", &userCreds)
+	if challenge != nil {
+		return challengeAnswer(ec, challenge)
+	}
+
+	return answer(ec, userData)
+}
+
+// tokenPairAnswer creates a new session of the user and answers with the tokens
+func (u *UserV1) tokenPairAnswer(ec echo.Context, userData *models.User) error {
+	log := ec.GetLog()
+
+	authV1, err := authv1.Get(u.ctx)
+	if err != nil {
+		log.Err(err).Msg("failed to get authv1 domain")
 		return ec.InternalServerError(err)
 	}
 
-	token, err := authV1.CreateToken(int(userData.ID))
+	tokenPair, err := authV1.CreateTokenPair(int(userData.ID), authv1.SessionClientFromRequest(ec))
 	if err != nil {
-		log.Err(err).Msgf("CREATE SESSION FAILED %+v", &userCreds)
+		log.Err(err).Msgf("CREATE SESSION FAILED, id %d", userData.ID)
 		return ec.BadRequest(err)
 	}
 
-	cookie := new(http.Cookie)
-	cookie.Name = authv1.SessionCookie
-	cookie.Value = token
-	cookie.Expires = time.Now().Add(u.cfg.Token.HMAC.TTL)
-
-	return ec.OK(TokenAndUserResult{Body: models.TokenAndUser{Token: token, User: userData}})
+	return ec.OK(TokenAndUserResult{Body: models.TokenAndUser{
+		Token:        tokenPair.Token,
+		RefreshToken: tokenPair.RefreshToken,
+		User:         userData,
+	}})
 }
 
 func (u *UserV1) userDeleteHandler(ec echo.Context) (err error) {
@@ -314,7 +413,10 @@ func (u *UserV1) userDeleteHandler(ec echo.Context) (err error) {
 			SetSummary("This handler for soft/hard delete user data by user_id").
 			AddInPathParameter("id", "User id", reflect.Int64).
 			AddInQueryParameter("hard", "Hard delete user, if equal true, delete hard", reflect.Bool, false).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
 			AddResponse(http.StatusOK, "OK", httpsrv.OkResult()).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
 			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
 			AddResponse(http.StatusConflict, "DATA NOT DELETED", httpsrv.NotDeleted(err))
 
@@ -331,6 +433,26 @@ func (u *UserV1) userDeleteHandler(ec echo.Context) (err error) {
 	}
 
 	hard := ec.QueryParam("hard")
+	if hard == "true" && !authv1.CallerHasPermission(ec, goGarageAuthTypes.PermissionUsersHardDelete) {
+		log.Debug().Msgf("FORBIDDEN, hard delete, id %d", userID)
+		return ec.Forbidden(authv1.ErrPermissionDenied)
+	}
+
+	caller, err := callerOf(ec)
+	if err == nil {
+		err = u.checkChange(caller, userID, nil)
+	}
+
+	if err != nil {
+		if isForbidden(err) {
+			log.Err(err).Msgf("FORBIDDEN, id %d", userID)
+			return ec.Forbidden(err)
+		}
+
+		log.Err(err).Msgf("DATA NOT DELETED, id %d", userID)
+		return ec.NotDeleted(err)
+	}
+
 	if hard == "true" {
 		err = u.hardDeleteUserByID(userID)
 	} else {
@@ -354,7 +476,10 @@ func (u *UserV1) userSearchPostHandler(ec echo.Context) (err error) {
 			SetDescription("Find User by email Handler").
 			SetSummary("This handler find user data by any field in User data struct. Can be multiple structs in request. Search by user_meta not work!").
 			AddInBodyParameter("users_data", "Users data", &ArrayOfUserData{}, true).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
 			AddResponse(http.StatusOK, "Users data", &UsersDataResult{Body: ArrayOfUserData{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
 			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
 			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err))
 
@@ -379,3 +504,789 @@ func (u *UserV1) userSearchPostHandler(ec echo.Context) (err error) {
 
 	return ec.OK(UserDataResult{Body: foundUsersData})
 }
+
+func (u *UserV1) loginPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Login Handler").
+			SetSummary("This handler checks user credentials and sets the session cookie. If there is a login, a login is taken; if there is no login, an email is taken. If MFA of the user is enabled, the handler returns MFA token for /auth/login/mfa").
+			AddInBodyParameter("user_creds", "User creds", &models.Credentials{}, true).
+			AddResponse(http.StatusOK, "User Data or MFA challenge", &UserDataResult{Body: models.User{}}).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "USER IS NOT ACTIVATED", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusTooManyRequests, "TOO MANY FAILED MFA CODES", MFALocked())
+
+		return nil
+	}
+
+	// Main code of handler
+	// The session is created after the check of TOTP code
+	return u.credentialsLogin(ec, func(ec echo.Context, challenge *models.MFAChallenge) error {
+		return ec.OK(MFAChallengeResult{Body: challenge})
+	}, u.sessionAnswer)
+}
+
+// sessionAnswer creates a new session of the user and sets the session cookie
+func (u *UserV1) sessionAnswer(ec echo.Context, userData *models.User) error {
+	log := ec.GetLog()
+
+	authV1, err := authv1.Get(u.ctx)
+	if err != nil {
+		log.Err(err).Msg("failed to get authv1 domain")
+		return ec.InternalServerError(err)
+	}
+
+	session, err := authV1.CreateSession(int(userData.ID), authv1.SessionClientFromRequest(ec))
+	if err != nil {
+		log.Err(err).Msgf("CREATE SESSION FAILED, id %d", userData.ID)
+		return ec.InternalServerError(err)
+	}
+
+	// The token is sent only in the cookie, so it isn't accessible from JavaScript
+	authV1.SetSessionCookies(ec, session.Token, time.Unix(session.ExpiredAt, 0))
+
+	return ec.OK(UserDataResult{Body: userData})
+}
+
+func (u *UserV1) activatePostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Activate User Handler").
+			SetSummary("This handler moves the user from NEW to ACTIVE by the single-use activation token").
+			AddInBodyParameter("activation", "Activation", &models.Activation{}, true).
+			AddResponse(http.StatusOK, "User Data", &UserDataResult{Body: models.User{}}).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	var activation models.Activation
+
+	err = ec.Bind(&activation)
+	if err != nil {
+		log.Err(err).Msg("BAD REQUEST")
+		return ec.BadRequest(err)
+	}
+
+	if !activation.Validate() {
+		log.Err(err).Msg("BAD REQUEST, empty activation token")
+		return ec.BadRequest(err)
+	}
+
+	userData, err := u.activateUser(activation.Token)
+	if err != nil {
+		log.Err(err).Msg("BAD REQUEST, activation failed")
+		return ec.BadRequest(err)
+	}
+
+	return ec.OK(UserDataResult{Body: userData})
+}
+
+func (u *UserV1) activationPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Resend Activation Handler").
+			SetSummary("This handler mails a new activation token to NEW user by user_id, the previous token becomes invalid").
+			AddInPathParameter("id", "User id", reflect.Int64).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "User Data", &UserDataResult{Body: models.User{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err)).
+			AddResponse(http.StatusConflict, "USER IS ALREADY ACTIVATED", httpsrv.NotUpdated(err)).
+			AddResponse(http.StatusTooManyRequests, "ACTIVATION TOKEN WAS GENERATED RECENTLY", ActivationRateLimited())
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	id, err := ec.GetInt64Param("id")
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, id %s", ec.Param("id"))
+		return ec.BadRequest(err)
+	}
+
+	userData, err := u.resendActivation(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrActivationRateLimited):
+			log.Err(err).Msgf("TOO MANY REQUESTS, id %d", id)
+			return ec.JSON(http.StatusTooManyRequests, ActivationRateLimited())
+		case errors.Is(err, ErrUserIsActivated):
+			log.Err(err).Msgf("DATA NOT UPDATED, id %d", id)
+			return ec.NotUpdated(err)
+		case errors.Is(err, sql.ErrNoRows):
+			log.Err(err).Msgf("NOT FOUND, id %d", id)
+			return ec.NotFound(err)
+		}
+
+		log.Err(err).Msgf("DATA NOT UPDATED, id %d", id)
+
+		return ec.NotUpdated(err)
+	}
+
+	return ec.OK(UserDataResult{Body: userData})
+}
+
+func (u *UserV1) activationResendPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Resend Activation By Email Handler").
+			SetSummary("This handler mails a new activation token to NEW user with the email. The answer is the same whether the email exists or not").
+			AddInBodyParameter("resend", "Activation resend", &models.ActivationResend{}, true).
+			AddResponse(http.StatusOK, "OK", httpsrv.OkResult()).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	var resend models.ActivationResend
+
+	err = ec.Bind(&resend)
+	if err != nil {
+		log.Err(err).Msg("BAD REQUEST")
+		return ec.BadRequest(err)
+	}
+
+	if !resend.Validate() {
+		log.Err(err).Msg("BAD REQUEST, empty email")
+		return ec.BadRequest(err)
+	}
+
+	// The activation is processed in background, so the time of the answer doesn't disclose whether the email exists
+	go func() {
+		if err := u.resendActivationByEmail(resend.Email); err != nil {
+			u.log.Err(err).Msgf("activation isn't sent to %s", resend.Email)
+		}
+	}()
+
+	return ec.OkResult()
+}
+
+func (u *UserV1) passwordForgotPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Forgot Password Handler").
+			SetSummary("This handler mails a single-use password reset token to the user with the email. The answer is the same whether the email exists or not").
+			AddInBodyParameter("forgot", "Password forgot", &models.PasswordForgot{}, true).
+			AddResponse(http.StatusOK, "OK", httpsrv.OkResult()).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	var forgot models.PasswordForgot
+
+	err = ec.Bind(&forgot)
+	if err != nil {
+		log.Err(err).Msg("BAD REQUEST")
+		return ec.BadRequest(err)
+	}
+
+	if !forgot.Validate() {
+		log.Err(err).Msg("BAD REQUEST, empty email")
+		return ec.BadRequest(err)
+	}
+
+	// The reset is processed in background, so the time of the answer doesn't disclose whether the email exists
+	go func() {
+		if err := u.forgotPassword(forgot.Email); err != nil {
+			u.log.Err(err).Msgf("password reset isn't sent to %s", forgot.Email)
+		}
+	}()
+
+	return ec.OkResult()
+}
+
+func (u *UserV1) passwordResetPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Reset Password Handler").
+			SetSummary("This handler sets a new password by the single-use reset token and revokes all sessions of the user").
+			AddInBodyParameter("reset", "Password reset", &models.PasswordReset{}, true).
+			AddResponse(http.StatusOK, "OK", httpsrv.OkResult()).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	var reset models.PasswordReset
+
+	err = ec.Bind(&reset)
+	if err != nil {
+		log.Err(err).Msg("BAD REQUEST")
+		return ec.BadRequest(err)
+	}
+
+	if !reset.Validate() {
+		log.Err(err).Msg("BAD REQUEST, empty reset token or password")
+		return ec.BadRequest(err)
+	}
+
+	if err = u.resetPassword(reset.Token, reset.Password); err != nil {
+		log.Err(err).Msg("BAD REQUEST, password reset failed")
+		return ec.BadRequest(err)
+	}
+
+	return ec.OkResult()
+}
+
+func (u *UserV1) credsMFAPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Check MFA Code Handler").
+			SetSummary("This handler exchanges MFA token from /credentials and TOTP code for the tokens").
+			AddInBodyParameter("mfa_verify", "MFA token and code", &models.MFAVerify{}, true).
+			AddResponse(http.StatusOK, "Tokens and User Data", &TokenAndUserResult{Body: models.TokenAndUser{}}).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusTooManyRequests, "TOO MANY FAILED MFA CODES", MFALocked())
+
+		return nil
+	}
+
+	// Main code of handler
+	return u.mfaLogin(ec, u.tokenPairAnswer)
+}
+
+// mfaLogin checks MFA token and TOTP code of the request and answers by answer
+func (u *UserV1) mfaLogin(ec echo.Context, answer userAnswer) error {
+	log := ec.GetLog()
+
+	var mfaVerify models.MFAVerify
+
+	err := ec.Bind(&mfaVerify)
+	if err != nil {
+		log.Err(err).Msg("BAD REQUEST")
+		return ec.BadRequest(err)
+	}
+
+	if !mfaVerify.Validate() {
+		log.Err(err).Msg("BAD REQUEST, empty MFA token or code")
+		return ec.BadRequest(err)
+	}
+
+	userData, err := u.verifyMFAChallenge(mfaVerify.Token, mfaVerify.Code)
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFAChallenge) || errors.Is(err, ErrInvalidMFACode) {
+			log.Err(err).Msg("UNAUTHORIZED, MFA check failed")
+			return ec.Unauthorized(err)
+		}
+
+		if errors.Is(err, ErrUserNotActivated) || authv1.IsForbidden(err) {
+			log.Err(err).Msg("FORBIDDEN")
+			return ec.Forbidden(err)
+		}
+
+		if errors.Is(err, ErrMFALocked) {
+			log.Err(err).Msg("TOO MANY REQUESTS")
+			return ec.JSON(http.StatusTooManyRequests, MFALocked())
+		}
+
+		log.Err(err).Msg("MFA CHECK FAILED")
+
+		return ec.InternalServerError(err)
+	}
+
+	return answer(ec, userData)
+}
+
+func (u *UserV1) loginMFAPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Login MFA Handler").
+			SetSummary("This handler exchanges MFA token from /auth/login and TOTP code for the session cookie").
+			AddInBodyParameter("mfa_verify", "MFA token and code", &models.MFAVerify{}, true).
+			AddResponse(http.StatusOK, "User Data", &UserDataResult{Body: models.User{}}).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusTooManyRequests, "TOO MANY FAILED MFA CODES", MFALocked())
+
+		return nil
+	}
+
+	// Main code of handler
+	return u.mfaLogin(ec, u.sessionAnswer)
+}
+
+func (u *UserV1) mfaPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Enroll MFA Handler").
+			SetSummary("This handler generates a new TOTP secret of the user by user_id, MFA is enabled after the confirmation by a code").
+			AddInPathParameter("id", "User id", reflect.Int64).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "TOTP secret and otpauth URI", &MFAEnrollmentResult{Body: models.MFAEnrollment{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err)).
+			AddResponse(http.StatusConflict, "MFA IS ALREADY ENABLED", httpsrv.NotUpdated(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	id, err := ec.GetInt64Param("id")
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, id %s", ec.Param("id"))
+		return ec.BadRequest(err)
+	}
+
+	// The secret is known to the caller, so nobody enrolls MFA of the user with the higher role
+	caller, err := callerOf(ec)
+	if err == nil {
+		err = u.checkChange(caller, id, nil)
+	}
+
+	if err != nil {
+		if isForbidden(err) {
+			log.Err(err).Msgf("FORBIDDEN, id %d", id)
+			return ec.Forbidden(err)
+		}
+
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Err(err).Msgf("NOT FOUND, id %d", id)
+			return ec.NotFound(err)
+		}
+
+		log.Err(err).Msgf("DATA NOT UPDATED, id %d", id)
+
+		return ec.NotUpdated(err)
+	}
+
+	enrollment, err := u.enrollMFA(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Err(err).Msgf("NOT FOUND, id %d", id)
+			return ec.NotFound(err)
+		}
+
+		log.Err(err).Msgf("DATA NOT UPDATED, id %d", id)
+
+		return ec.NotUpdated(err)
+	}
+
+	return ec.OK(MFAEnrollmentResult{Body: enrollment})
+}
+
+func (u *UserV1) mfaConfirmPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Confirm MFA Handler").
+			SetSummary("This handler enables MFA of the user by user_id by the first TOTP code of the enrolled secret").
+			AddInBodyParameter("mfa_code", "TOTP code", &models.MFACode{}, true).
+			AddInPathParameter("id", "User id", reflect.Int64).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "OK", httpsrv.OkResult()).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusConflict, "MFA IS NOT ENROLLED OR ALREADY ENABLED", httpsrv.NotUpdated(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	id, err := ec.GetInt64Param("id")
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, id %s", ec.Param("id"))
+		return ec.BadRequest(err)
+	}
+
+	var mfaCode models.MFACode
+
+	err = ec.Bind(&mfaCode)
+	if err != nil {
+		log.Err(err).Msg("BAD REQUEST")
+		return ec.BadRequest(err)
+	}
+
+	if !mfaCode.Validate() {
+		log.Err(err).Msgf("BAD REQUEST, empty code, id %d", id)
+		return ec.BadRequest(err)
+	}
+
+	caller, err := callerOf(ec)
+	if err == nil {
+		err = u.checkChange(caller, id, nil)
+	}
+
+	if err != nil {
+		if isForbidden(err) {
+			log.Err(err).Msgf("FORBIDDEN, id %d", id)
+			return ec.Forbidden(err)
+		}
+
+		log.Err(err).Msgf("DATA NOT UPDATED, id %d", id)
+
+		return ec.NotUpdated(err)
+	}
+
+	if err = u.confirmMFA(id, mfaCode.Code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			log.Err(err).Msgf("BAD REQUEST, id %d", id)
+			return ec.BadRequest(err)
+		}
+
+		log.Err(err).Msgf("DATA NOT UPDATED, id %d", id)
+
+		return ec.NotUpdated(err)
+	}
+
+	return ec.OkResult()
+}
+
+func (u *UserV1) mfaDeleteHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Reset MFA Handler").
+			SetSummary("This handler disables MFA of the user by user_id, e.g. if the user lost the authenticator").
+			AddInPathParameter("id", "User id", reflect.Int64).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "OK", httpsrv.OkResult()).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "FORBIDDEN", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err)).
+			AddResponse(http.StatusConflict, "DATA NOT DELETED", httpsrv.NotDeleted(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	id, err := ec.GetInt64Param("id")
+	if err != nil {
+		log.Err(err).Msgf("BAD REQUEST, id %s", ec.Param("id"))
+		return ec.BadRequest(err)
+	}
+
+	if err = u.resetMFA(id); err != nil {
+		if errors.Is(err, ErrMFANotEnrolled) {
+			log.Err(err).Msgf("NOT FOUND, id %d", id)
+			return ec.NotFound(err)
+		}
+
+		log.Err(err).Msgf("DATA NOT DELETED, id %d", id)
+
+		return ec.NotDeleted(err)
+	}
+
+	return ec.OkResult()
+}
+
+func (u *UserV1) webauthnRegisterBeginPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Begin Passkey Registration Handler").
+			SetSummary("This handler returns options of navigator.credentials.create for a new passkey of the caller").
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "Creation options", &WebAuthnOptionsResult{Body: webauthn.CreationOptions{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	id, err := callerID(ec)
+	if err != nil {
+		log.Err(err).Msg("UNAUTHORIZED")
+		return ec.Unauthorized(err)
+	}
+
+	options, err := u.beginWebAuthnRegistration(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Err(err).Msgf("NOT FOUND, id %d", id)
+			return ec.NotFound(err)
+		}
+
+		log.Err(err).Msgf("BEGIN PASSKEY REGISTRATION FAILED, id %d", id)
+
+		return ec.InternalServerError(err)
+	}
+
+	return ec.OK(WebAuthnOptionsResult{Body: options})
+}
+
+func (u *UserV1) webauthnRegisterFinishPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Finish Passkey Registration Handler").
+			SetSummary("This handler verifies the result of navigator.credentials.create and stores the passkey of the caller").
+			AddInBodyParameter("registration", "Passkey registration", &models.WebAuthnRegistration{}, true).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "Passkey", &WebAuthnCredentialResult{Body: models.WebAuthnCredential{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusConflict, "PASSKEY IS ALREADY REGISTERED", httpsrv.NotUpdated(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	id, err := callerID(ec)
+	if err != nil {
+		log.Err(err).Msg("UNAUTHORIZED")
+		return ec.Unauthorized(err)
+	}
+
+	var registration models.WebAuthnRegistration
+
+	err = ec.Bind(&registration)
+	if err != nil {
+		log.Err(err).Msg("BAD REQUEST")
+		return ec.BadRequest(err)
+	}
+
+	if !registration.Validate() {
+		log.Err(err).Msgf("BAD REQUEST, empty credential, id %d", id)
+		return ec.BadRequest(err)
+	}
+
+	credential, err := u.finishWebAuthnRegistration(id, &registration)
+	if err != nil {
+		if errors.Is(err, ErrPasskeyIsRegistered) {
+			log.Err(err).Msgf("DATA NOT UPDATED, id %d", id)
+			return ec.NotUpdated(err)
+		}
+
+		log.Err(err).Msgf("BAD REQUEST, passkey registration failed, id %d", id)
+
+		return ec.BadRequest(err)
+	}
+
+	return ec.OK(WebAuthnCredentialResult{Body: credential})
+}
+
+func (u *UserV1) webauthnLoginBeginPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Begin Passkey Login Handler").
+			SetSummary("This handler returns options of navigator.credentials.get, the passkey is chosen by the user").
+			AddResponse(http.StatusOK, "Request options", &WebAuthnOptionsResult{Body: webauthn.RequestOptions{}})
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	options, err := u.beginWebAuthnLogin()
+	if err != nil {
+		log.Err(err).Msg("BEGIN PASSKEY LOGIN FAILED")
+		return ec.InternalServerError(err)
+	}
+
+	return ec.OK(WebAuthnOptionsResult{Body: options})
+}
+
+func (u *UserV1) webauthnLoginFinishPostHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Finish Passkey Login Handler").
+			SetSummary("This handler verifies the result of navigator.credentials.get and creates a new session like /credentials. If the user isn't verified by the authenticator and MFA of the user is enabled, the handler returns MFA token for /credentials/mfa").
+			AddInBodyParameter("assertion", "PublicKeyCredential of navigator.credentials.get", &webauthn.AssertionResponse{}, true).
+			AddResponse(http.StatusOK, "Tokens and User Data or MFA token", &TokenAndUserResult{Body: models.TokenAndUser{}}).
+			AddResponse(http.StatusBadRequest, "BAD REQUEST", httpsrv.BadRequest(err)).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusForbidden, "USER IS NOT ACTIVATED", httpsrv.Forbidden(err)).
+			AddResponse(http.StatusTooManyRequests, "TOO MANY FAILED MFA CODES", MFALocked())
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	var assertion webauthn.AssertionResponse
+
+	err = ec.Bind(&assertion)
+	if err != nil {
+		log.Err(err).Msg("BAD REQUEST")
+		return ec.BadRequest(err)
+	}
+
+	userData, userVerified, err := u.finishWebAuthnLogin(&assertion)
+	if err != nil {
+		if errors.Is(err, ErrUserNotActivated) {
+			log.Err(err).Msgf("FORBIDDEN, passkey %s", assertion.ID)
+			return ec.Forbidden(err)
+		}
+
+		log.Err(err).Msgf("UNAUTHORIZED, passkey %s", assertion.ID)
+
+		return ec.Unauthorized(err)
+	}
+
+	// The passkey with user verification is the second factor itself,
+	// security keys without it prove only the possession, so MFA is asked like after the password
+	if !userVerified {
+		challenge, err := u.newMFAChallenge(userData.ID)
+		if err != nil {
+			if errors.Is(err, ErrMFALocked) {
+				log.Err(err).Msgf("TOO MANY REQUESTS, passkey %s", assertion.ID)
+				return ec.JSON(http.StatusTooManyRequests, MFALocked())
+			}
+
+			log.Err(err).Msgf("CREATE MFA CHALLENGE FAILED, passkey %s", assertion.ID)
+			return ec.InternalServerError(err)
+		}
+
+		if challenge != nil {
+			return ec.OK(TokenAndUserResult{Body: models.TokenAndUser{MFAToken: challenge.Token}})
+		}
+	}
+
+	return u.tokenPairAnswer(ec, userData)
+}
+
+func (u *UserV1) webauthnCredentialsGetHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Get Passkeys Handler").
+			SetSummary("This handler returns passkeys of the caller").
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "Passkeys", &WebAuthnCredentialsResult{Body: []models.WebAuthnCredential{}}).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	id, err := callerID(ec)
+	if err != nil {
+		log.Err(err).Msg("UNAUTHORIZED")
+		return ec.Unauthorized(err)
+	}
+
+	credentials, err := u.getWebAuthnCredentials(id)
+	if err != nil {
+		log.Err(err).Msgf("NOT FOUND, id %d", id)
+		return ec.NotFound(err)
+	}
+
+	return ec.OK(WebAuthnCredentialsResult{Body: credentials})
+}
+
+func (u *UserV1) webauthnCredentialDeleteHandler(ec echo.Context) (err error) {
+	// Swagger
+	if echoSwagger.IsBuildingSwagger(ec) {
+		err = fmt.Errorf("error")
+		echoSwagger.AddToSwagger(ec).
+			SetProduces("application/json").
+			SetDescription("Delete Passkey Handler").
+			SetSummary("This handler deletes the passkey of the caller by credential_id").
+			AddInPathParameter("credential_id", "Credential id", reflect.String).
+			AddInHeaderParameter("Authorization", "Bearer token", reflect.String, true).
+			AddResponse(http.StatusOK, "OK", httpsrv.OkResult()).
+			AddResponse(http.StatusUnauthorized, "UNAUTHORIZED", httpsrv.Unauthorized(err)).
+			AddResponse(http.StatusNotFound, "NOT FOUND DATA", httpsrv.NotFound(err)).
+			AddResponse(http.StatusConflict, "DATA NOT DELETED", httpsrv.NotDeleted(err))
+
+		return nil
+	}
+
+	// Main code of handler
+	log := ec.GetLog()
+
+	id, err := callerID(ec)
+	if err != nil {
+		log.Err(err).Msg("UNAUTHORIZED")
+		return ec.Unauthorized(err)
+	}
+
+	credentialID := ec.Param("credential_id")
+
+	if err = u.deleteWebAuthnCredential(id, credentialID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Err(err).Msgf("NOT FOUND, id %d, passkey %s", id, credentialID)
+			return ec.NotFound(err)
+		}
+
+		log.Err(err).Msgf("DATA NOT DELETED, id %d, passkey %s", id, credentialID)
+
+		return ec.NotDeleted(err)
+	}
+
+	return ec.OkResult()
+}
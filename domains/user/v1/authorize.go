@@ -0,0 +1,102 @@
+package userv1
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	authv1 "github.com/soldatov-s/go-garage-auth/domains/auth/v1"
+	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+	"github.com/soldatov-s/go-garage/providers/httpsrv/echo"
+)
+
+// callerOf returns the caller authenticated by authv1.RequireRole
+func callerOf(ec echo.Context) (*models.Token, error) {
+	caller, ok := authv1.Caller(ec)
+	if !ok {
+		return nil, authv1.ErrRoleNotAllowed
+	}
+
+	return caller, nil
+}
+
+// callerID returns the user ID of the caller authenticated by authv1.Require
+func callerID(ec echo.Context) (int64, error) {
+	caller, err := callerOf(ec)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(caller.Subject, 10, 64)
+}
+
+// isForbidden checks that the error is caused by the lack of rights
+func isForbidden(err error) bool {
+	return errors.Is(err, ErrAssignmentNotAllowed) || authv1.IsForbidden(err)
+}
+
+// checkAssignment checks that only managers of users assign the role and the status
+// and nobody assigns the role higher than its own
+func checkAssignment(caller *models.Token, role *goGarageAuthTypes.Role, statusChanged bool) error {
+	if !caller.HasPermission(goGarageAuthTypes.PermissionUsersWrite) && (role != nil || statusChanged) {
+		return ErrAssignmentNotAllowed
+	}
+
+	if role != nil && *role > caller.Role {
+		return ErrAssignmentNotAllowed
+	}
+
+	return nil
+}
+
+// checkPatchAssignment checks the role and the status changed by the merge patch
+func checkPatchAssignment(caller *models.Token, patch []byte) error {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(patch, &fields); err != nil {
+		return err
+	}
+
+	var role *goGarageAuthTypes.Role
+
+	if value, ok := fields["user_role"]; ok {
+		role = new(goGarageAuthTypes.Role)
+		if err := json.Unmarshal(value, role); err != nil {
+			return err
+		}
+	}
+
+	_, statusChanged := fields["user_status"]
+
+	return checkAssignment(caller, role, statusChanged)
+}
+
+// checkTarget checks that the caller isn't lower than the user, whose record is changed
+func (u *UserV1) checkTarget(caller *models.Token, id int64) error {
+	if caller.Subject == strconv.FormatInt(id, 10) {
+		return nil
+	}
+
+	target, err := u.GetUserDataByID(id)
+	if err != nil {
+		return err
+	}
+
+	if target.Role > caller.Role {
+		return authv1.ErrRoleNotAllowed
+	}
+
+	return nil
+}
+
+// checkChange checks that the caller is allowed to change the record of the user,
+// patch is nil if the role and the status aren't changed
+func (u *UserV1) checkChange(caller *models.Token, id int64, patch []byte) error {
+	if patch != nil {
+		if err := checkPatchAssignment(caller, patch); err != nil {
+			return err
+		}
+	}
+
+	return u.checkTarget(caller, id)
+}
@@ -0,0 +1,49 @@
+package userv1
+
+import (
+	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+)
+
+// bootstrapAdmin creates the active admin of the config if there is no admin yet.
+// Replicas might do it at once, the occupied email keeps only one of them.
+func (u *UserV1) bootstrapAdmin() {
+	// The connection is established and the migrations are applied after the start of the app
+	u.db.WaitForEstablishing()
+
+	var exists bool
+
+	err := u.db.Conn.Get(&exists, u.db.Conn.Rebind(
+		"SELECT EXISTS (SELECT 1 FROM production.user WHERE user_role=$1 AND deleted_at IS NULL)"),
+		goGarageAuthTypes.Admin)
+	if err != nil {
+		u.log.Err(err).Msg("failed to check admins")
+		return
+	}
+
+	if exists {
+		u.log.Debug().Msg("admin exists, bootstrap is skipped")
+		return
+	}
+
+	data, err := u.createUser(&models.NewCredentials{
+		Credentials: models.Credentials{
+			Email:    u.cfg.Bootstrap.AdminEmail,
+			Password: u.cfg.Bootstrap.AdminPassword,
+		},
+		Role:   goGarageAuthTypes.Admin,
+		Status: goGarageAuthTypes.Active,
+	})
+	if err != nil {
+		if err == ErrLoginOrEmailIsOccupied {
+			u.log.Warn().Msgf("bootstrap admin isn't created, email %s is occupied", u.cfg.Bootstrap.AdminEmail)
+			return
+		}
+
+		u.log.Err(err).Msg("failed to create bootstrap admin")
+
+		return
+	}
+
+	u.log.Info().Msgf("bootstrap admin is created, id %d", data.ID)
+}
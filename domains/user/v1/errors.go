@@ -12,6 +12,21 @@ var (
 	ErrNewPasswordIsSameAsOld = errors.New("new password is same as old")
 	ErrKeyDoNotMatch          = errors.New("key do not match")
 	ErrFailedTypeCast         = errors.New("failed typecast")
+	ErrAssignmentNotAllowed   = errors.New("assignment of the role or status is not allowed")
+	ErrUserNotActivated       = errors.New("user is not activated")
+	ErrUserIsActivated        = errors.New("user is already activated")
+	ErrInvalidActivation      = errors.New("activation token is invalid or expired")
+	ErrActivationRateLimited  = errors.New("activation token was generated recently")
+	ErrInvalidPasswordReset   = errors.New("password reset token is invalid or expired")
+	ErrPasswordResetLimited   = errors.New("password reset token was generated recently")
+	ErrMFAIsEnabled           = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled         = errors.New("two-factor authentication isn't enrolled")
+	ErrInvalidMFACode         = errors.New("invalid two-factor authentication code")
+	ErrInvalidMFAChallenge    = errors.New("two-factor authentication challenge is invalid or expired")
+	ErrMFALocked              = errors.New("two-factor authentication is locked after too many failed codes")
+	ErrInvalidWebAuthn        = errors.New("passkey ceremony is invalid or expired")
+	ErrUnknownPasskey         = errors.New("unknown passkey")
+	ErrPasskeyIsRegistered    = errors.New("passkey is already registered")
 )
 
 func EmailIsOccupied() httpsrv.ErrorAnsw {
@@ -21,3 +36,11 @@ func EmailIsOccupied() httpsrv.ErrorAnsw {
 func NewPasswordIsSameAsOld() httpsrv.ErrorAnsw {
 	return httpsrv.NewErrorAnsw(http.StatusConflict, "new password is same as old", ErrNewPasswordIsSameAsOld)
 }
+
+func ActivationRateLimited() httpsrv.ErrorAnsw {
+	return httpsrv.NewErrorAnsw(http.StatusTooManyRequests, "activation token was generated recently", ErrActivationRateLimited)
+}
+
+func MFALocked() httpsrv.ErrorAnsw {
+	return httpsrv.NewErrorAnsw(http.StatusTooManyRequests, "too many failed codes, try later", ErrMFALocked)
+}
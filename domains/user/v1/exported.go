@@ -4,8 +4,15 @@ import (
 	"context"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
+	apiv1 "github.com/soldatov-s/go-garage-auth/api/v1"
+	authv1 "github.com/soldatov-s/go-garage-auth/domains/auth/v1"
 	"github.com/soldatov-s/go-garage-auth/internal/cfg"
+	"github.com/soldatov-s/go-garage-auth/internal/csrf"
+	"github.com/soldatov-s/go-garage-auth/internal/grpcsrv"
+	"github.com/soldatov-s/go-garage-auth/internal/webauthn"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
 	"github.com/soldatov-s/go-garage/domains"
 	"github.com/soldatov-s/go-garage/providers/db/pq"
 	"github.com/soldatov-s/go-garage/providers/httpsrv/echo"
@@ -14,6 +21,8 @@ import (
 
 const (
 	DomainName = "userv1"
+
+	minimumMFASecretLength = 32
 )
 
 type empty struct{}
@@ -29,6 +38,8 @@ type UserV1 struct {
 	// cached value of current counter partitions
 	lastID int64
 	cfg    *cfg.Config
+	// relying party of passkeys
+	rp *webauthn.RelyingParty
 }
 
 func Registrate(ctx context.Context) (context.Context, error) {
@@ -37,7 +48,20 @@ func Registrate(ctx context.Context) (context.Context, error) {
 		log: logger.GetPackageLogger(ctx, empty{}),
 		cfg: cfg.Get(ctx),
 	}
+	if len(u.cfg.MFA.Secret) < minimumMFASecretLength {
+		return nil, errors.Errorf("secret for MFA is expected to be %d byte long, got %d byte",
+			minimumMFASecretLength, len(u.cfg.MFA.Secret))
+	}
+
+	if u.cfg.Bootstrap.AdminEmail != "" && u.cfg.Bootstrap.AdminPassword == "" {
+		return nil, errors.New("password of the bootstrap admin is empty")
+	}
+
 	var err error
+	if u.rp, err = webauthn.New(u.cfg.WebAuthn); err != nil {
+		return nil, err
+	}
+
 	if u.db, err = pq.GetEnityTypeCast(ctx, cfg.DBName); err != nil {
 		return nil, err
 	}
@@ -53,15 +77,79 @@ func Registrate(ctx context.Context) (context.Context, error) {
 		return nil, err
 	}
 
+	authV1, err := authv1.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every user is allowed to read and update the own record
 	grProtect := privateV1.Group
 	grProtect.Use(echo.HydrationLogger(&u.log))
-	grProtect.POST("/users", echo.Handler(u.userPostHandler))
-	grProtect.GET("/users/:id", echo.Handler(u.userGetHandler))
-	grProtect.PUT("/users/:id", echo.Handler(u.userPutHandler))
-	grProtect.PUT("/credentials/:id", echo.Handler(u.credsPutHandler))
+	grProtect.POST("/users", echo.Handler(u.userPostHandler),
+		authV1.RequirePermission(goGarageAuthTypes.PermissionUsersWrite, ""))
+	grProtect.GET("/users/:id", echo.Handler(u.userGetHandler),
+		authV1.RequirePermission(goGarageAuthTypes.PermissionUsersRead, "id"))
+	grProtect.PUT("/users/:id", echo.Handler(u.userPutHandler),
+		authV1.RequirePermission(goGarageAuthTypes.PermissionUsersWrite, "id"))
+	grProtect.PUT("/credentials/:id", echo.Handler(u.credsPutHandler),
+		authV1.RequirePermission(goGarageAuthTypes.PermissionUsersWrite, "id"))
+	// Credentials are checked by the handler itself
 	grProtect.POST("/credentials", echo.Handler(u.credsPostHandler))
-	grProtect.DELETE("/users/:id", echo.Handler(u.userDeleteHandler))
-	grProtect.POST("/users/search", echo.Handler(u.userSearchPostHandler))
+	grProtect.POST("/credentials/mfa", echo.Handler(u.credsMFAPostHandler))
+	grProtect.DELETE("/users/:id", echo.Handler(u.userDeleteHandler),
+		authV1.RequirePermission(goGarageAuthTypes.PermissionUsersDelete, ""))
+	grProtect.POST("/users/:id/activation", echo.Handler(u.activationPostHandler),
+		authV1.RequirePermission(goGarageAuthTypes.PermissionUsersWrite, ""))
+	grProtect.POST("/users/:id/mfa", echo.Handler(u.mfaPostHandler),
+		authV1.RequirePermission(goGarageAuthTypes.PermissionUsersWrite, "id"))
+	grProtect.POST("/users/:id/mfa/confirm", echo.Handler(u.mfaConfirmPostHandler),
+		authV1.RequirePermission(goGarageAuthTypes.PermissionUsersWrite, "id"))
+	grProtect.DELETE("/users/:id/mfa", echo.Handler(u.mfaDeleteHandler),
+		authV1.RequirePermission(goGarageAuthTypes.PermissionUsersMFAReset, ""))
+	grProtect.POST("/users/search", echo.Handler(u.userSearchPostHandler),
+		authV1.RequirePermission(goGarageAuthTypes.PermissionUsersRead, ""))
+
+	publicV1, err := echo.GetAPIVersionGroup(ctx, cfg.PublicHTTP, cfg.V1)
+	if err != nil {
+		return nil, err
+	}
+
+	csrfProtection, err := csrf.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	grPublic := publicV1.Group
+	grPublic.Use(echo.HydrationLogger(&u.log))
+	// Login sets the session cookie, so it needs CSRF token even without the session
+	csrfLogin := csrfProtection.LoginMiddleware()
+	grPublic.POST("/auth/login", echo.Handler(u.loginPostHandler), csrfLogin)
+	grPublic.POST("/auth/login/mfa", echo.Handler(u.loginMFAPostHandler), csrfLogin)
+	grPublic.POST("/auth/activate", echo.Handler(u.activatePostHandler))
+	grPublic.POST("/auth/activate/resend", echo.Handler(u.activationResendPostHandler))
+	grPublic.POST("/auth/password/forgot", echo.Handler(u.passwordForgotPostHandler))
+	grPublic.POST("/auth/password/reset", echo.Handler(u.passwordResetPostHandler))
+	grPublic.POST("/auth/webauthn/login/begin", echo.Handler(u.webauthnLoginBeginPostHandler))
+	grPublic.POST("/auth/webauthn/login/finish", echo.Handler(u.webauthnLoginFinishPostHandler))
+
+	// Passkeys are managed by the user itself
+	authenticated := authV1.Require(authv1.Authenticated(), "")
+	grPublic.POST("/auth/webauthn/register/begin", echo.Handler(u.webauthnRegisterBeginPostHandler), authenticated)
+	grPublic.POST("/auth/webauthn/register/finish", echo.Handler(u.webauthnRegisterFinishPostHandler), authenticated)
+	grPublic.GET("/auth/webauthn/credentials", echo.Handler(u.webauthnCredentialsGetHandler), authenticated)
+	grPublic.DELETE("/auth/webauthn/credentials/:credential_id", echo.Handler(u.webauthnCredentialDeleteHandler),
+		authenticated)
+
+	grpcServer, err := grpcsrv.GetEnityTypeCast(ctx, cfg.GRPC)
+	if err != nil {
+		return nil, err
+	}
+
+	apiv1.RegisterUserServiceServer(grpcServer.Server, &GRPCServer{userV1: u})
+
+	if u.cfg.Bootstrap.AdminEmail != "" {
+		go u.bootstrapAdmin()
+	}
 
 	return domains.RegistrateByName(ctx, DomainName, u), nil
 }
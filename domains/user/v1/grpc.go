@@ -0,0 +1,459 @@
+package userv1
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	apiv1 "github.com/soldatov-s/go-garage-auth/api/v1"
+	authv1 "github.com/soldatov-s/go-garage-auth/domains/auth/v1"
+	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+	"github.com/soldatov-s/go-garage/crypto/sha256"
+	"github.com/soldatov-s/go-garage/providers/db"
+	"github.com/soldatov-s/go-garage/types"
+	"github.com/soldatov-s/go-garage/utils/email"
+	"github.com/soldatov-s/go-garage/utils/phone"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	errInvalidCredentials = errors.New("invalid credentials")
+	errInvalidMFAVerify   = errors.New("empty MFA token or code")
+)
+
+// GRPCServer implements UserService of gRPC API, it mirrors the private REST API
+type GRPCServer struct {
+	apiv1.UnimplementedUserServiceServer
+	userV1 *UserV1
+}
+
+// authorize authenticates the caller like the routes of REST API,
+// owner is the user ID of the record or 0 if the owner isn't allowed
+func (s *GRPCServer) authorize(ctx context.Context, permission string, owner int64) (*models.Token, error) {
+	authV1, err := authv1.Get(s.userV1.ctx)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	var ownerID string
+	if owner != 0 {
+		ownerID = strconv.FormatInt(owner, 10)
+	}
+
+	return authV1.AuthorizeGRPC(ctx, authv1.HasPermission(permission), ownerID)
+}
+
+func (s *GRPCServer) CreateUser(ctx context.Context, req *apiv1.CreateUserRequest) (*apiv1.User, error) {
+	userCreds := models.NewCredentials{
+		Credentials: credentialsFromProto(req.GetCredentials()),
+	}
+
+	var err error
+	if userCreds.Role, err = roleFromProto(req.GetUserRole()); err != nil {
+		return nil, grpcError(err)
+	}
+
+	if userCreds.Status, err = statusFromProto(req.GetUserStatus()); err != nil {
+		return nil, grpcError(err)
+	}
+
+	if !userCreds.Validate() {
+		return nil, grpcError(errInvalidCredentials)
+	}
+
+	caller, err := s.authorize(ctx, goGarageAuthTypes.PermissionUsersWrite, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkAssignment(caller, &userCreds.Role, true); err != nil {
+		return nil, grpcError(err)
+	}
+
+	userData, err := s.userV1.createUser(&userCreds)
+	if err != nil {
+		s.userV1.log.Err(err).Msgf("CREATE USER FAILED %s", &userCreds)
+		return nil, grpcError(err)
+	}
+
+	return userToProto(userData)
+}
+
+func (s *GRPCServer) GetUser(ctx context.Context, req *apiv1.GetUserRequest) (*apiv1.User, error) {
+	if _, err := s.authorize(ctx, goGarageAuthTypes.PermissionUsersRead, req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	userData, err := s.userV1.GetUserDataByID(req.GetUserId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return userToProto(userData)
+}
+
+func (s *GRPCServer) UpdateUser(ctx context.Context, req *apiv1.UpdateUserRequest) (*apiv1.User, error) {
+	patch, err := json.Marshal(req.GetPatch().AsMap())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	caller, err := s.authorize(ctx, goGarageAuthTypes.PermissionUsersWrite, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userV1.checkChange(caller, req.GetUserId(), patch); err != nil {
+		return nil, grpcError(err)
+	}
+
+	userData, err := s.userV1.updateUserByID(req.GetUserId(), &patch)
+	if err != nil {
+		s.userV1.log.Err(err).Msgf("DATA NOT UPDATED, id %d, patch %s", req.GetUserId(), string(patch))
+		return nil, grpcError(err)
+	}
+
+	return userToProto(userData)
+}
+
+func (s *GRPCServer) UpdateCredentials(ctx context.Context, req *apiv1.UpdateCredentialsRequest) (*apiv1.User, error) {
+	userCreds := models.UpdateCredentials{
+		Password:    req.GetUserPassword(),
+		OldPassword: req.GetUserOldPassword(),
+	}
+
+	if !userCreds.Validate() {
+		return nil, grpcError(errInvalidCredentials)
+	}
+
+	caller, err := s.authorize(ctx, goGarageAuthTypes.PermissionUsersWrite, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userV1.checkChange(caller, req.GetUserId(), nil); err != nil {
+		return nil, grpcError(err)
+	}
+
+	userData, err := s.userV1.updateUserCredsByID(req.GetUserId(), &userCreds)
+	if err != nil {
+		s.userV1.log.Err(err).Msgf("DATA NOT UPDATED, id %d", req.GetUserId())
+		return nil, grpcError(err)
+	}
+
+	return userToProto(userData)
+}
+
+func (s *GRPCServer) DeleteUser(ctx context.Context, req *apiv1.DeleteUserRequest) (*emptypb.Empty, error) {
+	permission := goGarageAuthTypes.PermissionUsersDelete
+	if req.GetHard() {
+		permission = goGarageAuthTypes.PermissionUsersHardDelete
+	}
+
+	caller, err := s.authorize(ctx, permission, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.userV1.checkChange(caller, req.GetUserId(), nil); err != nil {
+		return nil, grpcError(err)
+	}
+
+	if req.GetHard() {
+		err = s.userV1.hardDeleteUserByID(req.GetUserId())
+	} else {
+		err = s.userV1.softDeleteUserByID(req.GetUserId())
+	}
+
+	if err != nil {
+		s.userV1.log.Err(err).Msgf("DATA NOT DELETED, id %d", req.GetUserId())
+		return nil, grpcError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *GRPCServer) SearchUsers(ctx context.Context, req *apiv1.SearchUsersRequest) (*apiv1.SearchUsersResponse, error) {
+	if _, err := s.authorize(ctx, goGarageAuthTypes.PermissionUsersRead, 0); err != nil {
+		return nil, err
+	}
+
+	filters := make(ArrayOfMapInterface, 0, len(req.GetFilters()))
+	for _, filter := range req.GetFilters() {
+		filters = append(filters, filter.AsMap())
+	}
+
+	foundUsersData, err := s.userV1.getUserDataByUserData(&filters)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &apiv1.SearchUsersResponse{Users: make([]*apiv1.User, 0, len(*foundUsersData))}
+
+	for i := range *foundUsersData {
+		user, err := userToProto(&(*foundUsersData)[i])
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Users = append(resp.Users, user)
+	}
+
+	return resp, nil
+}
+
+func (s *GRPCServer) CheckCredentials(
+	ctx context.Context,
+	req *apiv1.CheckCredentialsRequest) (*apiv1.CheckCredentialsResponse, error) {
+	userCreds := credentialsFromProto(req.GetCredentials())
+	if !userCreds.Validate() {
+		return nil, grpcError(errInvalidCredentials)
+	}
+
+	userData, err := s.userV1.GetUserDataByCreds(&userCreds)
+	if errors.Is(err, ErrUserNotActivated) {
+		s.userV1.log.Err(err).Msgf("FORBIDDEN, userCreds %s", &userCreds)
+		return nil, grpcError(err)
+	}
+
+	if err != nil {
+		s.userV1.log.Err(err).Msgf("UNAUTHORIZED, userCreds %s", &userCreds)
+		// The answer must not disclose whether the user exists
+		return nil, status.Error(codes.Unauthenticated, errInvalidCredentials.Error())
+	}
+
+	challenge, err := s.userV1.newMFAChallenge(userData.ID)
+	if err != nil {
+		s.userV1.log.Err(err).Msgf("CREATE MFA CHALLENGE FAILED %+v", &userCreds)
+		return nil, grpcError(err)
+	}
+
+	// The tokens are issued by VerifyMFA after the check of TOTP code
+	if challenge != nil {
+		return &apiv1.CheckCredentialsResponse{MfaToken: challenge.Token}, nil
+	}
+
+	return s.tokenPair(ctx, userData)
+}
+
+func (s *GRPCServer) VerifyMFA(
+	ctx context.Context,
+	req *apiv1.VerifyMFARequest) (*apiv1.CheckCredentialsResponse, error) {
+	mfaVerify := models.MFAVerify{Token: req.GetMfaToken(), Code: req.GetMfaCode()}
+	if !mfaVerify.Validate() {
+		return nil, grpcError(errInvalidMFAVerify)
+	}
+
+	userData, err := s.userV1.verifyMFAChallenge(mfaVerify.Token, mfaVerify.Code)
+	if errors.Is(err, ErrInvalidMFAChallenge) || errors.Is(err, ErrInvalidMFACode) {
+		s.userV1.log.Err(err).Msg("UNAUTHORIZED, MFA check failed")
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return s.tokenPair(ctx, userData)
+}
+
+// tokenPair creates a new session of the user
+func (s *GRPCServer) tokenPair(ctx context.Context, userData *models.User) (*apiv1.CheckCredentialsResponse, error) {
+	authV1, err := authv1.Get(s.userV1.ctx)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	tokenPair, err := authV1.CreateTokenPair(int(userData.ID), authv1.SessionClientFromContext(ctx))
+	if err != nil {
+		s.userV1.log.Err(err).Msgf("CREATE SESSION FAILED, id %d", userData.ID)
+		return nil, grpcError(err)
+	}
+
+	user, err := userToProto(userData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiv1.CheckCredentialsResponse{
+		Token:        tokenPair.Token,
+		RefreshToken: tokenPair.RefreshToken,
+		User:         user,
+	}, nil
+}
+
+func (s *GRPCServer) ResendActivation(ctx context.Context, req *apiv1.ResendActivationRequest) (*apiv1.User, error) {
+	if _, err := s.authorize(ctx, goGarageAuthTypes.PermissionUsersWrite, 0); err != nil {
+		return nil, err
+	}
+
+	userData, err := s.userV1.resendActivation(req.GetUserId())
+	if err != nil {
+		s.userV1.log.Err(err).Msgf("DATA NOT UPDATED, id %d", req.GetUserId())
+		return nil, grpcError(err)
+	}
+
+	return userToProto(userData)
+}
+
+func (s *GRPCServer) EnrollMFA(ctx context.Context, req *apiv1.EnrollMFARequest) (*apiv1.EnrollMFAResponse, error) {
+	if _, err := s.authorize(ctx, goGarageAuthTypes.PermissionUsersWrite, req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	enrollment, err := s.userV1.enrollMFA(req.GetUserId())
+	if err != nil {
+		s.userV1.log.Err(err).Msgf("DATA NOT UPDATED, id %d", req.GetUserId())
+		return nil, grpcError(err)
+	}
+
+	return &apiv1.EnrollMFAResponse{MfaSecret: enrollment.Secret, OtpauthUri: enrollment.URI}, nil
+}
+
+func (s *GRPCServer) ConfirmMFA(ctx context.Context, req *apiv1.ConfirmMFARequest) (*emptypb.Empty, error) {
+	mfaCode := models.MFACode{Code: req.GetMfaCode()}
+	if !mfaCode.Validate() {
+		return nil, grpcError(ErrInvalidMFACode)
+	}
+
+	if _, err := s.authorize(ctx, goGarageAuthTypes.PermissionUsersWrite, req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.userV1.confirmMFA(req.GetUserId(), mfaCode.Code); err != nil {
+		s.userV1.log.Err(err).Msgf("DATA NOT UPDATED, id %d", req.GetUserId())
+		return nil, grpcError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *GRPCServer) ResetMFA(ctx context.Context, req *apiv1.ResetMFARequest) (*emptypb.Empty, error) {
+	if _, err := s.authorize(ctx, goGarageAuthTypes.PermissionUsersMFAReset, 0); err != nil {
+		return nil, err
+	}
+
+	if err := s.userV1.resetMFA(req.GetUserId()); err != nil {
+		s.userV1.log.Err(err).Msgf("DATA NOT DELETED, id %d", req.GetUserId())
+		return nil, grpcError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// grpcError maps errors of the repository to gRPC status codes
+func grpcError(err error) error {
+	code := codes.Internal
+
+	switch {
+	case errors.Is(err, ErrLoginOrEmailIsOccupied):
+		code = codes.AlreadyExists
+	case errors.Is(err, ErrNewPasswordIsSameAsOld),
+		errors.Is(err, ErrUserIsActivated),
+		errors.Is(err, ErrMFAIsEnabled),
+		errors.Is(err, ErrMFANotEnrolled):
+		code = codes.FailedPrecondition
+	case errors.Is(err, sql.ErrNoRows):
+		code = codes.NotFound
+	case errors.Is(err, sha256.ErrMismatchedHashAndPassword),
+		errors.Is(err, ErrAssignmentNotAllowed),
+		errors.Is(err, ErrUserNotActivated),
+		authv1.IsForbidden(err):
+		code = codes.PermissionDenied
+	case errors.Is(err, ErrActivationRateLimited),
+		errors.Is(err, ErrMFALocked):
+		code = codes.ResourceExhausted
+	case errors.Is(err, db.ErrDBConnNotEstablished):
+		code = codes.Unavailable
+	case errors.Is(err, ErrKeyDoNotMatch),
+		errors.Is(err, ErrFailedTypeCast),
+		errors.Is(err, errInvalidCredentials),
+		errors.Is(err, errInvalidMFAVerify),
+		errors.Is(err, ErrInvalidMFACode),
+		errors.Is(err, models.ErrEmptyMail),
+		errors.Is(err, email.ErrNormilizeEmail),
+		errors.Is(err, phone.ErrNormilizeEmail),
+		errors.Is(err, goGarageAuthTypes.ErrBadRole),
+		errors.Is(err, goGarageAuthTypes.ErrBadStatus):
+		code = codes.InvalidArgument
+	}
+
+	return status.Error(code, err.Error())
+}
+
+func credentialsFromProto(c *apiv1.Credentials) models.Credentials {
+	return models.Credentials{
+		Password: c.GetUserPassword(),
+		Login:    c.GetUserLogin(),
+		Email:    c.GetUserEmail(),
+		Phone:    c.GetUserPhone(),
+	}
+}
+
+// roleFromProto parses the role, empty role is the default role
+func roleFromProto(name string) (goGarageAuthTypes.Role, error) {
+	if name == "" {
+		return goGarageAuthTypes.RestrictedUser, nil
+	}
+
+	role, ok := goGarageAuthTypes.StringToRole()[name]
+	if !ok {
+		return role, goGarageAuthTypes.ErrBadRole
+	}
+
+	return role, nil
+}
+
+// statusFromProto parses the status, empty status is the default status
+func statusFromProto(name string) (goGarageAuthTypes.Status, error) {
+	if name == "" {
+		return goGarageAuthTypes.New, nil
+	}
+
+	userStatus, ok := goGarageAuthTypes.StringToStatus()[name]
+	if !ok {
+		return userStatus, goGarageAuthTypes.ErrBadStatus
+	}
+
+	return userStatus, nil
+}
+
+func userToProto(u *models.User) (*apiv1.User, error) {
+	user := &apiv1.User{
+		UserId:     u.ID,
+		UserLogin:  u.Login,
+		UserEmail:  u.Email,
+		UserPhone:  u.Phone,
+		UserStatus: u.Status.String(),
+		UserRole:   u.Role.String(),
+		CreatedAt:  timeToProto(u.CreatedAt),
+		UpdatedAt:  timeToProto(u.UpdatedAt),
+		DeletedAt:  timeToProto(u.DeletedAt),
+	}
+
+	if u.Meta.Valid {
+		meta, err := structpb.NewStruct(u.Meta.Map)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		user.UserMeta = meta
+	}
+
+	return user, nil
+}
+
+func timeToProto(t types.NullTime) *timestamppb.Timestamp {
+	if !t.Valid {
+		return nil
+	}
+
+	return timestamppb.New(t.Time)
+}
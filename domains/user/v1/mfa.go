@@ -0,0 +1,303 @@
+package userv1
+
+import (
+	dbsql "database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	authv1 "github.com/soldatov-s/go-garage-auth/domains/auth/v1"
+	"github.com/soldatov-s/go-garage-auth/internal/totp"
+	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+	"github.com/soldatov-s/go-garage/providers/db"
+	"github.com/soldatov-s/go-garage/types"
+)
+
+// userMFA is TOTP secret of the user
+type userMFA struct {
+	UserID      int64  `db:"user_id"`
+	Secret      string `db:"mfa_secret"`
+	LastCounter int64  `db:"mfa_last_counter"`
+	// FailedAttempts is a number of codes checked by challenges since the last valid code
+	FailedAttempts int            `db:"mfa_failed_attempts"`
+	LockedUntil    types.NullTime `db:"mfa_locked_until"`
+	ConfirmedAt    types.NullTime `db:"mfa_confirmed_at"`
+	CreatedAt      time.Time      `db:"created_at"`
+	UpdatedAt      time.Time      `db:"updated_at"`
+}
+
+// enrollMFA generates a new TOTP secret of the user, it replaces the unconfirmed one
+func (u *UserV1) enrollMFA(id int64) (*models.MFAEnrollment, error) {
+	data, err := u.GetUserDataByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := totp.Seal(u.cfg.MFA.Secret, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	now := time.Now()
+
+	result, err := u.db.Conn.Exec(`INSERT INTO production.user_mfa (user_id, mfa_secret, created_at, updated_at)
+		VALUES ($1, $2, $3, $3) ON CONFLICT (user_id) DO UPDATE SET mfa_secret=EXCLUDED.mfa_secret, mfa_last_counter=0,
+		updated_at=EXCLUDED.updated_at WHERE production.user_mfa.mfa_confirmed_at IS NULL`, id, sealed, now)
+	if err != nil {
+		return nil, err
+	}
+
+	countRow, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if countRow == 0 {
+		return nil, ErrMFAIsEnabled
+	}
+
+	account := data.Email
+	if data.Login != "" {
+		account = data.Login
+	}
+
+	return &models.MFAEnrollment{
+		Secret: totp.EncodeSecret(secret),
+		URI:    totp.URI(u.cfg.MFA.Issuer, account, secret),
+	}, nil
+}
+
+// checkMFACode checks TOTP code of the user, every code can be used once.
+// The enrollment is checked by the code before it is confirmed.
+func (u *UserV1) checkMFACode(id int64, code string, confirmed bool) error {
+	if u.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	var data userMFA
+
+	err := u.db.Conn.Get(&data, "select * from production.user_mfa where user_id=$1", id)
+	if errors.Is(err, dbsql.ErrNoRows) {
+		return ErrMFANotEnrolled
+	}
+
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case data.ConfirmedAt.Valid && !confirmed:
+		return ErrMFAIsEnabled
+	case !data.ConfirmedAt.Valid && confirmed:
+		return ErrMFANotEnrolled
+	}
+
+	secret, err := totp.Open(u.cfg.MFA.Secret, data.Secret)
+	if err != nil {
+		return err
+	}
+
+	counter, ok := totp.Validate(secret, code, time.Now(), u.cfg.MFA.Skew, data.LastCounter)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	result, err := u.db.Conn.Exec(`UPDATE production.user_mfa SET mfa_last_counter=$1, mfa_failed_attempts=0,
+		mfa_locked_until=NULL, updated_at=$2 WHERE user_id=$3 AND mfa_last_counter<$1`, counter, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	countRow, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// The code or a later one was used concurrently
+	if countRow == 0 {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+// confirmMFA enables MFA of the user by the first code of the enrolled secret
+func (u *UserV1) confirmMFA(id int64, code string) error {
+	if err := u.checkMFACode(id, code, false); err != nil {
+		return err
+	}
+
+	_, err := u.db.Conn.Exec("UPDATE production.user_mfa SET mfa_confirmed_at=$1, updated_at=$1 WHERE user_id=$2",
+		time.Now(), id)
+
+	return err
+}
+
+// resetMFA disables MFA of the user, the user may enroll again
+func (u *UserV1) resetMFA(id int64) error {
+	if u.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	result, err := u.db.Conn.Exec("DELETE FROM production.user_mfa WHERE user_id=$1", id)
+	if err != nil {
+		return err
+	}
+
+	countRow, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if countRow == 0 {
+		return ErrMFANotEnrolled
+	}
+
+	_, err = u.db.Conn.Exec("DELETE FROM production.mfa_challenge WHERE user_id=$1", id)
+
+	return err
+}
+
+// newMFAChallenge returns a challenge if MFA of the user is enabled, otherwise it returns nil
+func (u *UserV1) newMFAChallenge(id int64) (*models.MFAChallenge, error) {
+	if u.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	var data userMFA
+
+	err := u.db.Conn.Get(&data, "select * from production.user_mfa where user_id=$1 and mfa_confirmed_at is not null", id)
+	if errors.Is(err, dbsql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	if data.LockedUntil.Valid && data.LockedUntil.Time.After(now) {
+		return nil, ErrMFALocked
+	}
+
+	token, err := newOneTimeToken()
+	if err != nil {
+		return nil, err
+	}
+
+	expiredAt := now.Add(u.cfg.MFA.ChallengeTTL)
+
+	_, err = u.db.Conn.Exec(`INSERT INTO production.mfa_challenge (challenge_hash, user_id, expired_at, created_at)
+		VALUES ($1, $2, $3, $4)`, hashToken(token), id, expiredAt, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MFAChallenge{Token: token, ExpiredAt: expiredAt.Unix()}, nil
+}
+
+// countMFAAttempt counts the code of the user before the check like the attempts of the challenge,
+// the valid code resets the counter. After MaxFailedAttempts the user is locked out for LockoutPeriod.
+func (u *UserV1) countMFAAttempt(id int64) error {
+	now := time.Now()
+
+	// The expired lockout starts the counter again
+	result, err := u.db.Conn.Exec(`UPDATE production.user_mfa SET
+		mfa_failed_attempts=CASE WHEN mfa_locked_until IS NULL THEN mfa_failed_attempts+1 ELSE 1 END,
+		mfa_locked_until=CASE WHEN (CASE WHEN mfa_locked_until IS NULL THEN mfa_failed_attempts+1 ELSE 1 END)>=$2
+			THEN $3::timestamptz END
+		WHERE user_id=$1 AND (mfa_locked_until IS NULL OR mfa_locked_until<=$4)`,
+		id, u.cfg.MFA.MaxFailedAttempts, now.Add(u.cfg.MFA.LockoutPeriod), now)
+	if err != nil {
+		return err
+	}
+
+	countRow, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if countRow == 0 {
+		return ErrMFALocked
+	}
+
+	return nil
+}
+
+// verifyMFAChallenge checks TOTP code of the challenge and returns the user, the challenge can be used once.
+// Every attempt is counted before the check, so concurrent requests can't exceed MaxAttempts.
+func (u *UserV1) verifyMFAChallenge(token, code string) (*models.User, error) {
+	if u.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	hash := hashToken(token)
+
+	var id int64
+
+	err := u.db.Conn.Get(&id, `UPDATE production.mfa_challenge SET challenge_attempts=challenge_attempts+1
+		WHERE challenge_hash=$1 AND expired_at>$2 AND challenge_attempts<$3 RETURNING user_id`,
+		hash, time.Now(), u.cfg.MFA.MaxAttempts)
+	if errors.Is(err, dbsql.ErrNoRows) {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = u.countMFAAttempt(id); err != nil {
+		return nil, err
+	}
+
+	if err = u.checkMFACode(id, code, true); err != nil {
+		return nil, err
+	}
+
+	result, err := u.db.Conn.Exec("DELETE FROM production.mfa_challenge WHERE challenge_hash=$1", hash)
+	if err != nil {
+		return nil, err
+	}
+
+	countRow, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	// The challenge was used by a concurrent request
+	if countRow == 0 {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	// The user might be deleted, restricted or deactivated after the password check
+	data, err := u.GetUserDataByID(id)
+	if errors.Is(err, dbsql.ErrNoRows) {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case data.DeletedAt.Valid:
+		return nil, ErrInvalidMFAChallenge
+	case data.Status == goGarageAuthTypes.New:
+		return nil, ErrUserNotActivated
+	case data.Status == goGarageAuthTypes.Restricted:
+		return nil, fmt.Errorf("%w: user %d", authv1.ErrUserRestricted, id)
+	}
+
+	return data, nil
+}
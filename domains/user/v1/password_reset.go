@@ -0,0 +1,123 @@
+package userv1
+
+import (
+	dbsql "database/sql"
+	"errors"
+	"time"
+
+	"github.com/soldatov-s/go-garage-auth/internal/mailer"
+	"github.com/soldatov-s/go-garage-auth/models"
+	"github.com/soldatov-s/go-garage/crypto/sha256"
+	"github.com/soldatov-s/go-garage/providers/db"
+	"github.com/soldatov-s/go-garage/utils/email"
+)
+
+// forgotPassword mails a new reset token to the user with the email, the previous token becomes invalid
+func (u *UserV1) forgotPassword(address string) error {
+	normalEmail, err := email.Normilize(address)
+	if err != nil {
+		return err
+	}
+
+	if u.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	data := &models.User{}
+	if err = u.db.Conn.Get(data, "select * from production.emailFastSearch($1)", normalEmail); err != nil {
+		return err
+	}
+
+	if data.DeletedAt.Valid {
+		return dbsql.ErrNoRows
+	}
+
+	token, err := newOneTimeToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiredAt := now.Add(u.cfg.PasswordReset.TTL)
+
+	// The previous token is replaced only if it is older than ResendInterval
+	result, err := u.db.Conn.Exec(`INSERT INTO production.password_reset (user_id, reset_hash, expired_at, created_at)
+		VALUES ($1, $2, $3, $4) ON CONFLICT (user_id) DO UPDATE SET reset_hash=EXCLUDED.reset_hash,
+		expired_at=EXCLUDED.expired_at, created_at=EXCLUDED.created_at WHERE production.password_reset.created_at<=$5`,
+		data.ID, hashToken(token), expiredAt, now, now.Add(-u.cfg.PasswordReset.ResendInterval))
+	if err != nil {
+		return err
+	}
+
+	countRow, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if countRow == 0 {
+		return ErrPasswordResetLimited
+	}
+
+	u.sendMail(mailer.TypePasswordReset, data, map[string]interface{}{
+		"Token":     token,
+		"ExpiredAt": expiredAt,
+	})
+
+	return nil
+}
+
+// resetPassword sets a new password by the reset token and revokes all sessions of the user,
+// the token can be used once
+func (u *UserV1) resetPassword(token, password string) error {
+	passwordHash, err := sha256.HashAndSalt(password)
+	if err != nil {
+		return err
+	}
+
+	if u.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	tx, err := u.db.Conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	var userID int64
+
+	err = tx.Get(&userID, "DELETE FROM production.password_reset WHERE reset_hash=$1 AND expired_at>$2 RETURNING user_id",
+		hashToken(token), time.Now())
+	if err != nil {
+		_ = tx.Rollback()
+
+		if errors.Is(err, dbsql.ErrNoRows) {
+			return ErrInvalidPasswordReset
+		}
+
+		return err
+	}
+
+	result, err := tx.Exec("UPDATE production.user SET user_hash=$1, updated_at=$2 WHERE user_id=$3 AND deleted_at IS NULL",
+		passwordHash, time.Now(), userID)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	countRow, err := result.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if countRow == 0 {
+		_ = tx.Rollback()
+		return ErrInvalidPasswordReset
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	return u.revokeAllTokens(userID)
+}
@@ -9,7 +9,9 @@ import (
 
 	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/jmoiron/sqlx"
+	authv1 "github.com/soldatov-s/go-garage-auth/domains/auth/v1"
 	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
 	"github.com/soldatov-s/go-garage/crypto/sha256"
 	"github.com/soldatov-s/go-garage/providers/db"
 	"github.com/soldatov-s/go-garage/types"
@@ -122,6 +124,13 @@ func (u *UserV1) createUser(c *models.NewCredentials) (data *models.User, err er
 
 	data.CreateTimestamp()
 
+	// New user must be activated by the token
+	if data.Status == goGarageAuthTypes.New {
+		if err = u.newActivation(data); err != nil {
+			return nil, err
+		}
+	}
+
 	if u.db.Conn == nil {
 		return nil, db.ErrDBConnNotEstablished
 	}
@@ -148,6 +157,10 @@ func (u *UserV1) createUser(c *models.NewCredentials) (data *models.User, err er
 		go u.createUserPartitions(data.ID)
 	}
 
+	if data.ActivationToken != "" {
+		u.sendActivation(data)
+	}
+
 	return data, nil
 }
 
@@ -198,6 +211,10 @@ func (u *UserV1) GetUserDataByCreds(c *models.Credentials) (data *models.User, e
 		return nil, err
 	}
 
+	if data.Status == goGarageAuthTypes.New {
+		return nil, ErrUserNotActivated
+	}
+
 	return data, nil
 }
 
@@ -321,6 +338,8 @@ func (u *UserV1) mergeUserData(oldData *models.User, patch *[]byte) (newData *mo
 	// Save hash
 	Hash := oldData.Hash
 	ActivationHash := oldData.ActivationHash
+	ActivationExpiredAt := oldData.ActivationExpiredAt
+	ActivationSentAt := oldData.ActivationSentAt
 
 	err = json.Unmarshal(merged, &newData)
 	if err != nil {
@@ -352,6 +371,11 @@ func (u *UserV1) mergeUserData(oldData *models.User, patch *[]byte) (newData *mo
 		newData.ActivationHash = ActivationHash
 	}
 
+	// Activation is changed only by the activation flow
+	newData.ActivationExpiredAt = ActivationExpiredAt
+	newData.ActivationSentAt = ActivationSentAt
+	newData.ActivationToken = ""
+
 	err = newData.Validate()
 	if err != nil {
 		return nil, err
@@ -457,12 +481,58 @@ func (u *UserV1) softDeleteUserByID(id int64) (err error) {
 	_, err = u.db.Conn.NamedExec(
 		u.db.Conn.Rebind(utils.JoinStrings(" ", "UPDATE production.user SET", strings.Join(query, ", "), "WHERE user_id=:user_id")),
 		data)
+	if err != nil {
+		return err
+	}
 
-	return
+	return u.revokeAllTokens(id)
 }
 
 func (u *UserV1) hardDeleteUserByID(id int64) (err error) {
-	return sql.HardDeleteByID(u.db.Conn, "production.user", id)
+	authV1, err := authv1.Get(u.ctx)
+	if err != nil {
+		return err
+	}
+
+	if u.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	tx, err := u.db.Conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	// Second factors, passkeys and pending tokens aren't partitioned with the user, so they are deleted separately
+	for _, table := range []string{
+		"production.user",
+		"production.user_mfa",
+		"production.mfa_challenge",
+		"production.webauthn_credential",
+		"production.password_reset",
+	} {
+		if _, err = tx.Exec("DELETE FROM "+table+" WHERE user_id=$1", id); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	if err = authV1.RevokeAllTokensTx(tx, int(id)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// revokeAllTokens revokes all sessions of the user
+func (u *UserV1) revokeAllTokens(id int64) error {
+	authV1, err := authv1.Get(u.ctx)
+	if err != nil {
+		return err
+	}
+
+	return authV1.RevokeAllTokens(int(id), "")
 }
 
 func (u *UserV1) updateUserCredsByID(id int64, c *models.UpdateCredentials) (data *models.User, err error) {
@@ -517,5 +587,12 @@ func (u *UserV1) updateUserCredsByID(id int64, c *models.UpdateCredentials) (dat
 		return nil, err
 	}
 
+	// A stolen token must not stay valid after the password change
+	if c.Password != "" {
+		if err = u.revokeAllTokens(id); err != nil {
+			return nil, err
+		}
+	}
+
 	return data, nil
 }
@@ -0,0 +1,294 @@
+package userv1
+
+import (
+	"bytes"
+	dbsql "database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/soldatov-s/go-garage-auth/internal/webauthn"
+	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+	"github.com/soldatov-s/go-garage/providers/db"
+	"github.com/soldatov-s/go-garage/types"
+)
+
+// Types of WebAuthn ceremonies
+const (
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+)
+
+// userHandle returns the user handle of passkeys, authenticators return it on login
+func userHandle(id int64) []byte {
+	return []byte(strconv.FormatInt(id, 10))
+}
+
+// hashChallenge returns the hash of the challenge, the database keeps only hashes
+func hashChallenge(challenge []byte) types.NullString {
+	return hashToken(base64.RawURLEncoding.EncodeToString(challenge))
+}
+
+// newWebAuthnCeremony stores the challenge of a new ceremony, the user is empty for login
+func (u *UserV1) newWebAuthnCeremony(ceremonyType string, id dbsql.NullInt64) ([]byte, error) {
+	if u.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	if _, err = u.db.Conn.Exec("DELETE FROM production.webauthn_ceremony WHERE expired_at<=$1", now); err != nil {
+		return nil, err
+	}
+
+	_, err = u.db.Conn.Exec(`INSERT INTO production.webauthn_ceremony (ceremony_hash, ceremony_type, user_id,
+		expired_at, created_at) VALUES ($1, $2, $3, $4, $5)`,
+		hashChallenge(challenge), ceremonyType, id, now.Add(u.cfg.WebAuthn.Timeout), now)
+	if err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// consumeWebAuthnCeremony deletes the ceremony of the challenge, every ceremony can be finished once
+func (u *UserV1) consumeWebAuthnCeremony(ceremonyType string, challenge []byte, id dbsql.NullInt64) error {
+	if u.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	result, err := u.db.Conn.Exec(`DELETE FROM production.webauthn_ceremony WHERE ceremony_hash=$1
+		AND ceremony_type=$2 AND user_id IS NOT DISTINCT FROM $3 AND expired_at>$4`,
+		hashChallenge(challenge), ceremonyType, id, time.Now())
+	if err != nil {
+		return err
+	}
+
+	countRow, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if countRow == 0 {
+		return ErrInvalidWebAuthn
+	}
+
+	return nil
+}
+
+// getWebAuthnCredentials returns passkeys of the user
+func (u *UserV1) getWebAuthnCredentials(id int64) ([]models.WebAuthnCredential, error) {
+	if u.db.Conn == nil {
+		return nil, db.ErrDBConnNotEstablished
+	}
+
+	credentials := []models.WebAuthnCredential{}
+
+	err := u.db.Conn.Select(&credentials,
+		"select * from production.webauthn_credential where user_id=$1 order by created_at", id)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+// deleteWebAuthnCredential deletes the passkey of the user
+func (u *UserV1) deleteWebAuthnCredential(id int64, credentialID string) error {
+	if u.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	result, err := u.db.Conn.Exec("DELETE FROM production.webauthn_credential WHERE user_id=$1 AND credential_id=$2",
+		id, credentialID)
+	if err != nil {
+		return err
+	}
+
+	countRow, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if countRow == 0 {
+		return dbsql.ErrNoRows
+	}
+
+	return nil
+}
+
+// beginWebAuthnRegistration returns options of navigator.credentials.create for a new passkey of the user
+func (u *UserV1) beginWebAuthnRegistration(id int64) (*webauthn.CreationOptions, error) {
+	data, err := u.GetUserDataByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := u.getWebAuthnCredentials(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The authenticator which already has a passkey of the user refuses to register another one
+	exclude := make([][]byte, 0, len(credentials))
+
+	for i := range credentials {
+		credentialID, err := base64.RawURLEncoding.DecodeString(credentials[i].ID)
+		if err != nil {
+			return nil, err
+		}
+
+		exclude = append(exclude, credentialID)
+	}
+
+	challenge, err := u.newWebAuthnCeremony(ceremonyRegistration, dbsql.NullInt64{Int64: id, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+
+	name := data.Email
+	if data.Login != "" {
+		name = data.Login
+	}
+
+	return u.rp.CreationOptions(challenge, webauthn.UserEntity{ID: userHandle(id), Name: name, DisplayName: name},
+		exclude), nil
+}
+
+// finishWebAuthnRegistration verifies the new passkey of the user and stores it
+func (u *UserV1) finishWebAuthnRegistration(
+	id int64,
+	registration *models.WebAuthnRegistration) (*models.WebAuthnCredential, error) {
+	challenge, err := registration.Credential.Challenge()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = u.consumeWebAuthnCeremony(ceremonyRegistration, challenge,
+		dbsql.NullInt64{Int64: id, Valid: true}); err != nil {
+		return nil, err
+	}
+
+	credential, err := u.rp.VerifyRegistration(&registration.Credential, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &models.WebAuthnCredential{
+		ID:         base64.RawURLEncoding.EncodeToString(credential.ID),
+		UserID:     id,
+		Name:       registration.Name,
+		PublicKey:  credential.PublicKey,
+		SignCount:  int64(credential.SignCount),
+		AAGUID:     hex.EncodeToString(credential.AAGUID),
+		Transports: credential.Transports,
+	}
+	data.CreatedAt.SetNow()
+
+	if data.Transports == nil {
+		data.Transports = []string{}
+	}
+
+	result, err := u.db.Conn.NamedExec(`INSERT INTO production.webauthn_credential (credential_id, user_id,
+		credential_name, credential_public_key, credential_sign_count, credential_aaguid, credential_transports,
+		created_at) VALUES (:credential_id, :user_id, :credential_name, :credential_public_key, :credential_sign_count,
+		:credential_aaguid, :credential_transports, :created_at) ON CONFLICT (credential_id) DO NOTHING`, data)
+	if err != nil {
+		return nil, err
+	}
+
+	countRow, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if countRow == 0 {
+		return nil, ErrPasskeyIsRegistered
+	}
+
+	return data, nil
+}
+
+// beginWebAuthnLogin returns options of navigator.credentials.get, the user is chosen by the authenticator
+func (u *UserV1) beginWebAuthnLogin() (*webauthn.RequestOptions, error) {
+	challenge, err := u.newWebAuthnCeremony(ceremonyLogin, dbsql.NullInt64{})
+	if err != nil {
+		return nil, err
+	}
+
+	return u.rp.RequestOptions(challenge), nil
+}
+
+// finishWebAuthnLogin verifies the assertion of the passkey and returns its user
+// and whether the authenticator verified the user
+func (u *UserV1) finishWebAuthnLogin(assertion *webauthn.AssertionResponse) (*models.User, bool, error) {
+	challenge, err := assertion.Challenge()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err = u.consumeWebAuthnCeremony(ceremonyLogin, challenge, dbsql.NullInt64{}); err != nil {
+		return nil, false, err
+	}
+
+	var credential models.WebAuthnCredential
+
+	err = u.db.Conn.Get(&credential, "select * from production.webauthn_credential where credential_id=$1",
+		base64.RawURLEncoding.EncodeToString(assertion.RawID))
+	if errors.Is(err, dbsql.ErrNoRows) {
+		return nil, false, ErrUnknownPasskey
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(assertion.Response.UserHandle) != 0 && !bytes.Equal(assertion.Response.UserHandle, userHandle(credential.UserID)) {
+		return nil, false, fmt.Errorf("%w: user handle doesn't match", ErrUnknownPasskey)
+	}
+
+	verified, err := u.rp.VerifyAssertion(assertion, challenge, credential.PublicKey, uint32(credential.SignCount))
+	if err != nil {
+		return nil, false, err
+	}
+
+	// The condition on the counter protects against concurrent logins by the cloned authenticator
+	result, err := u.db.Conn.Exec(`UPDATE production.webauthn_credential SET credential_sign_count=$1, last_used_at=$2
+		WHERE credential_id=$3 AND credential_sign_count=$4`, verified.SignCount, time.Now(), credential.ID, credential.SignCount)
+	if err != nil {
+		return nil, false, err
+	}
+
+	countRow, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if countRow == 0 {
+		return nil, false, webauthn.ErrSignCountRollback
+	}
+
+	data, err := u.GetUserDataByID(credential.UserID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if data.DeletedAt.Valid {
+		return nil, false, ErrUnknownPasskey
+	}
+
+	if data.Status == goGarageAuthTypes.New {
+		return nil, false, ErrUserNotActivated
+	}
+
+	return data, verified.UserVerified, nil
+}
@@ -3,11 +3,21 @@ module github.com/soldatov-s/go-garage-auth
 go 1.15
 
 require (
+	github.com/envoyproxy/go-control-plane v0.10.3
 	github.com/evanphx/json-patch v0.5.2
+	github.com/golang-jwt/jwt/v4 v4.5.2
 	github.com/jmoiron/sqlx v1.2.0
+	github.com/labstack/echo/v4 v4.1.17
+	github.com/lib/pq v1.7.0
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.7.1
 	github.com/rs/zerolog v1.20.0
 	github.com/soldatov-s/go-garage v0.0.0-20210228175809-cb3919fae4c6
 	github.com/soldatov-s/go-swagger v1.1.0
 	github.com/spf13/cobra v1.0.0
+	github.com/vrischmann/envconfig v1.2.0
+	golang.org/x/crypto v0.0.0-20200820211705-5c72a883971a
+	google.golang.org/genproto v0.0.0-20220329172620-7be39ac1afc7
+	google.golang.org/grpc v1.45.0
+	google.golang.org/protobuf v1.28.0
 )
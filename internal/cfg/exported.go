@@ -4,7 +4,13 @@ import (
 	"context"
 	"time"
 
+	"github.com/soldatov-s/go-garage-auth/internal/csrf"
+	"github.com/soldatov-s/go-garage-auth/internal/grpcsrv"
 	"github.com/soldatov-s/go-garage-auth/internal/hmac"
+	"github.com/soldatov-s/go-garage-auth/internal/jwt"
+	"github.com/soldatov-s/go-garage-auth/internal/mailer"
+	"github.com/soldatov-s/go-garage-auth/internal/paseto"
+	"github.com/soldatov-s/go-garage-auth/internal/webauthn"
 	"github.com/soldatov-s/go-garage/providers/config"
 	"github.com/soldatov-s/go-garage/providers/db/pq"
 	"github.com/soldatov-s/go-garage/providers/httpsrv/echo"
@@ -18,6 +24,8 @@ const (
 
 	PublicHTTP  = "public"
 	PrivateHTTP = "private"
+	GRPC        = "grpc"
+	ExtAuthz    = "extauthz"
 	V1          = "1"
 )
 
@@ -26,10 +34,86 @@ type Config struct {
 	DB          *pq.Config
 	PublicHTTP  *echo.Config
 	PrivateHTTP *echo.Config
-	Stats       *garage.Config
-	Token       struct {
+	// GRPC is gRPC server of private API
+	GRPC  *grpcsrv.Config
+	Stats *garage.Config
+	Token struct {
+		// Strategy is a name of the registered strategy of access tokens, hmac, jwt or paseto out of the box
+		Strategy             string `envconfig:"default=hmac"`
 		HMAC                 *hmac.Config
+		JWT                  *jwt.Config
+		PASETO               *paseto.Config
+		RefreshTTL           time.Duration `envconfig:"default=720h"`
 		ClearOldTokensPeriod time.Duration `envconfig:"default=48h"`
+		// DisableQueryToken disables passing the token in the query string, RFC 6750 does not recommend it
+		DisableQueryToken bool `envconfig:"default=false"`
+		// IntrospectionCache caches results of introspection on every replica,
+		// revoked tokens are invalidated through Postgres LISTEN/NOTIFY
+		IntrospectionCache struct {
+			// Size is a maximum number of cached results, 0 disables the cache
+			Size int           `envconfig:"default=10000"`
+			TTL  time.Duration `envconfig:"default=30s"`
+			// NegativeTTL is a lifetime of results for invalid and unknown tokens
+			NegativeTTL time.Duration `envconfig:"default=5s"`
+		}
+	}
+	// SessionCookie describes the cookie which is set by public login endpoint
+	SessionCookie struct {
+		Domain string `envconfig:"optional"`
+		Path   string `envconfig:"default=/"`
+		// SameSite is strict, lax or none
+		SameSite string `envconfig:"default=lax"`
+	}
+	// CSRF protects state-changing requests of public API authenticated by session cookie
+	CSRF *csrf.Config
+	// ExtAuthz is Envoy external authorization gRPC server, e.g. on 0.0.0.0:9200.
+	// If the address is empty, the server isn't started.
+	ExtAuthz *grpcsrv.Config
+	// Bootstrap creates the first active admin on start if there is no admin yet,
+	// because users are created by private API only with users:write
+	Bootstrap struct {
+		AdminEmail    string `envconfig:"optional"`
+		AdminPassword string `envconfig:"optional"`
+	}
+	// Activation of new users by a single-use token
+	Activation struct {
+		// TTL is a lifetime of the activation token
+		TTL time.Duration `envconfig:"default=48h"`
+		// ResendInterval is a minimal interval between activation tokens of the user
+		ResendInterval time.Duration `envconfig:"default=1m"`
+	}
+	// PasswordReset by a single-use token mailed to the user
+	PasswordReset struct {
+		// TTL is a lifetime of the reset token
+		TTL time.Duration `envconfig:"default=1h"`
+		// ResendInterval is a minimal interval between reset tokens of the user
+		ResendInterval time.Duration `envconfig:"default=1m"`
+	}
+	// MFA is two-factor authentication by TOTP codes of RFC 6238
+	MFA struct {
+		// Secret encrypts TOTP secrets at rest
+		Secret string `envconfig:"default=you_Really_Need_To_ChangeThis!!!"`
+		// Issuer is shown by authenticator apps
+		Issuer string `envconfig:"default=go-garage-auth"`
+		// Skew is a number of allowed time steps before and after the current one
+		Skew int `envconfig:"default=1"`
+		// ChallengeTTL is a lifetime of MFA challenge token issued after the password check
+		ChallengeTTL time.Duration `envconfig:"default=5m"`
+		// MaxAttempts is a maximal number of codes checked by one challenge
+		MaxAttempts int `envconfig:"default=5"`
+		// MaxFailedAttempts is a maximal number of failed codes of the user by all challenges,
+		// after them the user is locked out of MFA for LockoutPeriod
+		MaxFailedAttempts int           `envconfig:"default=10"`
+		LockoutPeriod     time.Duration `envconfig:"default=15m"`
+	}
+	// WebAuthn is passwordless login by passkeys
+	WebAuthn *webauthn.Config
+	// Mailer delivers transactional email
+	Mailer *mailer.Config
+	OAuth2 struct {
+		// Clients is a list of resource servers in format "client_id:client_secret",
+		// which are allowed to call OAuth2 endpoints
+		Clients []string `envconfig:"optional"`
 	}
 }
 
@@ -57,6 +141,9 @@ func NewConfig() *Config {
 			HideBanner: true,
 			HidePort:   true,
 		},
+		GRPC: &grpcsrv.Config{
+			Address: "0.0.0.0:9300",
+		},
 		Stats: &garage.Config{
 			HTTPProviderName: echo.DefaultProviderName,
 			HTTPEnityName:    "private",
@@ -6,9 +6,17 @@ import (
 	"os"
 
 	authv1 "github.com/soldatov-s/go-garage-auth/domains/auth/v1"
+	policyv1 "github.com/soldatov-s/go-garage-auth/domains/policy/v1"
+	rbacv1 "github.com/soldatov-s/go-garage-auth/domains/rbac/v1"
 	userv1 "github.com/soldatov-s/go-garage-auth/domains/user/v1"
 	"github.com/soldatov-s/go-garage-auth/internal/cfg"
+	"github.com/soldatov-s/go-garage-auth/internal/csrf"
+	"github.com/soldatov-s/go-garage-auth/internal/grpcsrv"
 	"github.com/soldatov-s/go-garage-auth/internal/hmac"
+	"github.com/soldatov-s/go-garage-auth/internal/jwt"
+	"github.com/soldatov-s/go-garage-auth/internal/mailer"
+	"github.com/soldatov-s/go-garage-auth/internal/paseto"
+	"github.com/soldatov-s/go-garage-auth/token"
 	"github.com/soldatov-s/go-garage/app"
 	"github.com/soldatov-s/go-garage/meta"
 	"github.com/soldatov-s/go-garage/providers/config/envconfig"
@@ -95,6 +103,20 @@ func initProviders(ctx context.Context) context.Context {
 		log.Fatal().Err(err).Msg("failed to create api group")
 	}
 
+	// Private gRPC
+	ctx, err = grpcsrv.RegistrateEnity(ctx, cfg.GRPC, c.GRPC)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to registrate grpc")
+	}
+
+	// Envoy external authorization gRPC
+	if c.ExtAuthz.Address != "" {
+		ctx, err = grpcsrv.RegistrateEnity(ctx, cfg.ExtAuthz, c.ExtAuthz)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to registrate ext_authz grpc")
+		}
+	}
+
 	if ctx, err = garage.RegistrateEnity(ctx, cfg.StatsName, c.Stats); err != nil {
 		log.Fatal().Err(err).Msg("failed to registrate stats")
 	}
@@ -106,19 +128,65 @@ func initDomains(ctx context.Context) context.Context {
 	var err error
 	log := logger.GetPackageLogger(ctx, empty{})
 
-	// Initilize domains
-	if ctx, err = userv1.Registrate(ctx); err != nil {
-		log.Fatal().Err(err).Msg("failed to create domain authv1")
+	// CSRF middleware should be used by public API group before any route is added
+	if ctx, err = csrf.Registrate(ctx, cfg.Get(ctx).CSRF, authv1.SessionCookie); err != nil {
+		log.Fatal().Err(err).Msg("failed to create domain csrf")
 	}
 
+	csrfProtection, err := csrf.Get(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to get domain csrf")
+	}
+
+	publicV1, err := echo.GetAPIVersionGroup(ctx, cfg.PublicHTTP, cfg.V1)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to get api group")
+	}
+
+	publicV1.Use(csrfProtection.Middleware())
+
+	if ctx, err = mailer.Registrate(ctx, cfg.Get(ctx).Mailer, cfg.DBName); err != nil {
+		log.Fatal().Err(err).Msg("failed to create domain mailer")
+	}
+
+	// Initilize domains, userv1, rbacv1 and policyv1 protect their routes by authv1
 	if ctx, err = authv1.Registrate(ctx); err != nil {
 		log.Fatal().Err(err).Msg("failed to create domain authv1")
 	}
 
+	if ctx, err = userv1.Registrate(ctx); err != nil {
+		log.Fatal().Err(err).Msg("failed to create domain userv1")
+	}
+
+	if ctx, err = rbacv1.Registrate(ctx); err != nil {
+		log.Fatal().Err(err).Msg("failed to create domain rbacv1")
+	}
+
+	if ctx, err = policyv1.Registrate(ctx); err != nil {
+		log.Fatal().Err(err).Msg("failed to create domain policyv1")
+	}
+
 	if ctx, err = hmac.Registrate(ctx, cfg.Get(ctx).Token.HMAC); err != nil {
 		log.Fatal().Err(err).Msg("failed to create domain hmac")
 	}
 
+	if cfg.Get(ctx).Token.Strategy == jwt.DomainName {
+		if ctx, err = jwt.Registrate(ctx, cfg.Get(ctx).Token.JWT); err != nil {
+			log.Fatal().Err(err).Msg("failed to create domain jwt")
+		}
+	}
+
+	if cfg.Get(ctx).Token.Strategy == paseto.DomainName {
+		if ctx, err = paseto.Registrate(ctx, cfg.Get(ctx).Token.PASETO); err != nil {
+			log.Fatal().Err(err).Msg("failed to create domain paseto")
+		}
+	}
+
+	// Custom token strategies should be registered by token.Registrate before this check
+	if _, err = token.Get(ctx, cfg.Get(ctx).Token.Strategy); err != nil {
+		log.Fatal().Err(err).Msgf("token strategy %s isn't registered", cfg.Get(ctx).Token.Strategy)
+	}
+
 	return ctx
 }
 
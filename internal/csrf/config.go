@@ -0,0 +1,12 @@
+package csrf
+
+type Config struct {
+	// Secret is a secret for deriving CSRF tokens from session tokens
+	Secret string `envconfig:"default=you_Really_Need_To_ChangeThis!!!"`
+	// Header is a request header with CSRF token
+	Header string `envconfig:"default=X-CSRF-Token"`
+	// Cookie is a cookie with CSRF token, it is readable by JavaScript of the site
+	Cookie string `envconfig:"default=go-garage-csrf"`
+	// Exempt is a list of routes which are not checked, e.g. /api/v1/auth/login
+	Exempt []string `envconfig:"optional"`
+}
@@ -0,0 +1,121 @@
+package csrf
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/soldatov-s/go-garage/domains"
+)
+
+const (
+	DomainName = "csrf"
+
+	minimumSecretLength = 32
+	// entropy of login tokens
+	loginTokenEntropy = 16
+)
+
+// CSRF protects cookie-authenticated requests by synchronizer tokens. The CSRF token is derived
+// from the session token, so it is bound to the session and needs no storage.
+type CSRF struct {
+	cfg *Config
+	key []byte
+	// name of session cookie
+	sessionCookie string
+	exempt        map[string]struct{}
+}
+
+func Registrate(ctx context.Context, cfg *Config, sessionCookie string) (context.Context, error) {
+	if len(cfg.Secret) < minimumSecretLength {
+		return nil, errors.Errorf("secret for CSRF tokens is expected to be 32 byte long, got %d byte", len(cfg.Secret))
+	}
+
+	c := &CSRF{
+		cfg:           cfg,
+		key:           []byte(cfg.Secret),
+		sessionCookie: sessionCookie,
+		exempt:        make(map[string]struct{}),
+	}
+
+	for _, route := range cfg.Exempt {
+		c.exempt[route] = struct{}{}
+	}
+
+	return domains.RegistrateByName(ctx, DomainName, c), nil
+}
+
+func Get(ctx context.Context) (*CSRF, error) {
+	if v, ok := domains.GetByName(ctx, DomainName).(*CSRF); ok {
+		return v, nil
+	}
+	return nil, domains.ErrInvalidDomainType
+}
+
+// Token returns CSRF token of the session
+func (c *CSRF) Token(session string) string {
+	mac := hmac.New(sha256.New, c.key)
+	_, _ = mac.Write([]byte(session))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Validate checks CSRF token of the session
+func (c *CSRF) Validate(session, token string) error {
+	if !hmac.Equal([]byte(c.Token(session)), []byte(token)) {
+		return ErrInvalidCSRFToken
+	}
+
+	return nil
+}
+
+// NewLoginToken returns a signed random CSRF token for login requests, which have no session yet
+func (c *CSRF) NewLoginToken() (string, error) {
+	nonce := make([]byte, loginTokenEntropy)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	encoded := hex.EncodeToString(nonce)
+
+	return encoded + "." + c.Token(encoded), nil
+}
+
+// ValidateLogin checks CSRF token of the login request, the token from the header must be equal
+// to the token from the CSRF cookie and must be signed
+func (c *CSRF) ValidateLogin(cookie, token string) error {
+	split := strings.Split(cookie, ".")
+	if len(split) != 2 || !hmac.Equal([]byte(cookie), []byte(token)) {
+		return ErrInvalidCSRFToken
+	}
+
+	return c.Validate(split[0], split[1])
+}
+
+// LoginCookie returns the CSRF cookie with the login token, it has the same scope as the session cookie
+func (c *CSRF) LoginCookie(sessionCookie *http.Cookie, token string) *http.Cookie {
+	cookie := c.Cookie(sessionCookie)
+	cookie.Value = token
+
+	return cookie
+}
+
+// Cookie returns the CSRF cookie for the session cookie. It has the same scope and lifetime,
+// but it isn't HttpOnly, so JavaScript of the site can copy it into the request header.
+func (c *CSRF) Cookie(sessionCookie *http.Cookie) *http.Cookie {
+	cookie := *sessionCookie
+	cookie.Name = c.cfg.Cookie
+	cookie.HttpOnly = false
+
+	if cookie.Value != "" {
+		cookie.Value = c.Token(sessionCookie.Value)
+	}
+
+	return &cookie
+}
@@ -0,0 +1,112 @@
+package csrf
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const testSessionCookie = "session"
+
+func newTestCSRF(t *testing.T, exempt ...string) *CSRF {
+	t.Helper()
+
+	ctx, err := Registrate(context.Background(), &Config{
+		Secret: "test_secret_which_is_32_bytes_long",
+		Header: "X-CSRF-Token",
+		Cookie: "csrf",
+		Exempt: exempt,
+	}, testSessionCookie)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestRegistrateShortSecret(t *testing.T) {
+	if _, err := Registrate(context.Background(), &Config{Secret: "short"}, testSessionCookie); err == nil {
+		t.Error("expected an error for the short secret")
+	}
+}
+
+func TestTokenValidate(t *testing.T) {
+	c := newTestCSRF(t)
+	token := c.Token("session-1")
+
+	if token != c.Token("session-1") {
+		t.Error("expected the same token for the same session")
+	}
+
+	if err := c.Validate("session-1", token); err != nil {
+		t.Errorf("expected valid token, got %v", err)
+	}
+
+	tampered := token[:len(token)-1] + "0"
+	if token[len(token)-1] == '0' {
+		tampered = token[:len(token)-1] + "1"
+	}
+
+	for name, tc := range map[string]struct {
+		session string
+		token   string
+	}{
+		"another session": {"session-2", token},
+		"tampered token":  {"session-1", tampered},
+		"empty token":     {"session-1", ""},
+	} {
+		if err := c.Validate(tc.session, tc.token); !errors.Is(err, ErrInvalidCSRFToken) {
+			t.Errorf("%s: expected %v, got %v", name, ErrInvalidCSRFToken, err)
+		}
+	}
+
+	other := newTestCSRF(t)
+	other.key = []byte("another_secret_which_is_32_bytes_long")
+
+	if err := other.Validate("session-1", token); !errors.Is(err, ErrInvalidCSRFToken) {
+		t.Errorf("another secret: expected %v, got %v", ErrInvalidCSRFToken, err)
+	}
+}
+
+func TestCookie(t *testing.T) {
+	c := newTestCSRF(t)
+	expires := time.Now().Add(time.Hour)
+
+	session := &http.Cookie{
+		Name:     testSessionCookie,
+		Value:    "session-1",
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+
+	cookie := c.Cookie(session)
+	if cookie.Name != "csrf" || cookie.Value != c.Token("session-1") {
+		t.Errorf("unexpected cookie %s=%s", cookie.Name, cookie.Value)
+	}
+
+	if cookie.HttpOnly {
+		t.Error("expected the cookie to be readable by JavaScript")
+	}
+
+	if !cookie.Secure || cookie.Path != "/" || !cookie.Expires.Equal(expires) || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("expected the scope and the lifetime of the session cookie, got %+v", cookie)
+	}
+
+	if session.Name != testSessionCookie || !session.HttpOnly {
+		t.Error("expected the session cookie to be unchanged")
+	}
+
+	if cleared := c.Cookie(&http.Cookie{Name: testSessionCookie, MaxAge: -1}); cleared.Value != "" || cleared.MaxAge != -1 {
+		t.Errorf("expected the expired cookie for the expired session cookie, got %+v", cleared)
+	}
+}
@@ -0,0 +1,7 @@
+package csrf
+
+import "errors"
+
+var (
+	ErrInvalidCSRFToken = errors.New("invalid csrf token")
+)
@@ -0,0 +1,71 @@
+package csrf
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	garageEcho "github.com/soldatov-s/go-garage/providers/httpsrv/echo"
+)
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// check checks CSRF token of the request authenticated by the session cookie. Requests without
+// the session cookie are checked only if login is true.
+func (c *CSRF) check(ec echo.Context, login bool) error {
+	if session, err := ec.Cookie(c.sessionCookie); err == nil && session.Value != "" {
+		return c.Validate(session.Value, ec.Request().Header.Get(c.cfg.Header))
+	}
+
+	if !login {
+		return nil
+	}
+
+	cookie, err := ec.Cookie(c.cfg.Cookie)
+	if err != nil {
+		return ErrInvalidCSRFToken
+	}
+
+	return c.ValidateLogin(cookie.Value, ec.Request().Header.Get(c.cfg.Header))
+}
+
+func (c *CSRF) middleware(login bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			if isSafeMethod(ec.Request().Method) {
+				return next(ec)
+			}
+
+			if _, ok := c.exempt[ec.Path()]; ok {
+				return next(ec)
+			}
+
+			if err := c.check(ec, login); err != nil {
+				gec := garageEcho.Context{Context: ec}
+				gec.GetLog().Err(err).Msgf("FORBIDDEN, %s %s", ec.Request().Method, ec.Path())
+				return gec.Forbidden(err)
+			}
+
+			return next(ec)
+		}
+	}
+}
+
+// Middleware checks CSRF token of state-changing requests authenticated by the session cookie.
+// Requests without the session cookie are not CSRF-prone and pass unchecked, except login requests.
+func (c *CSRF) Middleware() echo.MiddlewareFunc {
+	return c.middleware(false)
+}
+
+// LoginMiddleware checks CSRF token of login requests, which set the session cookie. Without the session
+// the token from the header must be equal to the login token from the CSRF cookie, so a cross-site form
+// can't log the user into the account of the attacker.
+func (c *CSRF) LoginMiddleware() echo.MiddlewareFunc {
+	return c.middleware(true)
+}
@@ -0,0 +1,99 @@
+package csrf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func serve(c *CSRF, method, path string, cookies []*http.Cookie, header string, m ...echo.MiddlewareFunc) int {
+	e := echo.New()
+	e.Use(c.Middleware())
+
+	handler := func(ec echo.Context) error { return ec.NoContent(http.StatusOK) }
+	e.Add(method, path, handler, m...)
+
+	req := httptest.NewRequest(method, path, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	if header != "" {
+		req.Header.Set(c.cfg.Header, header)
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	return rec.Code
+}
+
+func TestMiddleware(t *testing.T) {
+	c := newTestCSRF(t, "/exempt")
+
+	session := &http.Cookie{Name: testSessionCookie, Value: "session-1"}
+	token := c.Token(session.Value)
+	// The CSRF cookie is copied into the header by JavaScript of the site
+	csrfCookie := &http.Cookie{Name: c.cfg.Cookie, Value: token}
+	otherToken := c.Token("session-2")
+
+	for name, tc := range map[string]struct {
+		method  string
+		path    string
+		cookies []*http.Cookie
+		header  string
+		code    int
+	}{
+		"safe method":            {http.MethodGet, "/", []*http.Cookie{session}, "", http.StatusOK},
+		"no session cookie":      {http.MethodPost, "/", nil, "", http.StatusOK},
+		"matching header":        {http.MethodPost, "/", []*http.Cookie{session, csrfCookie}, token, http.StatusOK},
+		"missing header":         {http.MethodPost, "/", []*http.Cookie{session, csrfCookie}, "", http.StatusForbidden},
+		"header of another user": {http.MethodPost, "/", []*http.Cookie{session, csrfCookie}, otherToken, http.StatusForbidden},
+		"header mismatches cookie": {http.MethodDelete, "/", []*http.Cookie{session, {Name: c.cfg.Cookie, Value: otherToken}},
+			otherToken, http.StatusForbidden},
+		"exempt route": {http.MethodPost, "/exempt", []*http.Cookie{session}, "", http.StatusOK},
+	} {
+		if code := serve(c, tc.method, tc.path, tc.cookies, tc.header); code != tc.code {
+			t.Errorf("%s: expected %d, got %d", name, tc.code, code)
+		}
+	}
+}
+
+func TestLoginMiddleware(t *testing.T) {
+	c := newTestCSRF(t)
+
+	token, err := c.NewLoginToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherToken, err := c.NewLoginToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := &http.Cookie{Name: testSessionCookie, Value: "session-1"}
+	loginCookie := &http.Cookie{Name: c.cfg.Cookie, Value: token}
+	// The attacker can't sign the token without the secret
+	forged := "00." + c.Token("01")
+
+	for name, tc := range map[string]struct {
+		cookies []*http.Cookie
+		header  string
+		code    int
+	}{
+		"matching header":          {[]*http.Cookie{loginCookie}, token, http.StatusOK},
+		"no CSRF cookie":           {nil, token, http.StatusForbidden},
+		"missing header":           {[]*http.Cookie{loginCookie}, "", http.StatusForbidden},
+		"header mismatches cookie": {[]*http.Cookie{loginCookie}, otherToken, http.StatusForbidden},
+		"forged token":             {[]*http.Cookie{{Name: c.cfg.Cookie, Value: forged}}, forged, http.StatusForbidden},
+		"token of the session":     {[]*http.Cookie{session}, c.Token(session.Value), http.StatusOK},
+		"login token with session": {[]*http.Cookie{session, loginCookie}, token, http.StatusForbidden},
+	} {
+		if code := serve(c, http.MethodPost, "/login", tc.cookies, tc.header, c.LoginMiddleware()); code != tc.code {
+			t.Errorf("%s: expected %d, got %d", name, tc.code, code)
+		}
+	}
+}
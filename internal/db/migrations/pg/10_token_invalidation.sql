@@ -0,0 +1,20 @@
+-- +goose Up
+
+-- Replicas cache introspection results, so they are notified about every revoked token
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION production.notifytokeninvalidation() RETURNS TRIGGER AS $$
+BEGIN
+	IF OLD.expired_at > now() THEN
+		PERFORM pg_notify('token_invalidation', OLD.signature);
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS notify_deleted_token on production.token;
+CREATE TRIGGER notify_deleted_token AFTER DELETE ON production.token FOR EACH ROW EXECUTE PROCEDURE production.notifytokeninvalidation();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS notify_deleted_token on production.token;
+DROP FUNCTION IF EXISTS production.notifytokeninvalidation();
@@ -0,0 +1,95 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS production.role (
+    role_id BIGSERIAL PRIMARY KEY,
+    role_name character varying(255) NOT NULL UNIQUE,
+    role_description text,
+    created_at timestamp with time zone NOT NULL,
+    updated_at timestamp with time zone NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS production.permission (
+    permission_name character varying(255) PRIMARY KEY,
+    permission_description text,
+    created_at timestamp with time zone NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS production.role_permission (
+    role_id bigint NOT NULL REFERENCES production.role (role_id) ON DELETE CASCADE,
+    permission_name character varying(255) NOT NULL REFERENCES production.permission (permission_name) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_name)
+);
+
+-- The user table is partitioned, so assignments don't reference it and are deleted by the trigger
+CREATE TABLE IF NOT EXISTS production.role_assignment (
+    user_id bigint NOT NULL,
+    role_id bigint NOT NULL REFERENCES production.role (role_id) ON DELETE CASCADE,
+    created_at timestamp with time zone NOT NULL,
+    PRIMARY KEY (user_id, role_id)
+);
+CREATE INDEX IF NOT EXISTS role_assignment_role_id ON production.role_assignment (role_id);
+
+-- Seeded roles have the names of the roles of the user_role column
+INSERT INTO production.role (role_name, role_description, created_at, updated_at) VALUES
+    ('RESTRICTED_USER', 'Restricted user', now(), now()),
+    ('USER_L1', 'User of level 1', now(), now()),
+    ('USER_L2', 'User of level 2', now(), now()),
+    ('USER_L3', 'User of level 3', now(), now()),
+    ('USER_L4', 'User of level 4', now(), now()),
+    ('USER_L5', 'User of level 5', now(), now()),
+    ('SUPERUSER', 'Manager of users and sessions', now(), now()),
+    ('ADMIN', 'Administrator', now(), now())
+ON CONFLICT (role_name) DO NOTHING;
+
+INSERT INTO production.permission (permission_name, permission_description, created_at) VALUES
+    ('users:read', 'Read users', now()),
+    ('users:write', 'Create and update users', now()),
+    ('users:delete', 'Delete users softly', now()),
+    ('users:hard_delete', 'Delete users hard', now()),
+    ('sessions:read', 'Read sessions of users', now()),
+    ('sessions:write', 'Revoke sessions of users', now()),
+    ('roles:read', 'Read roles, permissions and assignments', now()),
+    ('roles:write', 'Manage roles, permissions and assignments', now())
+ON CONFLICT (permission_name) DO NOTHING;
+
+INSERT INTO production.role_permission (role_id, permission_name)
+SELECT r.role_id, p.permission_name FROM production.role r, production.permission p
+WHERE r.role_name = 'ADMIN' OR (r.role_name = 'SUPERUSER' AND p.permission_name IN (
+    'users:read', 'users:write', 'users:delete', 'sessions:read', 'sessions:write', 'roles:read'))
+ON CONFLICT DO NOTHING;
+
+INSERT INTO production.role_assignment (user_id, role_id, created_at)
+SELECT u.user_id, r.role_id, now() FROM production.user u JOIN production.role r ON r.role_name = u.user_role
+ON CONFLICT DO NOTHING;
+
+-- The user_role column stays the primary role of the user, the assignment of the seeded role follows it
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION production.syncroleassignment() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		DELETE FROM production.role_assignment ra USING production.role r
+		WHERE ra.user_id = OLD.user_id AND ra.role_id = r.role_id
+			AND (TG_OP = 'DELETE' OR (r.role_name = OLD.user_role AND OLD.user_role IS DISTINCT FROM NEW.user_role));
+	END IF;
+
+	IF TG_OP IN ('INSERT', 'UPDATE') THEN
+		INSERT INTO production.role_assignment (user_id, role_id, created_at)
+		SELECT NEW.user_id, r.role_id, now() FROM production.role r WHERE r.role_name = NEW.user_role
+		ON CONFLICT DO NOTHING;
+	END IF;
+
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS sync_role_assignment on production.user;
+CREATE TRIGGER sync_role_assignment AFTER INSERT OR UPDATE OF user_role OR DELETE ON production.user
+FOR EACH ROW EXECUTE PROCEDURE production.syncroleassignment();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS sync_role_assignment on production.user;
+DROP FUNCTION IF EXISTS production.syncroleassignment();
+DROP TABLE IF EXISTS production.role_assignment;
+DROP TABLE IF EXISTS production.role_permission;
+DROP TABLE IF EXISTS production.permission;
+DROP TABLE IF EXISTS production.role;
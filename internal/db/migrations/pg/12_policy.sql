@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS production.policy (
+    policy_id BIGSERIAL PRIMARY KEY,
+    policy_name character varying(255) NOT NULL UNIQUE,
+    policy_description text,
+    policy_effect character varying(16) NOT NULL,
+    policy_actions text[] NOT NULL,
+    policy_condition text NOT NULL DEFAULT '',
+    created_at timestamp with time zone NOT NULL,
+    updated_at timestamp with time zone NOT NULL
+);
+
+INSERT INTO production.permission (permission_name, permission_description, created_at) VALUES
+    ('policies:read', 'Read authorization policies', now()),
+    ('policies:write', 'Manage authorization policies', now())
+ON CONFLICT (permission_name) DO NOTHING;
+
+INSERT INTO production.role_permission (role_id, permission_name)
+SELECT r.role_id, p.permission_name FROM production.role r, production.permission p
+WHERE (r.role_name = 'ADMIN' AND p.permission_name IN ('policies:read', 'policies:write'))
+    OR (r.role_name = 'SUPERUSER' AND p.permission_name = 'policies:read')
+ON CONFLICT DO NOTHING;
+
+-- +goose Down
+DELETE FROM production.permission WHERE permission_name IN ('policies:read', 'policies:write');
+DROP TABLE IF EXISTS production.policy;
@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE production."user" ADD COLUMN IF NOT EXISTS user_activation_expired_at timestamp with time zone;
+ALTER TABLE production."user" ADD COLUMN IF NOT EXISTS user_activation_sent_at timestamp with time zone;
+CREATE INDEX IF NOT EXISTS user_activation_hash_idx ON production."user" (user_activation_hash);
+
+-- Users were created as NEW before the activation flow and couldn't be activated, so they stay able to log in
+UPDATE production."user" SET user_status = 'ACTIVE' WHERE user_status = 'NEW' AND user_activation_hash IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS production.user_activation_hash_idx;
+ALTER TABLE production."user" DROP COLUMN IF EXISTS user_activation_sent_at;
+ALTER TABLE production."user" DROP COLUMN IF EXISTS user_activation_expired_at;
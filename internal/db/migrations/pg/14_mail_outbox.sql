@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS production.mail_outbox (
+    mail_id BIGSERIAL PRIMARY KEY,
+    mail_type character varying(255) NOT NULL,
+    mail_locale character varying(255) NOT NULL,
+    mail_to character varying(255) NOT NULL,
+    mail_subject text NOT NULL,
+    mail_body text NOT NULL,
+    mail_attempts integer NOT NULL DEFAULT 0,
+    mail_last_error text,
+    mail_next_attempt_at timestamp with time zone NOT NULL,
+    mail_sent_at timestamp with time zone,
+    created_at timestamp with time zone NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS mail_outbox_pending_idx ON production.mail_outbox (mail_next_attempt_at)
+    WHERE mail_sent_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS production.mail_outbox;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Every user has at most one reset token, a new token replaces the previous one
+CREATE TABLE IF NOT EXISTS production.password_reset (
+    user_id bigint PRIMARY KEY,
+    reset_hash text NOT NULL UNIQUE,
+    expired_at timestamp with time zone NOT NULL,
+    created_at timestamp with time zone NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS production.password_reset;
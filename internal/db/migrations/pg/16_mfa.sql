@@ -0,0 +1,35 @@
+-- +goose Up
+-- mfa_secret is encrypted by MFA_SECRET, mfa_last_counter protects against replay of used codes
+CREATE TABLE IF NOT EXISTS production.user_mfa (
+    user_id bigint PRIMARY KEY,
+    mfa_secret text NOT NULL,
+    mfa_last_counter bigint NOT NULL DEFAULT 0,
+    mfa_confirmed_at timestamp with time zone,
+    created_at timestamp with time zone NOT NULL,
+    updated_at timestamp with time zone NOT NULL
+);
+
+-- Challenges are issued after the password check and exchanged for a session by TOTP code
+CREATE TABLE IF NOT EXISTS production.mfa_challenge (
+    challenge_hash text PRIMARY KEY,
+    user_id bigint NOT NULL,
+    challenge_attempts integer NOT NULL DEFAULT 0,
+    expired_at timestamp with time zone NOT NULL,
+    created_at timestamp with time zone NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS mfa_challenge_expired_at_idx ON production.mfa_challenge (expired_at);
+
+INSERT INTO production.permission (permission_name, permission_description, created_at) VALUES
+    ('users:mfa_reset', 'Reset two-factor authentication of users', now())
+ON CONFLICT (permission_name) DO NOTHING;
+
+INSERT INTO production.role_permission (role_id, permission_name)
+SELECT r.role_id, p.permission_name FROM production.role r, production.permission p
+WHERE r.role_name = 'ADMIN' AND p.permission_name = 'users:mfa_reset'
+ON CONFLICT DO NOTHING;
+
+-- +goose Down
+DELETE FROM production.permission WHERE permission_name = 'users:mfa_reset';
+DROP TABLE IF EXISTS production.mfa_challenge;
+DROP TABLE IF EXISTS production.user_mfa;
@@ -0,0 +1,30 @@
+-- +goose Up
+-- credential_id is base64url of the credential ID, credential_public_key is COSE_Key of the credential
+CREATE TABLE IF NOT EXISTS production.webauthn_credential (
+    credential_id text PRIMARY KEY,
+    user_id bigint NOT NULL,
+    credential_name text NOT NULL DEFAULT '',
+    credential_public_key bytea NOT NULL,
+    credential_sign_count bigint NOT NULL DEFAULT 0,
+    credential_aaguid text NOT NULL DEFAULT '',
+    credential_transports text[] NOT NULL DEFAULT '{}',
+    created_at timestamp with time zone NOT NULL,
+    last_used_at timestamp with time zone
+);
+
+CREATE INDEX IF NOT EXISTS webauthn_credential_user_id_idx ON production.webauthn_credential (user_id);
+
+-- Ceremonies are identified by the hash of the challenge, user_id is empty for login
+CREATE TABLE IF NOT EXISTS production.webauthn_ceremony (
+    ceremony_hash text PRIMARY KEY,
+    ceremony_type text NOT NULL,
+    user_id bigint,
+    expired_at timestamp with time zone NOT NULL,
+    created_at timestamp with time zone NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webauthn_ceremony_expired_at_idx ON production.webauthn_ceremony (expired_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS production.webauthn_ceremony;
+DROP TABLE IF EXISTS production.webauthn_credential;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Failed codes are counted per user, because anybody with the password may request new challenges
+ALTER TABLE production.user_mfa ADD COLUMN IF NOT EXISTS mfa_failed_attempts integer NOT NULL DEFAULT 0;
+ALTER TABLE production.user_mfa ADD COLUMN IF NOT EXISTS mfa_locked_until timestamp with time zone;
+
+-- +goose Down
+ALTER TABLE production.user_mfa DROP COLUMN IF EXISTS mfa_locked_until;
+ALTER TABLE production.user_mfa DROP COLUMN IF EXISTS mfa_failed_attempts;
@@ -0,0 +1,17 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS production.refresh_token (
+    signature character varying(255) PRIMARY KEY,
+    family character varying(255) NOT NULL,
+    subject character varying(255),
+    access_signature character varying(255),
+    meta jsonb,
+    created_at timestamp with time zone NOT NULL,
+    used_at timestamp with time zone,
+    expired_at timestamp with time zone
+);
+
+CREATE INDEX IF NOT EXISTS refresh_token_family ON production.refresh_token (family);
+
+-- +goose Down
+DROP TABLE production.refresh_token;
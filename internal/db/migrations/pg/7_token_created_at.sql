@@ -0,0 +1,6 @@
+-- +goose Up
+
+ALTER TABLE production.token ADD COLUMN IF NOT EXISTS created_at timestamp with time zone;
+
+-- +goose Down
+ALTER TABLE production.token DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,21 @@
+-- +goose Up
+
+ALTER TABLE production.token ADD COLUMN IF NOT EXISTS session_id character varying(255);
+ALTER TABLE production.token ADD COLUMN IF NOT EXISTS last_used_at timestamp with time zone;
+ALTER TABLE production.token ADD COLUMN IF NOT EXISTS client_ip character varying(255);
+ALTER TABLE production.token ADD COLUMN IF NOT EXISTS user_agent text;
+
+UPDATE production.token SET session_id = md5(signature) WHERE session_id IS NULL;
+ALTER TABLE production.token ALTER COLUMN session_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS token_subject ON production.token (subject);
+CREATE INDEX IF NOT EXISTS token_session_id ON production.token (session_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS production.token_session_id;
+DROP INDEX IF EXISTS production.token_subject;
+
+ALTER TABLE production.token DROP COLUMN IF EXISTS user_agent;
+ALTER TABLE production.token DROP COLUMN IF EXISTS client_ip;
+ALTER TABLE production.token DROP COLUMN IF EXISTS last_used_at;
+ALTER TABLE production.token DROP COLUMN IF EXISTS session_id;
@@ -0,0 +1,27 @@
+-- +goose Up
+
+-- Self-contained tokens are validated without production.token, so revoked tokens are kept in the denylist until they expire
+CREATE TABLE IF NOT EXISTS production.token_denylist (
+    signature character varying(255) PRIMARY KEY,
+    expired_at timestamp with time zone
+);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION production.denytoken() RETURNS TRIGGER AS $$
+BEGIN
+	IF OLD.expired_at > now() THEN
+		INSERT INTO production.token_denylist (signature, expired_at) VALUES (OLD.signature, OLD.expired_at)
+			ON CONFLICT DO NOTHING;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS deny_deleted_token on production.token;
+CREATE TRIGGER deny_deleted_token AFTER DELETE ON production.token FOR EACH ROW EXECUTE PROCEDURE production.denytoken();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS deny_deleted_token on production.token;
+DROP FUNCTION IF EXISTS production.denytoken();
+DROP TABLE IF EXISTS production.token_denylist;
@@ -0,0 +1,15 @@
+package grpcsrv
+
+type Config struct {
+	// Address is an address on which gRPC server will listen.
+	Address string `envconfig:"optional"`
+}
+
+// Validate checks the config
+func (c *Config) Validate() error {
+	if c.Address == "" {
+		return ErrBindAddressMissing
+	}
+
+	return nil
+}
@@ -0,0 +1,11 @@
+package grpcsrv
+
+import (
+	"errors"
+)
+
+var (
+	ErrBindAddressMissing   = errors.New("bind address wasn't specified")
+	ErrServerAlreadyStarted = errors.New("server already started")
+	ErrNotSupported         = errors.New("not supported by gRPC server")
+)
@@ -0,0 +1,83 @@
+package grpcsrv
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/soldatov-s/go-garage/providers/base/provider"
+	"github.com/soldatov-s/go-garage/providers/errors"
+	"github.com/soldatov-s/go-garage/providers/httpsrv"
+)
+
+const DefaultProviderName = "grpc"
+
+// Provider is a gRPC server provider. It is registered among HTTP servers,
+// so the servers are started and shutdown together with the application.
+type Provider struct {
+	*provider.Provider
+}
+
+func NewProvider(ctx context.Context) *Provider {
+	return &Provider{
+		Provider: provider.NewProvider(ctx, httpsrv.ProvidersName, DefaultProviderName),
+	}
+}
+
+// CreateEnity creates gRPC server using passed parameters.
+func (p *Provider) CreateEnity(enityName string, options interface{}) error {
+	if _, err := p.GetEnity(enityName); err == nil {
+		p.Log.Debug().Str("enity name", enityName).Msg("enity already created")
+		return nil
+	}
+
+	enity, err := NewEnity(p.GetContext(), enityName, options)
+	if err != nil {
+		return err
+	}
+
+	p.Entitys.Store(enityName, enity)
+
+	return nil
+}
+
+func (p *Provider) getEnity(enityName string) (*Enity, error) {
+	if enityName == "" {
+		return nil, errors.ErrEmptyEnityName
+	}
+
+	enity, found := p.Entitys.Load(enityName)
+	if !found {
+		return nil, errors.ErrEnityDoesNotExists
+	}
+
+	// Checking that enity type is *Enity
+	enityPointer, ok := enity.(*Enity)
+	if !ok {
+		return nil, errors.ErrInvalidEnityPointer(Enity{})
+	}
+
+	return enityPointer, nil
+}
+
+// GetEnity returns pointer to gRPC server structure to caller.
+func (p *Provider) GetEnity(enityName string) (interface{}, error) {
+	return p.getEnity(enityName)
+}
+
+// CreateAPIVersionGroup isn't supported, the version is a part of gRPC service name.
+func (p *Provider) CreateAPIVersionGroup(serverName, apiVersion string, middlewares ...interface{}) error {
+	return ErrNotSupported
+}
+
+// GetAPIVersionGroup isn't supported, the version is a part of gRPC service name.
+func (p *Provider) GetAPIVersionGroup(serverName, apiVersion string) (interface{}, error) {
+	return nil, ErrNotSupported
+}
+
+// RegisterEndpoint isn't supported, gRPC services are registered on Enity.Server.
+func (p *Provider) RegisterEndpoint(
+	serverName, method, endpoint string,
+	handler http.Handler,
+	middleware ...httpsrv.MiddleWareFunc) error {
+	return ErrNotSupported
+}
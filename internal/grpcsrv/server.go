@@ -0,0 +1,112 @@
+package grpcsrv
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/soldatov-s/go-garage/providers/errors"
+	"github.com/soldatov-s/go-garage/providers/httpsrv"
+	"github.com/soldatov-s/go-garage/providers/logger"
+	"google.golang.org/grpc"
+)
+
+// Enity describes gRPC server. Services should be registered on Server before the start.
+type Enity struct {
+	ctx     context.Context
+	log     zerolog.Logger
+	address string
+	name    string
+
+	// Server is a gRPC server
+	Server *grpc.Server
+
+	listener   net.Listener
+	listenerMu sync.Mutex
+}
+
+// NewEnity creates new gRPC server.
+func NewEnity(ctx context.Context, serverName string, options interface{}) (*Enity, error) {
+	if serverName == "" {
+		return nil, errors.ErrEmptyEnityName
+	}
+
+	srv := &Enity{
+		name: serverName,
+		ctx:  logger.Registrate(ctx),
+	}
+
+	logger.Get(srv.ctx).GetLogger(httpsrv.ProvidersName, nil).Info().Msgf("initializing enity " + serverName + "...")
+	srv.log = logger.Get(srv.ctx).GetLogger(httpsrv.ProvidersName, nil).With().Str("server name", serverName).Logger()
+
+	serverConfig, ok := options.(*Config)
+	if !ok {
+		return nil, errors.ErrInvalidEnityOptions(Config{})
+	}
+
+	if err := serverConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	srv.address = serverConfig.Address
+	srv.Server = grpc.NewServer(grpc.ChainUnaryInterceptor(srv.logInterceptor))
+
+	return srv, nil
+}
+
+// logInterceptor logs failed calls
+func (srv *Enity) logInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		srv.log.Debug().Err(err).Msgf("%s failed", info.FullMethod)
+	}
+
+	return resp, err
+}
+
+// Start starts gRPC server listening.
+func (srv *Enity) Start() error {
+	srv.listenerMu.Lock()
+	defer srv.listenerMu.Unlock()
+
+	if srv.listener != nil {
+		return ErrServerAlreadyStarted
+	}
+
+	listener, err := net.Listen("tcp", srv.address)
+	if err != nil {
+		return err
+	}
+
+	srv.listener = listener
+
+	go func() {
+		srv.log.Info().Str("address", srv.address).Msg("starting server...")
+
+		if err := srv.Server.Serve(listener); err != nil {
+			srv.log.Error().Err(err).Msg("grpc server critical error occurred")
+		}
+	}()
+
+	return nil
+}
+
+// Shutdown stops gRPC server gracefully.
+func (srv *Enity) Shutdown() error {
+	srv.listenerMu.Lock()
+	defer srv.listenerMu.Unlock()
+
+	if srv.listener == nil {
+		return nil
+	}
+
+	srv.Server.GracefulStop()
+	srv.listener = nil
+
+	return nil
+}
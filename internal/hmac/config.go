@@ -3,7 +3,11 @@ package hmac
 import "time"
 
 type Config struct {
-	TokenEntropy int           `envconfig:"default=100"`
-	SystemSecret string        `envconfig:"default=you_Really_Need_To_ChangeThis!!!"`
-	TTL          time.Duration `envconfig:"default=24h"`
+	TokenEntropy int    `envconfig:"default=100"`
+	SystemSecret string `envconfig:"default=you_Really_Need_To_ChangeThis!!!"`
+	// SystemSecretID is a key ID of SystemSecret, it is embedded in every generated token
+	SystemSecretID string `envconfig:"default=1"`
+	// RotatedSecrets is a list of retired secrets in format "id:secret", they are used only for validation
+	RotatedSecrets []string      `envconfig:"optional"`
+	TTL            time.Duration `envconfig:"default=24h"`
 }
@@ -5,4 +5,8 @@ import "errors"
 var (
 	ErrTokenSignatureMismatch = errors.New("token signature mismatch")
 	ErrInvalidTokenFormat     = errors.New("invalid token")
+	ErrUnknownKeyID           = errors.New("unknown key id")
+	ErrInvalidKeyID           = errors.New("invalid key id")
+	ErrInvalidRotatedSecret   = errors.New("invalid rotated secret, expected format id:secret")
+	ErrDuplicateKeyID         = errors.New("duplicate key id")
 )
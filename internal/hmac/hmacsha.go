@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"sync"
 
+	"github.com/soldatov-s/go-garage-auth/token"
 	"github.com/soldatov-s/go-garage/domains"
 )
 
@@ -13,8 +14,8 @@ const (
 )
 
 type HMACToken struct {
-	cfg          *Config
-	GlobalSecret []byte
+	cfg     *Config
+	Keyring *Keyring
 	sync.Mutex
 }
 
@@ -36,12 +37,19 @@ func HashByteSecret(secret []byte) []byte {
 }
 
 func Registrate(ctx context.Context, cfg *Config) (context.Context, error) {
+	keyring, err := NewKeyring(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	t := &HMACToken{
-		cfg:          cfg,
-		GlobalSecret: HashStringSecret(cfg.SystemSecret),
+		cfg:     cfg,
+		Keyring: keyring,
 	}
 
-	return domains.RegistrateByName(ctx, DomainName, t), nil
+	ctx = domains.RegistrateByName(ctx, DomainName, t)
+
+	return token.Registrate(ctx, DomainName, t), nil
 }
 
 func Get(ctx context.Context) (*HMACToken, error) {
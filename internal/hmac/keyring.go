@@ -0,0 +1,94 @@
+package hmac
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Key is a secret for signing HMAC-SHA256 with its ID
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Keyring holds the current signing key and the retired keys, which are used only for validation.
+// The key ID is embedded in every token, so Validate does not need to try all keys.
+type Keyring struct {
+	current *Key
+	keys    map[string]*Key
+	// retired keys in order of configuration, used for validation of tokens without key ID
+	retired []*Key
+}
+
+// NewKeyring creates a keyring from config. SystemSecret becomes the current signing key,
+// RotatedSecrets become the retired verification keys.
+func NewKeyring(cfg *Config) (*Keyring, error) {
+	k := &Keyring{
+		keys: make(map[string]*Key),
+	}
+
+	current, err := newKey(cfg.SystemSecretID, cfg.SystemSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	k.current = current
+	k.keys[current.ID] = current
+
+	for _, item := range cfg.RotatedSecrets {
+		split := strings.SplitN(item, ":", 2)
+		if len(split) != 2 {
+			return nil, errors.WithStack(ErrInvalidRotatedSecret)
+		}
+
+		key, err := newKey(split[0], split[1])
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := k.keys[key.ID]; ok {
+			return nil, errors.Wrap(ErrDuplicateKeyID, key.ID)
+		}
+
+		k.keys[key.ID] = key
+		k.retired = append(k.retired, key)
+	}
+
+	return k, nil
+}
+
+func newKey(id, secret string) (*Key, error) {
+	if id == "" || strings.ContainsAny(id, ".:") {
+		return nil, errors.Wrapf(ErrInvalidKeyID, "%q", id)
+	}
+
+	if len(secret) < minimumSecretLength {
+		return nil, errors.Errorf("secret %s for signing HMAC-SHA256 is expected to be 32 byte long, got %d byte", id, len(secret))
+	}
+
+	return &Key{
+		ID:     id,
+		Secret: HashStringSecret(secret),
+	}, nil
+}
+
+// Current returns the key for signing new tokens
+func (k *Keyring) Current() *Key {
+	return k.current
+}
+
+// Get returns the key by ID
+func (k *Keyring) Get(id string) (*Key, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownKeyID, "%q", id)
+	}
+
+	return key, nil
+}
+
+// All returns the current key followed by the retired keys
+func (k *Keyring) All() []*Key {
+	return append([]*Key{k.current}, k.retired...)
+}
@@ -6,8 +6,10 @@ import (
 	"encoding/base64"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/soldatov-s/go-garage-auth/models"
 )
 
 const (
@@ -21,17 +23,17 @@ const (
 var b64 = base64.URLEncoding.WithPadding(base64.NoPadding)
 
 // Generate generates a token and a matching signature or returns an error.
+// The token is signed with the current key of the keyring and carries its key ID.
+// The token is opaque, only IssuedAt and ExpiredAt of the claims are filled.
 // This method implements rfc6819 Section 5.1.4.2.2: Use High Entropy for Secrets.
-func (c *HMACToken) Generate() (string, string, error) {
+func (c *HMACToken) Generate(claims *models.TokenClaims) (string, string, error) {
 	c.Lock()
 	defer c.Unlock()
 
-	if len(c.cfg.SystemSecret) < minimumSecretLength {
-		return "", "", errors.Errorf("secret for signing HMAC-SHA256 is expected to be 32 byte long, got %d byte", len(c.cfg.SystemSecret))
-	}
+	key := c.Keyring.Current()
 
 	var signingKey [32]byte
-	copy(signingKey[:], c.GlobalSecret)
+	copy(signingKey[:], key.Secret)
 
 	if c.cfg.TokenEntropy < minimumEntropy {
 		c.cfg.TokenEntropy = minimumEntropy
@@ -44,29 +46,44 @@ func (c *HMACToken) Generate() (string, string, error) {
 
 	signature := generateHMAC(tokenKey, &signingKey)
 
+	claims.IssuedAt = time.Now().UTC()
+	claims.ExpiredAt = claims.IssuedAt.Add(c.cfg.TTL)
+
 	encodedSignature := b64.EncodeToString(signature)
-	encodedToken := fmt.Sprintf("%s.%s", b64.EncodeToString(tokenKey), encodedSignature)
+	encodedToken := fmt.Sprintf("%s.%s.%s", key.ID, b64.EncodeToString(tokenKey), encodedSignature)
 	return encodedToken, encodedSignature, nil
 }
 
-// Validate validates a token and returns its signature or an error if the token is not valid.
+// Validate validates a token or returns an error if the token is not valid.
+// The token is validated with the key which ID is embedded in the token. Tokens issued before
+// key IDs were introduced have no key ID, they are validated with every key in the keyring.
 func (c *HMACToken) Validate(token string) (err error) {
-	if err = c.validate(c.GlobalSecret, token); err == nil {
-		return nil
-	} else if errors.Is(err, ErrTokenSignatureMismatch) {
-	} else {
-		return err
-	}
+	split := strings.Split(token, ".")
+	switch len(split) {
+	case 3:
+		key, err := c.Keyring.Get(split[0])
+		if err != nil {
+			return err
+		}
+
+		return c.validate(key.Secret, split[1], split[2])
+	case 2:
+		// Fallback for tokens without key ID, try the current secret and then the rotated secrets
+		for _, key := range c.Keyring.All() {
+			if err = c.validate(key.Secret, split[0], split[1]); err == nil {
+				return nil
+			} else if !errors.Is(err, ErrTokenSignatureMismatch) {
+				return err
+			}
+		}
 
-	if err == nil {
-		return errors.New("a secret for signing HMAC-SHA256 is expected to be defined, but none were")
+		return err
+	default:
+		return errors.WithStack(ErrInvalidTokenFormat)
 	}
-
-	return err
 }
 
-func (c *HMACToken) validate(secret []byte, token string) error {
-	fmt.Println(token)
+func (c *HMACToken) validate(secret []byte, tokenKey, tokenSignature string) error {
 	if len(secret) < minimumSecretLength {
 		return errors.Errorf("secret for signing HMAC-SHA256 is expected to be 32 byte long, got %d byte", len(secret))
 	}
@@ -74,13 +91,6 @@ func (c *HMACToken) validate(secret []byte, token string) error {
 	var signingKey [32]byte
 	copy(signingKey[:], secret)
 
-	split := strings.Split(token, ".")
-	if len(split) != 2 {
-		return errors.WithStack(ErrInvalidTokenFormat)
-	}
-
-	tokenKey := split[0]
-	tokenSignature := split[1]
 	if tokenKey == "" || tokenSignature == "" {
 		return errors.WithStack(ErrInvalidTokenFormat)
 	}
@@ -107,11 +117,16 @@ func (c *HMACToken) validate(secret []byte, token string) error {
 func (c *HMACToken) Signature(token string) string {
 	split := strings.Split(token, ".")
 
-	if len(split) != 2 {
+	if len(split) != 2 && len(split) != 3 {
 		return ""
 	}
 
-	return split[1]
+	return split[len(split)-1]
+}
+
+// TTL returns the lifetime of the tokens
+func (c *HMACToken) TTL() time.Duration {
+	return c.cfg.TTL
 }
 
 func generateHMAC(data []byte, key *[32]byte) []byte {
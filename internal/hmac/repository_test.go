@@ -0,0 +1,141 @@
+package hmac
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soldatov-s/go-garage-auth/models"
+)
+
+const (
+	testSecret        = "test_secret_which_is_32_bytes_long"
+	testRotatedSecret = "rotated_secret_which_is_32_bytes_long"
+)
+
+func newTestToken(t *testing.T, id, secret string, rotated ...string) *HMACToken {
+	t.Helper()
+
+	cfg := &Config{
+		TokenEntropy:   32,
+		SystemSecret:   secret,
+		SystemSecretID: id,
+		RotatedSecrets: rotated,
+		TTL:            time.Hour,
+	}
+
+	keyring, err := NewKeyring(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &HMACToken{cfg: cfg, Keyring: keyring}
+}
+
+func generate(t *testing.T, c *HMACToken) (token, signature string) {
+	t.Helper()
+
+	token, signature, err := c.Generate(&models.TokenClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token, signature
+}
+
+// tamper changes the first character of the base64 part
+func tamper(part string) string {
+	if part[0] == 'A' {
+		return "B" + part[1:]
+	}
+
+	return "A" + part[1:]
+}
+
+func TestGenerateValidate(t *testing.T) {
+	c := newTestToken(t, "1", testSecret)
+	token, signature := generate(t, c)
+
+	if err := c.Validate(token); err != nil {
+		t.Fatalf("expected valid token, got %v", err)
+	}
+
+	if !strings.HasPrefix(token, "1.") {
+		t.Errorf("expected token with key ID 1, got %s", token)
+	}
+
+	if c.Signature(token) != signature {
+		t.Errorf("expected signature %s, got %s", signature, c.Signature(token))
+	}
+}
+
+func TestValidateRotation(t *testing.T) {
+	old := newTestToken(t, "1", testRotatedSecret)
+	oldToken, _ := generate(t, old)
+
+	c := newTestToken(t, "2", testSecret, "1:"+testRotatedSecret)
+
+	if err := c.Validate(oldToken); err != nil {
+		t.Errorf("expected token of the retired key to be valid, got %v", err)
+	}
+
+	token, _ := generate(t, c)
+	if !strings.HasPrefix(token, "2.") {
+		t.Errorf("expected token with the current key ID 2, got %s", token)
+	}
+
+	if err := old.Validate(token); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("expected %v for the keyring without the current key, got %v", ErrUnknownKeyID, err)
+	}
+}
+
+func TestValidateTampered(t *testing.T) {
+	c := newTestToken(t, "2", testSecret, "1:"+testRotatedSecret)
+	token, _ := generate(t, c)
+	split := strings.Split(token, ".")
+
+	for name, tc := range map[string]struct {
+		token string
+		err   error
+	}{
+		"tampered signature": {strings.Join([]string{split[0], split[1], tamper(split[2])}, "."), ErrTokenSignatureMismatch},
+		"tampered key":       {strings.Join([]string{split[0], tamper(split[1]), split[2]}, "."), ErrTokenSignatureMismatch},
+		"retired kid":        {strings.Join([]string{"1", split[1], split[2]}, "."), ErrTokenSignatureMismatch},
+		"unknown kid":        {strings.Join([]string{"3", split[1], split[2]}, "."), ErrUnknownKeyID},
+		"empty signature":    {strings.Join([]string{split[0], split[1], ""}, "."), ErrInvalidTokenFormat},
+		"too many parts":     {token + ".extra", ErrInvalidTokenFormat},
+	} {
+		if err := c.Validate(tc.token); !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected %v, got %v", name, tc.err, err)
+		}
+	}
+}
+
+func TestValidateLegacy(t *testing.T) {
+	old := newTestToken(t, "1", testRotatedSecret)
+	oldToken, _ := generate(t, old)
+
+	c := newTestToken(t, "2", testSecret, "1:"+testRotatedSecret)
+	token, signature := generate(t, c)
+
+	// Tokens issued before key IDs have only the key and the signature
+	for name, legacy := range map[string]string{
+		"current key": strings.SplitN(token, ".", 2)[1],
+		"retired key": strings.SplitN(oldToken, ".", 2)[1],
+	} {
+		if err := c.Validate(legacy); err != nil {
+			t.Errorf("%s: expected valid legacy token, got %v", name, err)
+		}
+	}
+
+	legacy := strings.SplitN(token, ".", 2)[1]
+	if c.Signature(legacy) != signature {
+		t.Errorf("expected signature %s of legacy token, got %s", signature, c.Signature(legacy))
+	}
+
+	split := strings.Split(legacy, ".")
+	if err := c.Validate(split[0] + "." + tamper(split[1])); !errors.Is(err, ErrTokenSignatureMismatch) {
+		t.Errorf("expected %v for tampered legacy token, got %v", ErrTokenSignatureMismatch, err)
+	}
+}
@@ -0,0 +1,22 @@
+package jwt
+
+import "time"
+
+type Config struct {
+	// Algorithm is an algorithm of generated signing key, RS256, ES256 or EdDSA.
+	// It is used only if the key is generated.
+	Algorithm string `envconfig:"default=RS256"`
+	// PrivateKeyFile is a path to PEM encoded private key for signing tokens, it is required
+	// unless GenerateKey is set
+	PrivateKeyFile string `envconfig:"optional"`
+	// GenerateKey allows to generate a new key on start if PrivateKeyFile is empty. It is intended for development,
+	// the key isn't shared by replicas and tokens don't survive restart. Its key ID is JWK thumbprint of RFC 7638.
+	GenerateKey bool `envconfig:"default=false"`
+	// KeyID is a key ID of the signing key from PrivateKeyFile, it is embedded in every generated token
+	KeyID string `envconfig:"default=1"`
+	// RotatedPublicKeyFiles is a list of retired public keys in format "id:path", they are used only for validation
+	RotatedPublicKeyFiles []string `envconfig:"optional"`
+	Issuer                string   `envconfig:"optional"`
+	// TTL should be short, revoked tokens stay valid for downstream services until they expire
+	TTL time.Duration `envconfig:"default=5m"`
+}
@@ -0,0 +1,16 @@
+package jwt
+
+import "errors"
+
+var (
+	ErrUnknownKeyID          = errors.New("unknown key id")
+	ErrInvalidKeyID          = errors.New("invalid key id")
+	ErrDuplicateKeyID        = errors.New("duplicate key id")
+	ErrInvalidRotatedKey     = errors.New("invalid rotated public key, expected format id:path")
+	ErrUnsupportedAlgorithm  = errors.New("unsupported algorithm")
+	ErrUnsupportedKeyType    = errors.New("unsupported key type")
+	ErrInvalidPEM            = errors.New("failed to decode PEM block")
+	ErrSigningMethodMismatch = errors.New("signing method mismatch")
+	ErrInvalidClaims         = errors.New("invalid claims")
+	ErrNoPrivateKey          = errors.New("private key file isn't configured")
+)
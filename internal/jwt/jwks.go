@@ -0,0 +1,129 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+)
+
+var b64 = base64.RawURLEncoding
+
+// JWK is a public key in JSON Web Key format by RFC 7517
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC and OKP
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the keyring, including the retired keys,
+// so downstream services can validate tokens issued before rotation
+func (c *JWTToken) JWKS() *JWKS {
+	set := &JWKS{Keys: []JWK{}}
+
+	for _, key := range c.Keyring.All() {
+		jwk, err := publicJWK(key.Public)
+		if err != nil {
+			continue
+		}
+
+		jwk.KeyID = key.ID
+		jwk.Use = "sig"
+		jwk.Algorithm = key.Method.Alg()
+
+		set.Keys = append(set.Keys, *jwk)
+	}
+
+	return set
+}
+
+// publicJWK returns the members of JWK describing the public key itself
+func publicJWK(public crypto.PublicKey) (*JWK, error) {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return &JWK{
+			KeyType: "RSA",
+			N:       b64.EncodeToString(k.N.Bytes()),
+			E:       b64.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+
+		return &JWK{
+			KeyType: "EC",
+			Curve:   k.Curve.Params().Name,
+			X:       b64.EncodeToString(padBytes(k.X.Bytes(), size)),
+			Y:       b64.EncodeToString(padBytes(k.Y.Bytes(), size)),
+		}, nil
+	case ed25519.PublicKey:
+		return &JWK{
+			KeyType: "OKP",
+			Curve:   "Ed25519",
+			X:       b64.EncodeToString(k),
+		}, nil
+	default:
+		return nil, ErrUnsupportedKeyType
+	}
+}
+
+// thumbprint returns JWK thumbprint of the public key by RFC 7638, it is used as the key ID of generated keys
+func thumbprint(public crypto.PublicKey) (string, error) {
+	jwk, err := publicJWK(public)
+	if err != nil {
+		return "", err
+	}
+
+	// Only the required members are hashed, JSON of the map is sorted and has no whitespace
+	members := map[string]string{"kty": jwk.KeyType}
+
+	switch jwk.KeyType {
+	case "RSA":
+		members["n"] = jwk.N
+		members["e"] = jwk.E
+	case "EC":
+		members["crv"] = jwk.Curve
+		members["x"] = jwk.X
+		members["y"] = jwk.Y
+	default:
+		members["crv"] = jwk.Curve
+		members["x"] = jwk.X
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return b64.EncodeToString(sum[:]), nil
+}
+
+// padBytes pads the big-endian number with leading zeros up to size, as required by RFC 7518 Section 6.2.1.2
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+
+	return padded
+}
@@ -0,0 +1,51 @@
+package jwt
+
+import (
+	"context"
+
+	"github.com/soldatov-s/go-garage-auth/token"
+	"github.com/soldatov-s/go-garage/domains"
+	"github.com/soldatov-s/go-garage/providers/logger"
+)
+
+const (
+	DomainName = "jwt"
+)
+
+type empty struct{}
+
+// JWTToken is a strategy of self-contained signed tokens, which can be validated by
+// downstream services with public keys from JWKS
+type JWTToken struct {
+	cfg     *Config
+	Keyring *Keyring
+}
+
+func Registrate(ctx context.Context, cfg *Config) (context.Context, error) {
+	keyring, err := NewKeyring(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.PrivateKeyFile == "" {
+		log := logger.GetPackageLogger(ctx, empty{})
+		log.Warn().Msgf("JWT SIGNING KEY %s IS GENERATED, tokens aren't valid on other replicas and after restart, "+
+			"set PrivateKeyFile for production", keyring.Current().ID)
+	}
+
+	t := &JWTToken{
+		cfg:     cfg,
+		Keyring: keyring,
+	}
+
+	ctx = domains.RegistrateByName(ctx, DomainName, t)
+
+	return token.Registrate(ctx, DomainName, t), nil
+}
+
+func Get(ctx context.Context) (*JWTToken, error) {
+	if v, ok := domains.GetByName(ctx, DomainName).(*JWTToken); ok {
+		return v, nil
+	}
+	return nil, domains.ErrInvalidDomainType
+}
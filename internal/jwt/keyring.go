@@ -0,0 +1,231 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"strings"
+
+	jwtgo "github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+
+	rsaKeySize = 2048
+)
+
+// Key is a key for signing or validation of JWT with its ID
+type Key struct {
+	ID     string
+	Method jwtgo.SigningMethod
+	// Private is nil for retired keys
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// Keyring holds the current signing key and the retired keys, which are used only for validation
+type Keyring struct {
+	current *Key
+	keys    map[string]*Key
+	// retired keys in order of configuration
+	retired []*Key
+}
+
+// NewKeyring creates a keyring from config. The private key becomes the current signing key,
+// RotatedPublicKeyFiles become the retired verification keys. The private key is generated
+// only if GenerateKey is set.
+func NewKeyring(cfg *Config) (*Keyring, error) {
+	if cfg.KeyID == "" || strings.Contains(cfg.KeyID, ":") {
+		return nil, errors.Wrapf(ErrInvalidKeyID, "%q", cfg.KeyID)
+	}
+
+	k := &Keyring{
+		keys: make(map[string]*Key),
+	}
+
+	var (
+		private crypto.Signer
+		err     error
+	)
+
+	switch {
+	case cfg.PrivateKeyFile != "":
+		private, err = loadPrivateKey(cfg.PrivateKeyFile)
+	case cfg.GenerateKey:
+		private, err = generatePrivateKey(cfg.Algorithm)
+	default:
+		err = errors.WithStack(ErrNoPrivateKey)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	method, err := signingMethod(private.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	// The generated keys of replicas and restarts differ, so they must not share the configured ID
+	id := cfg.KeyID
+	if cfg.PrivateKeyFile == "" {
+		if id, err = thumbprint(private.Public()); err != nil {
+			return nil, err
+		}
+	}
+
+	k.current = &Key{
+		ID:      id,
+		Method:  method,
+		Private: private,
+		Public:  private.Public(),
+	}
+	k.keys[k.current.ID] = k.current
+
+	for _, item := range cfg.RotatedPublicKeyFiles {
+		split := strings.SplitN(item, ":", 2)
+		if len(split) != 2 || split[0] == "" {
+			return nil, errors.WithStack(ErrInvalidRotatedKey)
+		}
+
+		if _, ok := k.keys[split[0]]; ok {
+			return nil, errors.Wrap(ErrDuplicateKeyID, split[0])
+		}
+
+		public, err := loadPublicKey(split[1])
+		if err != nil {
+			return nil, err
+		}
+
+		method, err := signingMethod(public)
+		if err != nil {
+			return nil, err
+		}
+
+		key := &Key{
+			ID:     split[0],
+			Method: method,
+			Public: public,
+		}
+
+		k.keys[key.ID] = key
+		k.retired = append(k.retired, key)
+	}
+
+	return k, nil
+}
+
+// Current returns the key for signing new tokens
+func (k *Keyring) Current() *Key {
+	return k.current
+}
+
+// Get returns the key by ID
+func (k *Keyring) Get(id string) (*Key, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownKeyID, "%q", id)
+	}
+
+	return key, nil
+}
+
+// All returns the current key followed by the retired keys
+func (k *Keyring) All() []*Key {
+	return append([]*Key{k.current}, k.retired...)
+}
+
+func generatePrivateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case AlgorithmRS256:
+		return rsa.GenerateKey(rand.Reader, rsaKeySize)
+	case AlgorithmES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	default:
+		return nil, errors.Wrap(ErrUnsupportedAlgorithm, algorithm)
+	}
+}
+
+// signingMethod returns the signing method for the key type, so the algorithm from the token header is never trusted
+func signingMethod(public crypto.PublicKey) (jwtgo.SigningMethod, error) {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return jwtgo.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return nil, errors.Wrap(ErrUnsupportedKeyType, k.Curve.Params().Name)
+		}
+		return jwtgo.SigningMethodES256, nil
+	case ed25519.PublicKey:
+		return jwtgo.SigningMethodEdDSA, nil
+	default:
+		return nil, ErrUnsupportedKeyType
+	}
+}
+
+func readPEM(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.Wrap(ErrInvalidPEM, path)
+	}
+
+	return block.Bytes, nil
+}
+
+func loadPrivateKey(path string) (crypto.Signer, error) {
+	der, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, ErrUnsupportedKeyType
+		}
+		return signer, nil
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParseECPrivateKey(der)
+	if err != nil {
+		return nil, errors.Wrap(ErrUnsupportedKeyType, path)
+	}
+
+	return key, nil
+}
+
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	der, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,126 @@
+package jwt
+
+import (
+	"encoding/hex"
+	"time"
+
+	jwtgo "github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+	"github.com/soldatov-s/go-garage-auth/internal/hmac"
+	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+)
+
+const (
+	// entropy of jti
+	idEntropy = 16
+)
+
+type claims struct {
+	jwtgo.RegisteredClaims
+	SessionID string `json:"sid,omitempty"`
+	Role      string `json:"role"`
+	Status    string `json:"status"`
+}
+
+// Generate generates a signed JWT with the claims and returns the token and its jti.
+// The ID, IssuedAt and ExpiredAt of the claims are filled by Generate.
+func (c *JWTToken) Generate(tokenClaims *models.TokenClaims) (string, string, error) {
+	id, err := hmac.RandomBytes(idEntropy)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	tokenClaims.ID = hex.EncodeToString(id)
+	tokenClaims.IssuedAt = now
+	tokenClaims.ExpiredAt = now.Add(c.cfg.TTL)
+
+	key := c.Keyring.Current()
+
+	token := jwtgo.NewWithClaims(key.Method, &claims{
+		RegisteredClaims: jwtgo.RegisteredClaims{
+			ID:        tokenClaims.ID,
+			Issuer:    c.cfg.Issuer,
+			Subject:   tokenClaims.Subject,
+			IssuedAt:  jwtgo.NewNumericDate(tokenClaims.IssuedAt),
+			ExpiresAt: jwtgo.NewNumericDate(tokenClaims.ExpiredAt),
+		},
+		SessionID: tokenClaims.SessionID,
+		Role:      tokenClaims.Role.String(),
+		Status:    tokenClaims.Status.String(),
+	})
+	token.Header["kid"] = key.ID
+
+	signed, err := token.SignedString(key.Private)
+	if err != nil {
+		return "", "", errors.WithStack(err)
+	}
+
+	return signed, tokenClaims.ID, nil
+}
+
+// Validate validates a token or returns an error if the token is not valid
+func (c *JWTToken) Validate(token string) error {
+	_, err := c.Claims(token)
+	return err
+}
+
+// Claims validates a token and returns its claims
+func (c *JWTToken) Claims(token string) (*models.TokenClaims, error) {
+	parsed := &claims{}
+
+	_, err := jwtgo.ParseWithClaims(token, parsed, c.keyFunc)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if parsed.ID == "" || parsed.ExpiresAt == nil || parsed.IssuedAt == nil {
+		return nil, errors.WithStack(ErrInvalidClaims)
+	}
+
+	return &models.TokenClaims{
+		ID:        parsed.ID,
+		Subject:   parsed.Subject,
+		SessionID: parsed.SessionID,
+		Role:      goGarageAuthTypes.StringToRole()[parsed.Role],
+		Status:    goGarageAuthTypes.StringToStatus()[parsed.Status],
+		IssuedAt:  parsed.IssuedAt.Time,
+		ExpiredAt: parsed.ExpiresAt.Time,
+	}, nil
+}
+
+func (c *JWTToken) keyFunc(token *jwtgo.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, err := c.Keyring.Get(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	// The algorithm is defined by the key, not by the token header
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrSigningMethodMismatch
+	}
+
+	return key.Public, nil
+}
+
+// Signature returns the jti of the token, it identifies the token in the storage.
+// The token is not validated, the caller must validate it before.
+func (c *JWTToken) Signature(token string) string {
+	parsed := &claims{}
+
+	_, _, err := jwtgo.NewParser().ParseUnverified(token, parsed)
+	if err != nil {
+		return ""
+	}
+
+	return parsed.ID
+}
+
+// TTL returns the lifetime of the tokens
+func (c *JWTToken) TTL() time.Duration {
+	return c.cfg.TTL
+}
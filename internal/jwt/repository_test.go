@@ -0,0 +1,283 @@
+package jwt
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	jwtgo "github.com/golang-jwt/jwt/v4"
+	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+)
+
+func newTestToken(t *testing.T, cfg *Config) *JWTToken {
+	t.Helper()
+
+	if cfg.TTL == 0 {
+		cfg.TTL = time.Minute
+	}
+
+	if cfg.PrivateKeyFile == "" && !cfg.GenerateKey {
+		cfg.PrivateKeyFile = writePrivateKey(t, cfg.Algorithm)
+	}
+
+	keyring, err := NewKeyring(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &JWTToken{cfg: cfg, Keyring: keyring}
+}
+
+func generate(t *testing.T, c *JWTToken) (token, id string) {
+	t.Helper()
+
+	token, id, err := c.Generate(&models.TokenClaims{
+		Subject:   "1",
+		SessionID: "session",
+		Role:      goGarageAuthTypes.UserL1,
+		Status:    goGarageAuthTypes.Active,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token, id
+}
+
+// writePrivateKey writes a new private key of the algorithm in PEM and returns its path
+func writePrivateKey(t *testing.T, algorithm string) string {
+	t.Helper()
+
+	private, err := generatePrivateKey(algorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "private.pem")
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// writePublicKey writes the public key of the current key in PEM and returns its path
+func writePublicKey(t *testing.T, c *JWTToken) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(c.Keyring.Current().Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "public.pem")
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// tamper changes the first character of the base64 part
+func tamper(part string) string {
+	if part[0] == 'A' {
+		return "B" + part[1:]
+	}
+
+	return "A" + part[1:]
+}
+
+func kidOf(t *testing.T, token string) string {
+	t.Helper()
+
+	parsed, _, err := jwtgo.NewParser().ParseUnverified(token, &claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kid, _ := parsed.Header["kid"].(string)
+
+	return kid
+}
+
+func TestGenerateClaims(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA} {
+		c := newTestToken(t, &Config{Algorithm: algorithm, KeyID: "1"})
+		token, id := generate(t, c)
+
+		tokenClaims, err := c.Claims(token)
+		if err != nil {
+			t.Errorf("%s: expected valid token, got %v", algorithm, err)
+			continue
+		}
+
+		if tokenClaims.ID != id || c.Signature(token) != id {
+			t.Errorf("%s: expected jti %s, got %s and %s", algorithm, id, tokenClaims.ID, c.Signature(token))
+		}
+
+		if tokenClaims.Subject != "1" || tokenClaims.SessionID != "session" ||
+			tokenClaims.Role != goGarageAuthTypes.UserL1 || tokenClaims.Status != goGarageAuthTypes.Active {
+			t.Errorf("%s: unexpected claims %+v", algorithm, tokenClaims)
+		}
+
+		if kid := kidOf(t, token); kid != "1" {
+			t.Errorf("%s: expected kid 1, got %s", algorithm, kid)
+		}
+	}
+}
+
+func TestValidateRotation(t *testing.T) {
+	old := newTestToken(t, &Config{Algorithm: AlgorithmES256, KeyID: "1"})
+	oldToken, _ := generate(t, old)
+
+	c := newTestToken(t, &Config{
+		Algorithm:             AlgorithmEdDSA,
+		KeyID:                 "2",
+		RotatedPublicKeyFiles: []string{"1:" + writePublicKey(t, old)},
+	})
+
+	if err := c.Validate(oldToken); err != nil {
+		t.Errorf("expected token of the retired key to be valid, got %v", err)
+	}
+
+	token, _ := generate(t, c)
+	if kid := kidOf(t, token); kid != "2" {
+		t.Errorf("expected token with the current kid 2, got %s", kid)
+	}
+
+	if err := old.Validate(token); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("expected %v for the keyring without the current key, got %v", ErrUnknownKeyID, err)
+	}
+}
+
+func TestValidateTampered(t *testing.T) {
+	c := newTestToken(t, &Config{Algorithm: AlgorithmEdDSA, KeyID: "1"})
+	token, _ := generate(t, c)
+	split := strings.Split(token, ".")
+
+	if err := c.Validate(split[0] + "." + split[1] + "." + tamper(split[2])); err == nil {
+		t.Error("expected tampered signature to be invalid")
+	}
+
+	// The claims of another token keep the signature of the original one
+	other, _ := generate(t, c)
+	if err := c.Validate(split[0] + "." + strings.Split(other, ".")[1] + "." + split[2]); err == nil {
+		t.Error("expected tampered claims to be invalid")
+	}
+
+	for name, tc := range map[string]struct {
+		kid string
+		err error
+	}{
+		"unknown kid": {"3", ErrUnknownKeyID},
+		"empty kid":   {"", ErrUnknownKeyID},
+	} {
+		header := jwtgo.NewWithClaims(jwtgo.SigningMethodEdDSA, &claims{})
+		header.Header["kid"] = tc.kid
+
+		signed, err := header.SignedString(c.Keyring.Current().Private)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tampered := strings.Split(signed, ".")[0] + "." + split[1] + "." + split[2]
+		if err := c.Validate(tampered); !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected %v, got %v", name, tc.err, err)
+		}
+	}
+}
+
+func TestValidateAlgorithmConfusion(t *testing.T) {
+	c := newTestToken(t, &Config{Algorithm: AlgorithmRS256, KeyID: "1"})
+	es := newTestToken(t, &Config{Algorithm: AlgorithmES256, KeyID: "1"})
+
+	der, err := x509.MarshalPKIXPublicKey(c.Keyring.Current().Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	for name, tc := range map[string]struct {
+		method jwtgo.SigningMethod
+		key    interface{}
+	}{
+		// The public key is known to everybody from JWKS
+		"HS256 with public key":  {jwtgo.SigningMethodHS256, publicPEM},
+		"ES256 with another key": {jwtgo.SigningMethodES256, es.Keyring.Current().Private},
+		"none":                   {jwtgo.SigningMethodNone, jwtgo.UnsafeAllowNoneSignatureType},
+	} {
+		forged := jwtgo.NewWithClaims(tc.method, &claims{
+			RegisteredClaims: jwtgo.RegisteredClaims{
+				ID:        "forged",
+				Subject:   "1",
+				IssuedAt:  jwtgo.NewNumericDate(time.Now()),
+				ExpiresAt: jwtgo.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+			Role: goGarageAuthTypes.Admin.String(),
+		})
+		forged.Header["kid"] = "1"
+
+		signed, err := forged.SignedString(tc.key)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if err := c.Validate(signed); !errors.Is(err, ErrSigningMethodMismatch) {
+			t.Errorf("%s: expected %v, got %v", name, ErrSigningMethodMismatch, err)
+		}
+	}
+}
+
+func TestNewKeyringWithoutKeyFile(t *testing.T) {
+	if _, err := NewKeyring(&Config{Algorithm: AlgorithmES256, KeyID: "1"}); !errors.Is(err, ErrNoPrivateKey) {
+		t.Errorf("expected %v, got %v", ErrNoPrivateKey, err)
+	}
+
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA} {
+		c := newTestToken(t, &Config{Algorithm: algorithm, KeyID: "1", GenerateKey: true})
+
+		expected, err := thumbprint(c.Keyring.Current().Public)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		token, _ := generate(t, c)
+		if kid := kidOf(t, token); kid == "1" || kid != expected {
+			t.Errorf("%s: expected kid %s of the thumbprint, got %s", algorithm, expected, kid)
+		}
+	}
+}
+
+func TestThumbprint(t *testing.T) {
+	// The example of RFC 7638 Section 3.1
+	n, err := b64.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECP" +
+		"ebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnY" +
+		"b9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csF" +
+		"Cur-kEgU8awapJzKnqDKgw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kid, err := thumbprint(&rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; kid != expected {
+		t.Errorf("expected %s, got %s", expected, kid)
+	}
+}
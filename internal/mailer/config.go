@@ -0,0 +1,35 @@
+package mailer
+
+import "time"
+
+type Config struct {
+	// Transport is smtp, file or log, file and log are intended for development
+	Transport string `envconfig:"default=log"`
+	From      string `envconfig:"default=no-reply@localhost"`
+	// Templates is a directory of templates in format <locale>/<type>.tmpl
+	Templates     string `envconfig:"default=/internal/mailer/templates"`
+	DefaultLocale string `envconfig:"default=en"`
+	// BaseURL is an URL of the site for links in messages, it is available in templates as .BaseURL
+	BaseURL string `envconfig:"default=http://localhost:9000"`
+	SMTP    struct {
+		Host     string        `envconfig:"default=localhost"`
+		Port     int           `envconfig:"default=1025"`
+		Username string        `envconfig:"optional"`
+		Password string        `envconfig:"optional"`
+		Timeout  time.Duration `envconfig:"default=10s"`
+	}
+	// Directory of file transport, every message is written into a separate .eml file
+	Directory string `envconfig:"default=/tmp/mail"`
+	// Outbox keeps messages until they are delivered
+	Outbox struct {
+		// Period is a period of polling the outbox
+		Period      time.Duration `envconfig:"default=5s"`
+		BatchSize   int           `envconfig:"default=100"`
+		MaxAttempts int           `envconfig:"default=10"`
+		// MinBackoff is a delay after the first failed attempt, it is doubled after every next one up to MaxBackoff
+		MinBackoff time.Duration `envconfig:"default=10s"`
+		MaxBackoff time.Duration `envconfig:"default=1h"`
+		// Retention is a lifetime of delivered and undeliverable messages
+		Retention time.Duration `envconfig:"default=24h"`
+	}
+}
@@ -0,0 +1,9 @@
+package mailer
+
+import "errors"
+
+var (
+	ErrUnknownTransport = errors.New("unknown mail transport, expected smtp, file or log")
+	ErrUnknownTemplate  = errors.New("unknown mail template")
+	ErrEmptyRecipient   = errors.New("empty recipient")
+)
@@ -0,0 +1,99 @@
+package mailer
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/soldatov-s/go-garage/domains"
+	"github.com/soldatov-s/go-garage/providers/db"
+	"github.com/soldatov-s/go-garage/providers/db/pq"
+	"github.com/soldatov-s/go-garage/providers/logger"
+)
+
+const (
+	DomainName = "mailer"
+)
+
+// Types of messages, every type has a template per locale
+const (
+	TypeActivation    = "activation"
+	TypePasswordReset = "password_reset"
+)
+
+type empty struct{}
+
+// Mailer renders messages by templates and puts them into Postgres outbox,
+// the outbox is delivered in background with retries, so messages aren't lost if SMTP server is down
+type Mailer struct {
+	log       zerolog.Logger
+	cfg       *Config
+	db        *pq.Enity
+	sender    Sender
+	templates templates
+}
+
+func Registrate(ctx context.Context, cfg *Config, dbName string) (context.Context, error) {
+	m := &Mailer{
+		log: logger.GetPackageLogger(ctx, empty{}),
+		cfg: cfg,
+	}
+
+	var err error
+	if m.db, err = pq.GetEnityTypeCast(ctx, dbName); err != nil {
+		return nil, err
+	}
+
+	if m.sender, err = NewSender(cfg, &m.log); err != nil {
+		return nil, err
+	}
+
+	if m.templates, err = loadTemplates(cfg.Templates); err != nil {
+		return nil, err
+	}
+
+	go m.deliverOutbox()
+
+	return domains.RegistrateByName(ctx, DomainName, m), nil
+}
+
+func Get(ctx context.Context) (*Mailer, error) {
+	if v, ok := domains.GetByName(ctx, DomainName).(*Mailer); ok {
+		return v, nil
+	}
+	return nil, domains.ErrInvalidDomainType
+}
+
+// Send renders the message of the type and the locale and puts it into the outbox.
+// The data is available in the template with BaseURL from config.
+func (m *Mailer) Send(mailType, locale, to string, data map[string]interface{}) error {
+	if to == "" {
+		return ErrEmptyRecipient
+	}
+
+	if locale == "" {
+		locale = m.cfg.DefaultLocale
+	}
+
+	values := map[string]interface{}{"BaseURL": m.cfg.BaseURL}
+	for k, v := range data {
+		values[k] = v
+	}
+
+	subject, body, err := m.templates.render(mailType, locale, m.cfg.DefaultLocale, values)
+	if err != nil {
+		return err
+	}
+
+	if m.db.Conn == nil {
+		return db.ErrDBConnNotEstablished
+	}
+
+	now := time.Now()
+
+	_, err = m.db.Conn.Exec(`INSERT INTO production.mail_outbox (mail_type, mail_locale, mail_to, mail_subject,
+		mail_body, mail_next_attempt_at, created_at) VALUES ($1, $2, $3, $4, $5, $6, $6)`,
+		mailType, locale, to, subject, body, now)
+
+	return err
+}
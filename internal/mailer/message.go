@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"bytes"
+	"mime"
+	"mime/quotedprintable"
+	"time"
+)
+
+// Message is a plain text email
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Body    string
+}
+
+// Bytes returns the message in RFC 5322 format, the body is encoded as quoted-printable UTF-8
+func (m *Message) Bytes() []byte {
+	var b bytes.Buffer
+
+	b.WriteString("From: " + m.From + "\r\n")
+	b.WriteString("To: " + m.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", m.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	w := quotedprintable.NewWriter(&b)
+	_, _ = w.Write([]byte(m.Body))
+	_ = w.Close()
+
+	return b.Bytes()
+}
+
+// Sender delivers messages
+type Sender interface {
+	Send(m *Message) error
+}
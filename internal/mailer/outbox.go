@@ -0,0 +1,104 @@
+package mailer
+
+import (
+	"time"
+
+	"github.com/soldatov-s/go-garage/types"
+)
+
+// mail is a message in the outbox
+type mail struct {
+	ID            int64            `db:"mail_id"`
+	Type          string           `db:"mail_type"`
+	Locale        string           `db:"mail_locale"`
+	To            string           `db:"mail_to"`
+	Subject       string           `db:"mail_subject"`
+	Body          string           `db:"mail_body"`
+	Attempts      int              `db:"mail_attempts"`
+	LastError     types.NullString `db:"mail_last_error"`
+	NextAttemptAt time.Time        `db:"mail_next_attempt_at"`
+	SentAt        types.NullTime   `db:"mail_sent_at"`
+	CreatedAt     time.Time        `db:"created_at"`
+}
+
+// backoff returns a delay before the next attempt after the failed attempts
+func (m *Mailer) backoff(attempts int) time.Duration {
+	delay := m.cfg.Outbox.MinBackoff
+	for i := 1; i < attempts && delay < m.cfg.Outbox.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > m.cfg.Outbox.MaxBackoff {
+		delay = m.cfg.Outbox.MaxBackoff
+	}
+
+	return delay
+}
+
+func (m *Mailer) deliverOutbox() {
+	for {
+		time.Sleep(m.cfg.Outbox.Period)
+
+		if m.db.Conn == nil {
+			continue
+		}
+
+		if err := m.deliverBatch(); err != nil {
+			m.log.Err(err).Msg("failed to deliver mail outbox")
+		}
+
+		_, err := m.db.Conn.Exec(`DELETE FROM production.mail_outbox WHERE mail_sent_at<=$1
+			OR (mail_sent_at IS NULL AND mail_attempts>=$2 AND mail_next_attempt_at<=$1)`,
+			time.Now().Add(-m.cfg.Outbox.Retention), m.cfg.Outbox.MaxAttempts)
+		if err != nil {
+			m.log.Err(err).Msg("failed to clear mail outbox")
+		}
+	}
+}
+
+// deliverBatch sends the pending messages, the rows are locked,
+// so every message is sent by only one replica
+func (m *Mailer) deliverBatch() error {
+	tx, err := m.db.Conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	var mails []mail
+
+	err = tx.Select(&mails, `SELECT * FROM production.mail_outbox WHERE mail_sent_at IS NULL AND mail_attempts<$1
+		AND mail_next_attempt_at<=$2 ORDER BY mail_id LIMIT $3 FOR UPDATE SKIP LOCKED`,
+		m.cfg.Outbox.MaxAttempts, time.Now(), m.cfg.Outbox.BatchSize)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	for i := range mails {
+		item := &mails[i]
+		item.Attempts++
+
+		sendErr := m.sender.Send(&Message{From: m.cfg.From, To: item.To, Subject: item.Subject, Body: item.Body})
+		if sendErr == nil {
+			_, err = tx.Exec(`UPDATE production.mail_outbox SET mail_attempts=$1, mail_sent_at=$2, mail_last_error=NULL
+				WHERE mail_id=$3`, item.Attempts, time.Now(), item.ID)
+		} else {
+			m.log.Err(sendErr).Msgf("failed to send mail %d %s, attempt %d", item.ID, item.Type, item.Attempts)
+
+			if item.Attempts >= m.cfg.Outbox.MaxAttempts {
+				m.log.Error().Msgf("mail %d %s is undeliverable", item.ID, item.Type)
+			}
+
+			_, err = tx.Exec(`UPDATE production.mail_outbox SET mail_attempts=$1, mail_last_error=$2,
+				mail_next_attempt_at=$3 WHERE mail_id=$4`,
+				item.Attempts, sendErr.Error(), time.Now().Add(m.backoff(item.Attempts)), item.ID)
+		}
+
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,115 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// NewSender returns the sender of the transport from config
+func NewSender(cfg *Config, log *zerolog.Logger) (Sender, error) {
+	switch cfg.Transport {
+	case "smtp":
+		return &SMTPSender{cfg: cfg}, nil
+	case "file":
+		if err := os.MkdirAll(cfg.Directory, 0o700); err != nil {
+			return nil, err
+		}
+
+		return &FileSender{directory: cfg.Directory}, nil
+	case "log":
+		return &LogSender{log: log}, nil
+	}
+
+	return nil, ErrUnknownTransport
+}
+
+// SMTPSender delivers messages by SMTP server, STARTTLS is used if the server supports it
+type SMTPSender struct {
+	cfg *Config
+}
+
+func (s *SMTPSender) Send(m *Message) error {
+	addr := net.JoinHostPort(s.cfg.SMTP.Host, strconv.Itoa(s.cfg.SMTP.Port))
+
+	conn, err := net.DialTimeout("tcp", addr, s.cfg.SMTP.Timeout)
+	if err != nil {
+		return err
+	}
+
+	if err = conn.SetDeadline(time.Now().Add(s.cfg.SMTP.Timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, s.cfg.SMTP.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: s.cfg.SMTP.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return err
+		}
+	}
+
+	if s.cfg.SMTP.Username != "" {
+		auth := smtp.PlainAuth("", s.cfg.SMTP.Username, s.cfg.SMTP.Password, s.cfg.SMTP.Host)
+		if err = c.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err = c.Mail(m.From); err != nil {
+		return err
+	}
+
+	if err = c.Rcpt(m.To); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.Write(m.Bytes()); err != nil {
+		return err
+	}
+
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// FileSender writes every message into a separate .eml file of the directory
+type FileSender struct {
+	directory string
+}
+
+func (s *FileSender) Send(m *Message) error {
+	name := filepath.Join(s.directory, fmt.Sprintf("%d.eml", time.Now().UnixNano()))
+	return ioutil.WriteFile(name, m.Bytes(), 0o600)
+}
+
+// LogSender writes messages into the log
+type LogSender struct {
+	log *zerolog.Logger
+}
+
+func (s *LogSender) Send(m *Message) error {
+	s.log.Info().Str("to", m.To).Str("subject", m.Subject).Msg(m.Body)
+	return nil
+}
@@ -0,0 +1,72 @@
+package mailer
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// templates are parsed templates by locale and type of message
+type templates map[string]map[string]*template.Template
+
+// loadTemplates parses the templates in format <locale>/<type>.tmpl,
+// every template defines "subject" and "body"
+func loadTemplates(directory string) (templates, error) {
+	files, err := filepath.Glob(filepath.Join(directory, "*", "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+
+	t := make(templates)
+
+	for _, file := range files {
+		locale := filepath.Base(filepath.Dir(file))
+		mailType := strings.TrimSuffix(filepath.Base(file), ".tmpl")
+
+		parsed, err := template.New(mailType).Option("missingkey=error").ParseFiles(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range []string{"subject", "body"} {
+			if parsed.Lookup(name) == nil {
+				return nil, ErrUnknownTemplate
+			}
+		}
+
+		if t[locale] == nil {
+			t[locale] = make(map[string]*template.Template)
+		}
+
+		t[locale][mailType] = parsed
+	}
+
+	return t, nil
+}
+
+// render returns the subject and the body of the message, the template of the default locale
+// is used if there is no template of the locale
+func (t templates) render(mailType, locale, defaultLocale string, data interface{}) (subject, body string, err error) {
+	tmpl, ok := t[locale][mailType]
+	if !ok {
+		if tmpl, ok = t[defaultLocale][mailType]; !ok {
+			return "", "", ErrUnknownTemplate
+		}
+	}
+
+	var b bytes.Buffer
+	if err = tmpl.ExecuteTemplate(&b, "subject", data); err != nil {
+		return "", "", err
+	}
+
+	subject = strings.TrimSpace(b.String())
+
+	b.Reset()
+
+	if err = tmpl.ExecuteTemplate(&b, "body", data); err != nil {
+		return "", "", err
+	}
+
+	return subject, b.String(), nil
+}
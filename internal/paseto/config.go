@@ -0,0 +1,19 @@
+package paseto
+
+import "time"
+
+type Config struct {
+	// Purpose is local for encrypted tokens or public for signed tokens
+	Purpose string `envconfig:"default=local"`
+	// Secret is a secret of local tokens, it is hashed to 32 bytes key
+	Secret string `envconfig:"default=you_Really_Need_To_ChangeThis!!!"`
+	// PrivateKeyFile is a path to PEM encoded Ed25519 private key for signing public tokens.
+	// If it is empty, a new key is generated on start and tokens don't survive restart.
+	PrivateKeyFile string `envconfig:"optional"`
+	// KeyID is a key ID of the current key, it is embedded in the footer of every generated token
+	KeyID string `envconfig:"default=1"`
+	// RotatedKeys is a list of retired keys, they are used only for validation.
+	// The format is "id:secret" for local tokens and "id:path" to PEM encoded public key for public tokens.
+	RotatedKeys []string      `envconfig:"optional"`
+	TTL         time.Duration `envconfig:"default=15m"`
+}
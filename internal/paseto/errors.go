@@ -0,0 +1,17 @@
+package paseto
+
+import "errors"
+
+var (
+	ErrUnknownKeyID       = errors.New("unknown key id")
+	ErrInvalidKeyID       = errors.New("invalid key id")
+	ErrDuplicateKeyID     = errors.New("duplicate key id")
+	ErrInvalidRotatedKey  = errors.New("invalid rotated key, expected format id:secret or id:path")
+	ErrUnsupportedPurpose = errors.New("unsupported purpose")
+	ErrUnsupportedKeyType = errors.New("unsupported key type, expected Ed25519")
+	ErrInvalidPEM         = errors.New("failed to decode PEM block")
+	ErrInvalidTokenFormat = errors.New("invalid token")
+	ErrInvalidToken       = errors.New("token authentication failed")
+	ErrInvalidClaims      = errors.New("invalid claims")
+	ErrTokenExpired       = errors.New("token has expired")
+)
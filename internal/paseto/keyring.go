@@ -0,0 +1,217 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/soldatov-s/go-garage-auth/internal/hmac"
+)
+
+const (
+	PurposeLocal  = "local"
+	PurposePublic = "public"
+
+	minimumSecretLength = 32
+)
+
+// Key is a key for encryption or signing of PASETO with its ID
+type Key struct {
+	ID string
+	// Local is a symmetric key of v4.local tokens
+	Local []byte
+	// Private is nil for retired keys of v4.public tokens
+	Private ed25519.PrivateKey
+	Public  ed25519.PublicKey
+}
+
+// Keyring holds the current key and the retired keys, which are used only for validation
+type Keyring struct {
+	current *Key
+	keys    map[string]*Key
+}
+
+// NewKeyring creates a keyring from config. Secret or PrivateKeyFile becomes the current key
+// depending on the purpose, RotatedKeys become the retired keys.
+func NewKeyring(cfg *Config) (*Keyring, error) {
+	if cfg.Purpose != PurposeLocal && cfg.Purpose != PurposePublic {
+		return nil, errors.Wrap(ErrUnsupportedPurpose, cfg.Purpose)
+	}
+
+	if err := validateKeyID(cfg.KeyID); err != nil {
+		return nil, err
+	}
+
+	k := &Keyring{
+		keys: make(map[string]*Key),
+	}
+
+	var err error
+
+	if cfg.Purpose == PurposeLocal {
+		k.current, err = newLocalKey(cfg.KeyID, cfg.Secret)
+	} else {
+		k.current, err = newPrivateKey(cfg.KeyID, cfg.PrivateKeyFile)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	k.keys[k.current.ID] = k.current
+
+	for _, item := range cfg.RotatedKeys {
+		split := strings.SplitN(item, ":", 2)
+		if len(split) != 2 {
+			return nil, errors.WithStack(ErrInvalidRotatedKey)
+		}
+
+		if err := validateKeyID(split[0]); err != nil {
+			return nil, err
+		}
+
+		if _, ok := k.keys[split[0]]; ok {
+			return nil, errors.Wrap(ErrDuplicateKeyID, split[0])
+		}
+
+		var key *Key
+		if cfg.Purpose == PurposeLocal {
+			key, err = newLocalKey(split[0], split[1])
+		} else {
+			key, err = newPublicKey(split[0], split[1])
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		k.keys[key.ID] = key
+	}
+
+	return k, nil
+}
+
+// Current returns the key for new tokens
+func (k *Keyring) Current() *Key {
+	return k.current
+}
+
+// Get returns the key by ID
+func (k *Keyring) Get(id string) (*Key, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownKeyID, "%q", id)
+	}
+
+	return key, nil
+}
+
+func validateKeyID(id string) error {
+	if id == "" || strings.Contains(id, ":") {
+		return errors.Wrapf(ErrInvalidKeyID, "%q", id)
+	}
+
+	return nil
+}
+
+func newLocalKey(id, secret string) (*Key, error) {
+	if len(secret) < minimumSecretLength {
+		return nil, errors.Errorf("secret %s for PASETO is expected to be 32 byte long, got %d byte", id, len(secret))
+	}
+
+	return &Key{
+		ID:    id,
+		Local: hmac.HashStringSecret(secret),
+	}, nil
+}
+
+func newPrivateKey(id, path string) (*Key, error) {
+	var (
+		private ed25519.PrivateKey
+		err     error
+	)
+
+	if path != "" {
+		private, err = loadPrivateKey(path)
+	} else {
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	}
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &Key{
+		ID:      id,
+		Private: private,
+		Public:  private.Public().(ed25519.PublicKey),
+	}, nil
+}
+
+func newPublicKey(id, path string) (*Key, error) {
+	public, err := loadPublicKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{
+		ID:     id,
+		Public: public,
+	}, nil
+}
+
+func readPEM(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.Wrap(ErrInvalidPEM, path)
+	}
+
+	return block.Bytes, nil
+}
+
+func loadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.Wrap(ErrUnsupportedKeyType, path)
+	}
+
+	return private, nil
+}
+
+func loadPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.Wrap(ErrUnsupportedKeyType, path)
+	}
+
+	return public, nil
+}
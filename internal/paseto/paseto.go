@@ -0,0 +1,42 @@
+package paseto
+
+import (
+	"context"
+
+	"github.com/soldatov-s/go-garage-auth/token"
+	"github.com/soldatov-s/go-garage/domains"
+)
+
+const (
+	DomainName = "paseto"
+)
+
+// PASETOToken is a strategy of self-contained PASETO v4 tokens. The local tokens are encrypted
+// and opaque for clients, the public tokens are signed and can be validated by downstream services.
+type PASETOToken struct {
+	cfg     *Config
+	Keyring *Keyring
+}
+
+func Registrate(ctx context.Context, cfg *Config) (context.Context, error) {
+	keyring, err := NewKeyring(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &PASETOToken{
+		cfg:     cfg,
+		Keyring: keyring,
+	}
+
+	ctx = domains.RegistrateByName(ctx, DomainName, t)
+
+	return token.Registrate(ctx, DomainName, t), nil
+}
+
+func Get(ctx context.Context) (*PASETOToken, error) {
+	if v, ok := domains.GetByName(ctx, DomainName).(*PASETOToken); ok {
+		return v, nil
+	}
+	return nil, domains.ErrInvalidDomainType
+}
@@ -0,0 +1,170 @@
+package paseto
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/soldatov-s/go-garage-auth/internal/hmac"
+	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+)
+
+const (
+	// entropy of jti
+	idEntropy = 16
+)
+
+// claims is a payload of the token, registered claims are named by PASETO spec
+type claims struct {
+	ID        string                 `json:"jti"`
+	Subject   string                 `json:"sub"`
+	IssuedAt  time.Time              `json:"iat"`
+	ExpiredAt time.Time              `json:"exp"`
+	SessionID string                 `json:"sid,omitempty"`
+	Role      string                 `json:"role"`
+	Status    string                 `json:"status"`
+	Meta      map[string]interface{} `json:"meta,omitempty"`
+}
+
+// footer of the token, it is authenticated but not encrypted
+type tokenFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// Generate generates a PASETO token with the claims and returns the token and its jti.
+// The ID, IssuedAt and ExpiredAt of the claims are filled by Generate.
+func (c *PASETOToken) Generate(tokenClaims *models.TokenClaims) (string, string, error) {
+	id, err := hmac.RandomBytes(idEntropy)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	tokenClaims.ID = hex.EncodeToString(id)
+	tokenClaims.IssuedAt = now
+	tokenClaims.ExpiredAt = now.Add(c.cfg.TTL)
+
+	payload, err := json.Marshal(&claims{
+		ID:        tokenClaims.ID,
+		Subject:   tokenClaims.Subject,
+		IssuedAt:  tokenClaims.IssuedAt,
+		ExpiredAt: tokenClaims.ExpiredAt,
+		SessionID: tokenClaims.SessionID,
+		Role:      tokenClaims.Role.String(),
+		Status:    tokenClaims.Status.String(),
+		Meta:      tokenClaims.Meta,
+	})
+	if err != nil {
+		return "", "", errors.WithStack(err)
+	}
+
+	key := c.Keyring.Current()
+
+	f, err := json.Marshal(&tokenFooter{KeyID: key.ID})
+	if err != nil {
+		return "", "", errors.WithStack(err)
+	}
+
+	var token string
+
+	if c.cfg.Purpose == PurposeLocal {
+		token, err = encryptLocal(key.Local, payload, f)
+		if err != nil {
+			return "", "", err
+		}
+	} else {
+		token = signPublic(key.Private, payload, f)
+	}
+
+	return token, tokenClaims.ID, nil
+}
+
+// Validate validates a token or returns an error if the token is not valid
+func (c *PASETOToken) Validate(token string) error {
+	_, err := c.Claims(token)
+	return err
+}
+
+// Claims validates a token and returns its claims
+func (c *PASETOToken) Claims(token string) (*models.TokenClaims, error) {
+	parsed, err := c.claims(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed.ID == "" || parsed.IssuedAt.IsZero() || parsed.ExpiredAt.IsZero() {
+		return nil, errors.WithStack(ErrInvalidClaims)
+	}
+
+	if time.Now().After(parsed.ExpiredAt) {
+		return nil, errors.WithStack(ErrTokenExpired)
+	}
+
+	return &models.TokenClaims{
+		ID:        parsed.ID,
+		Subject:   parsed.Subject,
+		SessionID: parsed.SessionID,
+		Role:      goGarageAuthTypes.StringToRole()[parsed.Role],
+		Status:    goGarageAuthTypes.StringToStatus()[parsed.Status],
+		IssuedAt:  parsed.IssuedAt,
+		ExpiredAt: parsed.ExpiredAt,
+		Meta:      parsed.Meta,
+	}, nil
+}
+
+// claims authenticates the token with the key from its footer and decodes the payload
+func (c *PASETOToken) claims(token string) (*claims, error) {
+	f, err := footer(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var tf tokenFooter
+	if err = json.Unmarshal(f, &tf); err != nil {
+		return nil, errors.Wrap(ErrInvalidTokenFormat, err.Error())
+	}
+
+	key, err := c.Keyring.Get(tf.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+
+	// The purpose is defined by the config, not by the token header
+	if c.cfg.Purpose == PurposeLocal {
+		payload, err = decryptLocal(key.Local, token)
+	} else {
+		payload, err = verifyPublic(key.Public, token)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := &claims{}
+	if err := json.Unmarshal(payload, parsed); err != nil {
+		return nil, errors.Wrap(ErrInvalidClaims, err.Error())
+	}
+
+	return parsed, nil
+}
+
+// Signature returns the jti of the token, it identifies the token in the storage.
+// The payload of local tokens is encrypted, so the token is authenticated to read it.
+func (c *PASETOToken) Signature(token string) string {
+	parsed, err := c.claims(token)
+	if err != nil {
+		return ""
+	}
+
+	return parsed.ID
+}
+
+// TTL returns the lifetime of the tokens
+func (c *PASETOToken) TTL() time.Duration {
+	return c.cfg.TTL
+}
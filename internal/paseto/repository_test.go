@@ -0,0 +1,215 @@
+package paseto
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+)
+
+const (
+	testSecret        = "test_secret_which_is_32_bytes_long"
+	testRotatedSecret = "rotated_secret_which_is_32_bytes_long"
+)
+
+func newTestToken(t *testing.T, cfg *Config) *PASETOToken {
+	t.Helper()
+
+	if cfg.TTL == 0 {
+		cfg.TTL = time.Minute
+	}
+
+	keyring, err := NewKeyring(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &PASETOToken{cfg: cfg, Keyring: keyring}
+}
+
+func generate(t *testing.T, c *PASETOToken) (token, id string) {
+	t.Helper()
+
+	token, id, err := c.Generate(&models.TokenClaims{
+		Subject:   "1",
+		SessionID: "session",
+		Role:      goGarageAuthTypes.UserL1,
+		Status:    goGarageAuthTypes.Active,
+		Meta:      map[string]interface{}{"tenant": "acme"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token, id
+}
+
+// writePublicKey writes the public key of the current key in PEM and returns its path
+func writePublicKey(t *testing.T, c *PASETOToken) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(c.Keyring.Current().Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "public.pem")
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// withFooter replaces the footer of the token
+func withFooter(token, f string) string {
+	return token[:strings.LastIndex(token, ".")+1] + b64.EncodeToString([]byte(f))
+}
+
+// withFlippedBit flips a bit of the body of the token at the offset from the end of the body
+func withFlippedBit(t *testing.T, token, header string, offset int) string {
+	t.Helper()
+
+	body, f, err := decode(header, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body[len(body)-1-offset] ^= 1
+
+	return encode(header, body, f)
+}
+
+func testConfigs(t *testing.T) map[string]func() (*PASETOToken, *PASETOToken) {
+	return map[string]func() (*PASETOToken, *PASETOToken){
+		PurposeLocal: func() (*PASETOToken, *PASETOToken) {
+			old := newTestToken(t, &Config{Purpose: PurposeLocal, Secret: testRotatedSecret, KeyID: "1"})
+			c := newTestToken(t, &Config{
+				Purpose:     PurposeLocal,
+				Secret:      testSecret,
+				KeyID:       "2",
+				RotatedKeys: []string{"1:" + testRotatedSecret},
+			})
+
+			return old, c
+		},
+		PurposePublic: func() (*PASETOToken, *PASETOToken) {
+			old := newTestToken(t, &Config{Purpose: PurposePublic, KeyID: "1"})
+			c := newTestToken(t, &Config{
+				Purpose:     PurposePublic,
+				KeyID:       "2",
+				RotatedKeys: []string{"1:" + writePublicKey(t, old)},
+			})
+
+			return old, c
+		},
+	}
+}
+
+func TestGenerateClaims(t *testing.T) {
+	for purpose, newTokens := range testConfigs(t) {
+		_, c := newTokens()
+		token, id := generate(t, c)
+
+		tokenClaims, err := c.Claims(token)
+		if err != nil {
+			t.Errorf("%s: expected valid token, got %v", purpose, err)
+			continue
+		}
+
+		if tokenClaims.ID != id || c.Signature(token) != id {
+			t.Errorf("%s: expected jti %s, got %s and %s", purpose, id, tokenClaims.ID, c.Signature(token))
+		}
+
+		if tokenClaims.Subject != "1" || tokenClaims.SessionID != "session" ||
+			tokenClaims.Role != goGarageAuthTypes.UserL1 || tokenClaims.Meta["tenant"] != "acme" {
+			t.Errorf("%s: unexpected claims %+v", purpose, tokenClaims)
+		}
+	}
+}
+
+func TestValidateRotation(t *testing.T) {
+	for purpose, newTokens := range testConfigs(t) {
+		old, c := newTokens()
+		oldToken, _ := generate(t, old)
+
+		if err := c.Validate(oldToken); err != nil {
+			t.Errorf("%s: expected token of the retired key to be valid, got %v", purpose, err)
+		}
+
+		token, _ := generate(t, c)
+
+		f, err := footer(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(f) != `{"kid":"2"}` {
+			t.Errorf("%s: expected token with the current kid 2, got %s", purpose, f)
+		}
+
+		if err := old.Validate(token); !errors.Is(err, ErrUnknownKeyID) {
+			t.Errorf("%s: expected %v for the keyring without the current key, got %v", purpose, ErrUnknownKeyID, err)
+		}
+	}
+}
+
+func TestValidateTampered(t *testing.T) {
+	headers := map[string]string{PurposeLocal: headerLocal, PurposePublic: headerPublic}
+
+	for purpose, newTokens := range testConfigs(t) {
+		_, c := newTokens()
+		token, _ := generate(t, c)
+		header := headers[purpose]
+
+		for name, tc := range map[string]struct {
+			token string
+			err   error
+		}{
+			"tampered signature": {withFlippedBit(t, token, header, 0), ErrInvalidToken},
+			"tampered payload":   {withFlippedBit(t, token, header, 70), ErrInvalidToken},
+			// The footer is authenticated, so the token isn't valid with another key
+			"retired kid":     {withFooter(token, `{"kid":"1"}`), ErrInvalidToken},
+			"unknown kid":     {withFooter(token, `{"kid":"3"}`), ErrUnknownKeyID},
+			"invalid kid":     {withFooter(token, `kid`), ErrInvalidTokenFormat},
+			"another purpose": {strings.Replace(token, header, "v4.other.", 1), ErrInvalidTokenFormat},
+		} {
+			if err := c.Validate(tc.token); !errors.Is(err, tc.err) {
+				t.Errorf("%s, %s: expected %v, got %v", purpose, name, tc.err, err)
+			}
+		}
+	}
+}
+
+func TestValidatePurposeConfusion(t *testing.T) {
+	local := newTestToken(t, &Config{Purpose: PurposeLocal, Secret: testSecret, KeyID: "1"})
+	public := newTestToken(t, &Config{Purpose: PurposePublic, KeyID: "1"})
+
+	localToken, _ := generate(t, local)
+	publicToken, _ := generate(t, public)
+
+	// The purpose is defined by the config, so the token of another purpose is refused whatever its header is
+	if err := local.Validate(publicToken); !errors.Is(err, ErrInvalidTokenFormat) {
+		t.Errorf("local: expected %v for public token, got %v", ErrInvalidTokenFormat, err)
+	}
+
+	if err := public.Validate(localToken); !errors.Is(err, ErrInvalidTokenFormat) {
+		t.Errorf("public: expected %v for local token, got %v", ErrInvalidTokenFormat, err)
+	}
+}
+
+func TestValidateExpired(t *testing.T) {
+	c := newTestToken(t, &Config{Purpose: PurposeLocal, Secret: testSecret, KeyID: "1", TTL: -time.Minute})
+	token, _ := generate(t, c)
+
+	if err := c.Validate(token); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expected %v, got %v", ErrTokenExpired, err)
+	}
+}
@@ -0,0 +1,203 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/soldatov-s/go-garage-auth/internal/hmac"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	headerLocal  = "v4.local."
+	headerPublic = "v4.public."
+
+	nonceSize = 32
+	macSize   = 32
+)
+
+var b64 = base64.RawURLEncoding
+
+// pae is a Pre-Authentication Encoding of PASETO
+func pae(pieces ...[]byte) []byte {
+	var le64 [8]byte
+
+	binary.LittleEndian.PutUint64(le64[:], uint64(len(pieces)))
+	out := append([]byte{}, le64[:]...)
+
+	for _, piece := range pieces {
+		binary.LittleEndian.PutUint64(le64[:], uint64(len(piece)))
+		out = append(out, le64[:]...)
+		out = append(out, piece...)
+	}
+
+	return out
+}
+
+func blake2bMAC(size int, key []byte, data ...[]byte) []byte {
+	h, err := blake2b.New(size, key)
+	// blake2b.New returns error only for wrong size or key longer 64 bytes, the panic should never happen
+	if err != nil {
+		panic(err)
+	}
+
+	for _, d := range data {
+		_, _ = h.Write(d)
+	}
+
+	return h.Sum(nil)
+}
+
+// splitKeys derives the encryption key, the counter nonce and the authentication key from the key and the nonce
+func splitKeys(key, nonce []byte) (encKey, counterNonce, authKey []byte) {
+	tmp := blake2bMAC(56, key, []byte("paseto-encryption-key"), nonce)
+	authKey = blake2bMAC(32, key, []byte("paseto-auth-key-for-aead"), nonce)
+
+	return tmp[:32], tmp[32:], authKey
+}
+
+func encode(header string, body, footer []byte) string {
+	token := header + b64.EncodeToString(body)
+	if len(footer) > 0 {
+		token += "." + b64.EncodeToString(footer)
+	}
+
+	return token
+}
+
+// decode splits the token into the body and the footer
+func decode(header, token string) (body, footer []byte, err error) {
+	if !strings.HasPrefix(token, header) {
+		return nil, nil, errors.WithStack(ErrInvalidTokenFormat)
+	}
+
+	split := strings.Split(token[len(header):], ".")
+	if len(split) > 2 {
+		return nil, nil, errors.WithStack(ErrInvalidTokenFormat)
+	}
+
+	if body, err = b64.DecodeString(split[0]); err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	if len(split) == 2 {
+		if footer, err = b64.DecodeString(split[1]); err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+	}
+
+	return body, footer, nil
+}
+
+// footer returns the footer of the token without validation
+func footer(token string) ([]byte, error) {
+	for _, header := range []string{headerLocal, headerPublic} {
+		if strings.HasPrefix(token, header) {
+			_, f, err := decode(header, token)
+			return f, err
+		}
+	}
+
+	return nil, errors.WithStack(ErrInvalidTokenFormat)
+}
+
+// encryptLocal encrypts the message into v4.local token
+func encryptLocal(key, message, footer []byte) (string, error) {
+	nonce, err := hmac.RandomBytes(nonceSize)
+	if err != nil {
+		return "", err
+	}
+
+	return encryptLocalWithNonce(key, nonce, message, footer)
+}
+
+func encryptLocalWithNonce(key, nonce, message, footer []byte) (string, error) {
+	encKey, counterNonce, authKey := splitKeys(key, nonce)
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(encKey, counterNonce)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	ciphertext := make([]byte, len(message))
+	cipher.XORKeyStream(ciphertext, message)
+
+	mac := blake2bMAC(macSize, authKey, pae([]byte(headerLocal), nonce, ciphertext, footer, nil))
+
+	body := make([]byte, 0, nonceSize+len(ciphertext)+macSize)
+	body = append(body, nonce...)
+	body = append(body, ciphertext...)
+	body = append(body, mac...)
+
+	return encode(headerLocal, body, footer), nil
+}
+
+// decryptLocal authenticates and decrypts v4.local token
+func decryptLocal(key []byte, token string) ([]byte, error) {
+	body, footer, err := decode(headerLocal, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) < nonceSize+macSize {
+		return nil, errors.WithStack(ErrInvalidTokenFormat)
+	}
+
+	nonce := body[:nonceSize]
+	ciphertext := body[nonceSize : len(body)-macSize]
+	mac := body[len(body)-macSize:]
+
+	encKey, counterNonce, authKey := splitKeys(key, nonce)
+
+	expectedMAC := blake2bMAC(macSize, authKey, pae([]byte(headerLocal), nonce, ciphertext, footer, nil))
+	if subtle.ConstantTimeCompare(mac, expectedMAC) != 1 {
+		return nil, errors.WithStack(ErrInvalidToken)
+	}
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(encKey, counterNonce)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	message := make([]byte, len(ciphertext))
+	cipher.XORKeyStream(message, ciphertext)
+
+	return message, nil
+}
+
+// signPublic signs the message into v4.public token
+func signPublic(key ed25519.PrivateKey, message, footer []byte) string {
+	signature := ed25519.Sign(key, pae([]byte(headerPublic), message, footer, nil))
+
+	body := make([]byte, 0, len(message)+ed25519.SignatureSize)
+	body = append(body, message...)
+	body = append(body, signature...)
+
+	return encode(headerPublic, body, footer)
+}
+
+// verifyPublic verifies v4.public token and returns its message
+func verifyPublic(key ed25519.PublicKey, token string) ([]byte, error) {
+	body, footer, err := decode(headerPublic, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) < ed25519.SignatureSize {
+		return nil, errors.WithStack(ErrInvalidTokenFormat)
+	}
+
+	message := body[:len(body)-ed25519.SignatureSize]
+	signature := body[len(body)-ed25519.SignatureSize:]
+
+	if !ed25519.Verify(key, pae([]byte(headerPublic), message, footer, nil), signature) {
+		return nil, errors.WithStack(ErrInvalidToken)
+	}
+
+	return message, nil
+}
@@ -0,0 +1,10 @@
+package policy
+
+import "errors"
+
+var (
+	ErrSyntax        = errors.New("syntax error")
+	ErrTypeMismatch  = errors.New("type mismatch")
+	ErrUnknownEffect = errors.New("unknown effect, expected allow or deny")
+	ErrEmptyActions  = errors.New("policy has no actions")
+)
@@ -0,0 +1,463 @@
+package policy
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Expression is a compiled condition of the policy. The language has literals (strings, numbers,
+// true, false, null and lists [a, b]), attribute paths like subject.meta.tenant, comparison operators
+// ==, !=, <, <=, >, >=, the operator in for lists, substrings and keys of objects and logical operators
+// &&, || and !. Missing attributes are null.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile parses the expression, the empty expression is always true
+func Compile(src string) (*Expression, error) {
+	if strings.TrimSpace(src) == "" {
+		return &Expression{source: src, root: literal{value: true}}, nil
+	}
+
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("%w: unexpected %q at %d", ErrSyntax, tok.text, tok.pos)
+	}
+
+	return &Expression{source: src, root: root}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression with the attributes, the result must be boolean
+func (e *Expression) Eval(attributes map[string]interface{}) (bool, error) {
+	value, err := e.root.eval(attributes)
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("%w: expression is %T, expected bool", ErrTypeMismatch, value)
+	}
+
+	return result, nil
+}
+
+type node interface {
+	eval(attributes map[string]interface{}) (interface{}, error)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+
+	return tok
+}
+
+// accept skips the operator or the keyword if it is next
+func (p *parser) accept(text string) bool {
+	tok := p.peek()
+	if (tok.kind == tokenOperator || tok.kind == tokenIdent) && tok.text == text {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		tok := p.peek()
+		return fmt.Errorf("%w: expected %q at %d", ErrSyntax, text, tok.pos)
+	}
+
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = binary{op: "||", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		left = binary{op: "&&", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.accept("!") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return not{operand: operand}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">", "in"} {
+		if p.accept(op) {
+			right, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+
+			return binary{op: op, left: left, right: right}, nil
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+
+	switch {
+	case tok.kind == tokenString, tok.kind == tokenNumber:
+		return literal{value: tok.value}, nil
+	case tok.kind == tokenIdent:
+		switch tok.text {
+		case "true":
+			return literal{value: true}, nil
+		case "false":
+			return literal{value: false}, nil
+		case "null":
+			return literal{value: nil}, nil
+		case "in":
+			return nil, fmt.Errorf("%w: unexpected %q at %d", ErrSyntax, tok.text, tok.pos)
+		}
+
+		return p.parsePath(tok.text)
+	case tok.kind == tokenOperator && tok.text == "(":
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		return expr, p.expect(")")
+	case tok.kind == tokenOperator && tok.text == "[":
+		return p.parseList()
+	}
+
+	return nil, fmt.Errorf("%w: unexpected %q at %d", ErrSyntax, tok.text, tok.pos)
+}
+
+func (p *parser) parsePath(first string) (node, error) {
+	parts := []string{first}
+
+	for p.accept(".") {
+		tok := p.next()
+		if tok.kind != tokenIdent {
+			return nil, fmt.Errorf("%w: expected attribute name at %d", ErrSyntax, tok.pos)
+		}
+
+		parts = append(parts, tok.text)
+	}
+
+	return path{parts: parts}, nil
+}
+
+func (p *parser) parseList() (node, error) {
+	items := list{}
+
+	if p.accept("]") {
+		return items, nil
+	}
+
+	for {
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+
+		if p.accept("]") {
+			return items, nil
+		}
+
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+type literal struct {
+	value interface{}
+}
+
+func (l literal) eval(map[string]interface{}) (interface{}, error) {
+	return l.value, nil
+}
+
+type path struct {
+	parts []string
+}
+
+func (p path) eval(attributes map[string]interface{}) (interface{}, error) {
+	var value interface{} = attributes
+
+	for _, part := range p.parts {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+
+		value = object[part]
+	}
+
+	return value, nil
+}
+
+type list []node
+
+func (l list) eval(attributes map[string]interface{}) (interface{}, error) {
+	values := make([]interface{}, 0, len(l))
+
+	for _, item := range l {
+		value, err := item.eval(attributes)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+type not struct {
+	operand node
+}
+
+func (n not) eval(attributes map[string]interface{}) (interface{}, error) {
+	value, err := evalBool(n.operand, attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	return !value, nil
+}
+
+type binary struct {
+	op          string
+	left, right node
+}
+
+func (b binary) eval(attributes map[string]interface{}) (interface{}, error) {
+	switch b.op {
+	case "&&", "||":
+		left, err := evalBool(b.left, attributes)
+		if err != nil {
+			return nil, err
+		}
+
+		// Short-circuit evaluation
+		if (b.op == "&&" && !left) || (b.op == "||" && left) {
+			return left, nil
+		}
+
+		return evalBool(b.right, attributes)
+	}
+
+	left, err := b.left.eval(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := b.right.eval(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch b.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left)
+	}
+
+	return compare(b.op, left, right)
+}
+
+func evalBool(n node, attributes map[string]interface{}) (bool, error) {
+	value, err := n.eval(attributes)
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("%w: %T is used as bool", ErrTypeMismatch, value)
+	}
+
+	return result, nil
+}
+
+// number converts numeric values to float64, so numbers of JSON and of Go are comparable
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+
+	return 0, false
+}
+
+func equal(left, right interface{}) bool {
+	if l, ok := number(left); ok {
+		r, ok := number(right)
+		return ok && l == r
+	}
+
+	return reflect.DeepEqual(left, right)
+}
+
+func contains(container, item interface{}) (bool, error) {
+	switch c := container.(type) {
+	case nil:
+		return false, nil
+	case []interface{}:
+		for _, value := range c {
+			if equal(value, item) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	case []string:
+		for _, value := range c {
+			if equal(value, item) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	case map[string]interface{}:
+		key, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("%w: key of object is %T", ErrTypeMismatch, item)
+		}
+
+		_, ok = c[key]
+
+		return ok, nil
+	case string:
+		substring, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("%w: substring is %T", ErrTypeMismatch, item)
+		}
+
+		return strings.Contains(c, substring), nil
+	}
+
+	return false, fmt.Errorf("%w: in %T", ErrTypeMismatch, container)
+}
+
+func compare(op string, left, right interface{}) (bool, error) {
+	var result int
+
+	l, lok := number(left)
+	r, rok := number(right)
+
+	switch {
+	case lok && rok:
+		switch {
+		case l < r:
+			result = -1
+		case l > r:
+			result = 1
+		}
+	default:
+		ls, lok := left.(string)
+		rs, rok := right.(string)
+
+		if !lok || !rok {
+			return false, fmt.Errorf("%w: %T %s %T", ErrTypeMismatch, left, op, right)
+		}
+
+		result = strings.Compare(ls, rs)
+	}
+
+	switch op {
+	case "<":
+		return result < 0, nil
+	case "<=":
+		return result <= 0, nil
+	case ">":
+		return result > 0, nil
+	}
+
+	return result >= 0, nil
+}
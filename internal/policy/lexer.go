@@ -0,0 +1,92 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+// operators are sorted so the longest operator is matched first
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ",", "."}
+
+// lex splits the expression into tokens
+func lex(src string) ([]token, error) {
+	var tokens []token
+
+	for pos := 0; pos < len(src); {
+		r := rune(src[pos])
+
+		switch {
+		case unicode.IsSpace(r):
+			pos++
+		case r == '"' || r == '\'':
+			end := strings.IndexRune(src[pos+1:], r)
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated string at %d", ErrSyntax, pos)
+			}
+
+			text := src[pos : pos+end+2]
+			tokens = append(tokens, token{kind: tokenString, text: text, value: text[1 : len(text)-1], pos: pos})
+			pos += len(text)
+		case unicode.IsDigit(r) || (r == '-' && pos+1 < len(src) && unicode.IsDigit(rune(src[pos+1]))):
+			end := pos + 1
+			for end < len(src) && (unicode.IsDigit(rune(src[end])) || src[end] == '.') {
+				end++
+			}
+
+			value, err := strconv.ParseFloat(src[pos:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: bad number %q at %d", ErrSyntax, src[pos:end], pos)
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: src[pos:end], value: value, pos: pos})
+			pos = end
+		case unicode.IsLetter(r) || r == '_':
+			end := pos + 1
+			for end < len(src) && (unicode.IsLetter(rune(src[end])) || unicode.IsDigit(rune(src[end])) || src[end] == '_') {
+				end++
+			}
+
+			tokens = append(tokens, token{kind: tokenIdent, text: src[pos:end], pos: pos})
+			pos = end
+		default:
+			op := matchOperator(src[pos:])
+			if op == "" {
+				return nil, fmt.Errorf("%w: unexpected %q at %d", ErrSyntax, r, pos)
+			}
+
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: pos})
+			pos += len(op)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+func matchOperator(src string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(src, op) {
+			return op
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,114 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Effect is an effect of the policy matched the request
+type Effect string
+
+const (
+	Allow Effect = "allow"
+	Deny  Effect = "deny"
+)
+
+// Policy allows or denies the actions if the condition is true
+type Policy struct {
+	Name   string
+	Effect Effect
+	// Actions are names of actions, * matches any action and users:* matches any action of users
+	Actions   []string
+	Condition *Expression
+}
+
+// NewPolicy compiles the condition of the policy
+func NewPolicy(name string, effect Effect, actions []string, condition string) (*Policy, error) {
+	if effect != Allow && effect != Deny {
+		return nil, ErrUnknownEffect
+	}
+
+	if len(actions) == 0 {
+		return nil, ErrEmptyActions
+	}
+
+	expression, err := Compile(condition)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Policy{Name: name, Effect: effect, Actions: actions, Condition: expression}, nil
+}
+
+// Input describes the request of authorization decision
+type Input struct {
+	Subject  map[string]interface{}
+	Action   string
+	Resource map[string]interface{}
+	// Context has attributes of the request, e.g. IP address of the client
+	Context map[string]interface{}
+}
+
+// attributes returns the attributes available in conditions
+func (i *Input) attributes() map[string]interface{} {
+	return map[string]interface{}{
+		"subject":  i.Subject,
+		"action":   i.Action,
+		"resource": i.Resource,
+		"context":  i.Context,
+	}
+}
+
+// Decision is a result of evaluation
+type Decision struct {
+	Allowed bool
+	Reason  string
+	// Policy is a name of the policy which made the decision, it is empty if no policy matched
+	Policy string
+}
+
+// Evaluate evaluates the policies, deny overrides allow and nothing is allowed by default.
+// Failed conditions of deny policies deny the request, failed conditions of allow policies don't allow it.
+func Evaluate(policies []*Policy, input *Input) Decision {
+	attributes := input.attributes()
+
+	var allowedBy *Policy
+
+	for _, p := range policies {
+		if !p.matchAction(input.Action) {
+			continue
+		}
+
+		matched, err := p.Condition.Eval(attributes)
+
+		switch {
+		case err != nil && p.Effect == Deny:
+			return Decision{Reason: fmt.Sprintf("condition of policy %s failed: %s", p.Name, err), Policy: p.Name}
+		case err != nil, !matched:
+			continue
+		case p.Effect == Deny:
+			return Decision{Reason: fmt.Sprintf("denied by policy %s", p.Name), Policy: p.Name}
+		case allowedBy == nil:
+			allowedBy = p
+		}
+	}
+
+	if allowedBy == nil {
+		return Decision{Reason: fmt.Sprintf("no policy allows action %s", input.Action)}
+	}
+
+	return Decision{Allowed: true, Reason: fmt.Sprintf("allowed by policy %s", allowedBy.Name), Policy: allowedBy.Name}
+}
+
+func (p *Policy) matchAction(action string) bool {
+	for _, pattern := range p.Actions {
+		switch {
+		case pattern == "*", pattern == action:
+			return true
+		case strings.HasSuffix(pattern, ":*") && strings.HasPrefix(action, strings.TrimSuffix(pattern, "*")):
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,132 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+)
+
+func testInput() *Input {
+	return &Input{
+		Subject: map[string]interface{}{
+			"id":          "1",
+			"role":        "USER_L1",
+			"permissions": []string{"users:read"},
+			"meta":        map[string]interface{}{"tenant": "acme", "level": float64(3)},
+		},
+		Action:   "documents:read",
+		Resource: map[string]interface{}{"tenant": "acme", "owner": "1", "tags": []interface{}{"public"}},
+		Context:  map[string]interface{}{"ip": "10.0.0.1"},
+	}
+}
+
+func TestExpressionEval(t *testing.T) {
+	attributes := testInput().attributes()
+
+	for src, expected := range map[string]bool{
+		``:                                       true,
+		`subject.meta.tenant == resource.tenant`: true,
+		`subject.meta.tenant != "acme"`:          false,
+		`subject.meta.level >= 3 && subject.meta.level < 4`: true,
+		`subject.id == resource.owner || false`:             true,
+		`!(subject.role in ["ADMIN", "SUPERUSER"])`:         true,
+		`"users:read" in subject.permissions`:               true,
+		`"public" in resource.tags`:                         true,
+		`"tenant" in subject.meta`:                          true,
+		`"10.0." in context.ip`:                             true,
+		`subject.meta.missing == null`:                      true,
+		`action == 'documents:read'`:                        true,
+		`-1 < 0`:                                            true,
+		`"a" < "b"`:                                         true,
+	} {
+		expression, err := Compile(src)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+
+		result, err := expression.Eval(attributes)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+
+		if result != expected {
+			t.Errorf("%s: expected %v, got %v", src, expected, result)
+		}
+	}
+}
+
+func TestExpressionErrors(t *testing.T) {
+	for _, src := range []string{`subject.id ==`, `(true`, `[1, 2`, `"unterminated`, `a # b`, `in`, `true true`} {
+		if _, err := Compile(src); !errors.Is(err, ErrSyntax) {
+			t.Errorf("%s: expected syntax error, got %v", src, err)
+		}
+	}
+
+	attributes := testInput().attributes()
+
+	for _, src := range []string{`subject.id`, `subject.meta.level && true`, `subject.meta.missing < 1`} {
+		expression, err := Compile(src)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+
+		if _, err := expression.Eval(attributes); !errors.Is(err, ErrTypeMismatch) {
+			t.Errorf("%s: expected type mismatch, got %v", src, err)
+		}
+	}
+}
+
+func mustPolicy(t *testing.T, name string, effect Effect, actions []string, condition string) *Policy {
+	t.Helper()
+
+	p, err := NewPolicy(name, effect, actions, condition)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestEvaluate(t *testing.T) {
+	sameTenant := mustPolicy(t, "same-tenant", Allow, []string{"documents:*"}, `subject.meta.tenant == resource.tenant`)
+	externalIP := mustPolicy(t, "external-ip", Deny, []string{"*"}, `!("10." in context.ip)`)
+	broken := mustPolicy(t, "broken", Deny, []string{"documents:write"}, `subject.meta.missing < 1`)
+
+	input := testInput()
+
+	decision := Evaluate([]*Policy{sameTenant, externalIP, broken}, input)
+	if !decision.Allowed || decision.Policy != "same-tenant" {
+		t.Fatalf("expected allow by same-tenant, got %+v", decision)
+	}
+
+	input.Context["ip"] = "192.168.0.1"
+
+	decision = Evaluate([]*Policy{sameTenant, externalIP}, input)
+	if decision.Allowed || decision.Policy != "external-ip" {
+		t.Fatalf("expected deny by external-ip, got %+v", decision)
+	}
+
+	input = testInput()
+	input.Action = "documents:write"
+
+	decision = Evaluate([]*Policy{sameTenant, broken}, input)
+	if decision.Allowed || decision.Policy != "broken" {
+		t.Fatalf("expected deny by failed condition, got %+v", decision)
+	}
+
+	input.Action = "users:read"
+
+	decision = Evaluate([]*Policy{sameTenant}, input)
+	if decision.Allowed || decision.Policy != "" {
+		t.Fatalf("expected deny by default, got %+v", decision)
+	}
+}
+
+func TestNewPolicyErrors(t *testing.T) {
+	if _, err := NewPolicy("p", "permit", []string{"*"}, ""); !errors.Is(err, ErrUnknownEffect) {
+		t.Errorf("expected unknown effect, got %v", err)
+	}
+
+	if _, err := NewPolicy("p", Allow, nil, ""); !errors.Is(err, ErrEmptyActions) {
+		t.Errorf("expected empty actions, got %v", err)
+	}
+}
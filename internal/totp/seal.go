@@ -0,0 +1,59 @@
+package totp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	goGarageHMAC "github.com/soldatov-s/go-garage-auth/internal/hmac"
+)
+
+var ErrMalformedSealedSecret = errors.New("malformed sealed secret")
+
+// newAEAD returns AES-256-GCM with the key derived from the passphrase
+func newAEAD(passphrase string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(passphrase))
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// Seal encrypts the secret for storing at rest
+func Seal(passphrase string, secret []byte) (string, error) {
+	aead, err := newAEAD(passphrase)
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := goGarageHMAC.RandomBytes(aead.NonceSize())
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, secret, nil)), nil
+}
+
+// Open decrypts the sealed secret
+func Open(passphrase, sealed string) ([]byte, error) {
+	aead, err := newAEAD(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < aead.NonceSize() {
+		return nil, ErrMalformedSealedSecret
+	}
+
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+}
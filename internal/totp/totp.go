@@ -0,0 +1,86 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" // nolint:gosec // RFC 6238 authenticators use HMAC-SHA1 by default
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/url"
+	"time"
+
+	goGarageHMAC "github.com/soldatov-s/go-garage-auth/internal/hmac"
+)
+
+const (
+	// SecretSize is a size of generated secrets, RFC 4226 recommends 160 bits
+	SecretSize = 20
+	Digits     = 6
+	Period     = 30 * time.Second
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a new random secret
+func GenerateSecret() ([]byte, error) {
+	return goGarageHMAC.RandomBytes(SecretSize)
+}
+
+// EncodeSecret returns the secret in base32 for manual input into authenticator
+func EncodeSecret(secret []byte) string {
+	return b32.EncodeToString(secret)
+}
+
+// URI returns otpauth:// URI of the secret for QR code
+func URI(issuer, account string, secret []byte) string {
+	query := url.Values{}
+	query.Set("secret", EncodeSecret(secret))
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(int(Period.Seconds())))
+
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+account) + "?" + query.Encode()
+}
+
+// Code returns the code of the counter by RFC 4226
+func Code(secret []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, secret)
+	_, _ = mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", Digits, value%uint32(math.Pow10(Digits)))
+}
+
+// Counter returns the time step of the moment
+func Counter(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Validate checks the code of the moment, skew is a number of allowed time steps before and after the moment.
+// The codes of the counters up to last were already used and are refused, so every code is accepted once.
+// It returns the counter of the code, the caller stores it as the new last one.
+func Validate(secret []byte, code string, t time.Time, skew int, last int64) (int64, bool) {
+	current := Counter(t)
+
+	for i := -skew; i <= skew; i++ {
+		counter := current + int64(i)
+		if counter <= last {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare([]byte(Code(secret, counter)), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+
+	return 0, false
+}
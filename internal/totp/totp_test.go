@@ -0,0 +1,105 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// Secret of SHA1 test vectors of RFC 6238 Appendix B
+var testSecret = []byte("12345678901234567890")
+
+func TestCodeRFC6238(t *testing.T) {
+	// The vectors have 8 digits, the codes are their last 6 digits
+	for unix, expected := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	} {
+		if code := Code(testSecret, Counter(time.Unix(unix, 0))); code != expected {
+			t.Errorf("%d: expected %s, got %s", unix, expected, code)
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Counter(now)
+
+	for shift, expected := range map[int64]bool{
+		-2: false,
+		-1: true,
+		0:  true,
+		1:  true,
+		2:  false,
+	} {
+		code := Code(testSecret, current+shift)
+
+		counter, ok := Validate(testSecret, code, now, 1, 0)
+		if ok != expected {
+			t.Errorf("shift %d: expected %v, got %v", shift, expected, ok)
+		}
+
+		if ok && counter != current+shift {
+			t.Errorf("shift %d: expected counter %d, got %d", shift, current+shift, counter)
+		}
+	}
+
+	if _, ok := Validate(testSecret, Code(testSecret, current+1), now, 0, 0); ok {
+		t.Error("expected the code of the next step to be refused without skew")
+	}
+
+	if _, ok := Validate([]byte("another secret"), Code(testSecret, current), now, 1, 0); ok {
+		t.Error("expected the code of another secret to be refused")
+	}
+}
+
+func TestValidateReplay(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code := Code(testSecret, Counter(now))
+
+	last, ok := Validate(testSecret, code, now, 1, 0)
+	if !ok {
+		t.Fatal("expected the code to be accepted")
+	}
+
+	if _, ok := Validate(testSecret, code, now, 1, last); ok {
+		t.Error("expected the used code to be refused")
+	}
+
+	// The code of the previous step is refused after the later code was used
+	if _, ok := Validate(testSecret, Code(testSecret, last-1), now, 1, last); ok {
+		t.Error("expected the code of the previous step to be refused")
+	}
+
+	if _, ok := Validate(testSecret, Code(testSecret, last+1), now, 1, last); !ok {
+		t.Error("expected the code of the next step to be accepted")
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := Seal("passphrase", secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := Open("passphrase", sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(opened) != string(secret) {
+		t.Error("expected the opened secret to be equal to the sealed one")
+	}
+
+	if _, err := Open("another passphrase", sealed); err == nil {
+		t.Error("expected an error for the wrong passphrase")
+	}
+}
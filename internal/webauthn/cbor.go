@@ -0,0 +1,174 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// maxCBORDepth limits nesting of decoded items, WebAuthn structures are at most a few levels deep
+const maxCBORDepth = 8
+
+// CBOR major types of RFC 8949
+const (
+	cborUnsigned = iota
+	cborNegative
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+// decodeCBOR decodes the first item of data and returns the rest. Only the subset used by WebAuthn
+// is supported: integers, byte and text strings, arrays and maps of definite length, booleans and null.
+// Integers are decoded as int64, byte strings as []byte, maps as map[interface{}]interface{}.
+func decodeCBOR(data []byte) (item interface{}, rest []byte, err error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > maxCBORDepth {
+		return nil, nil, fmt.Errorf("%w: nesting is too deep", ErrMalformedCBOR)
+	}
+
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("%w: unexpected end of data", ErrMalformedCBOR)
+	}
+
+	major, info := data[0]>>5, data[0]&0x1f
+	data = data[1:]
+
+	if major == cborSimple {
+		switch info {
+		case 20:
+			return false, data, nil
+		case 21:
+			return true, data, nil
+		case 22, 23:
+			return nil, data, nil
+		}
+
+		return nil, nil, fmt.Errorf("%w: unsupported simple value %d", ErrMalformedCBOR, info)
+	}
+
+	value, data, err := decodeCBORArgument(info, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		if value > 1<<63-1 {
+			return nil, nil, fmt.Errorf("%w: integer overflow", ErrMalformedCBOR)
+		}
+
+		return int64(value), data, nil
+	case cborNegative:
+		if value > 1<<63-1 {
+			return nil, nil, fmt.Errorf("%w: integer overflow", ErrMalformedCBOR)
+		}
+
+		return -1 - int64(value), data, nil
+	case cborBytes, cborText:
+		if value > uint64(len(data)) {
+			return nil, nil, fmt.Errorf("%w: unexpected end of data", ErrMalformedCBOR)
+		}
+
+		if major == cborText {
+			return string(data[:value]), data[value:], nil
+		}
+
+		return append([]byte(nil), data[:value]...), data[value:], nil
+	case cborArray:
+		// Every item takes at least one byte, so the length can't exceed the rest of data
+		if value > uint64(len(data)) {
+			return nil, nil, fmt.Errorf("%w: unexpected end of data", ErrMalformedCBOR)
+		}
+
+		items := make([]interface{}, 0, value)
+
+		for i := uint64(0); i < value; i++ {
+			var item interface{}
+			if item, data, err = decodeCBORItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+
+			items = append(items, item)
+		}
+
+		return items, data, nil
+	case cborMap:
+		if value > uint64(len(data)) {
+			return nil, nil, fmt.Errorf("%w: unexpected end of data", ErrMalformedCBOR)
+		}
+
+		items := make(map[interface{}]interface{}, value)
+
+		for i := uint64(0); i < value; i++ {
+			var key, item interface{}
+			if key, data, err = decodeCBORItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, fmt.Errorf("%w: unsupported map key %T", ErrMalformedCBOR, key)
+			}
+
+			if item, data, err = decodeCBORItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+
+			if _, ok := items[key]; ok {
+				return nil, nil, fmt.Errorf("%w: duplicate map key %v", ErrMalformedCBOR, key)
+			}
+
+			items[key] = item
+		}
+
+		return items, data, nil
+	}
+
+	return nil, nil, fmt.Errorf("%w: unsupported major type %d", ErrMalformedCBOR, major)
+}
+
+// decodeCBORArgument decodes the argument of the item head, indefinite lengths aren't supported
+func decodeCBORArgument(info byte, data []byte) (uint64, []byte, error) {
+	var size int
+
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, nil, fmt.Errorf("%w: unsupported additional information %d", ErrMalformedCBOR, info)
+	}
+
+	if len(data) < size {
+		return 0, nil, fmt.Errorf("%w: unexpected end of data", ErrMalformedCBOR)
+	}
+
+	var value uint64
+
+	switch size {
+	case 1:
+		value = uint64(data[0])
+	case 2:
+		value = uint64(binary.BigEndian.Uint16(data))
+	case 4:
+		value = uint64(binary.BigEndian.Uint32(data))
+	case 8:
+		value = binary.BigEndian.Uint64(data)
+	}
+
+	return value, data[size:], nil
+}
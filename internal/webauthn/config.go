@@ -0,0 +1,23 @@
+package webauthn
+
+import "time"
+
+// User verification requirements of WebAuthn
+const (
+	UserVerificationRequired    = "required"
+	UserVerificationPreferred   = "preferred"
+	UserVerificationDiscouraged = "discouraged"
+)
+
+type Config struct {
+	// RPID is the domain of the site, credentials are scoped to it
+	RPID string `envconfig:"default=localhost"`
+	// RPName is the name of the site shown by authenticators
+	RPName string `envconfig:"default=go-garage-auth"`
+	// Origins are origins of the pages allowed to run ceremonies
+	Origins []string `envconfig:"default=http://localhost:9000"`
+	// Timeout is a lifetime of registration and login ceremonies
+	Timeout time.Duration `envconfig:"default=5m"`
+	// UserVerification is required, preferred or discouraged, the assertion without it is rejected only if required
+	UserVerification string `envconfig:"default=preferred"`
+}
@@ -0,0 +1,15 @@
+package webauthn
+
+import "errors"
+
+var (
+	ErrMalformedCBOR             = errors.New("malformed CBOR")
+	ErrMalformedResponse         = errors.New("malformed authenticator response")
+	ErrUnsupportedAttestation    = errors.New("unsupported attestation format")
+	ErrUnsupportedKey            = errors.New("unsupported public key")
+	ErrInvalidClientData         = errors.New("invalid client data")
+	ErrInvalidAuthenticatorData  = errors.New("invalid authenticator data")
+	ErrInvalidSignature          = errors.New("invalid signature")
+	ErrSignCountRollback         = errors.New("signature counter didn't increase, the authenticator may be cloned")
+	ErrInvalidRelyingPartyConfig = errors.New("invalid relying party config")
+)
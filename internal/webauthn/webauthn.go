@@ -0,0 +1,475 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ChallengeSize is a size of generated challenges, WebAuthn requires at least 16 bytes
+	ChallengeSize = 32
+
+	credentialType = "public-key"
+
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+
+	attestationNone = "none"
+)
+
+// Flags of authenticator data
+const (
+	flagUserPresent            = 0x01
+	flagUserVerified           = 0x04
+	flagAttestedCredentialData = 0x40
+	flagExtensionData          = 0x80
+)
+
+// URLEncoded is binary data encoded by base64url without padding like in JSON of WebAuthn Level 3
+type URLEncoded []byte
+
+func (u URLEncoded) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(u))
+}
+
+func (u *URLEncoded) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+
+	*u = decoded
+
+	return nil
+}
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity describes the user for authenticator, ID is the user handle returned by assertions
+type UserEntity struct {
+	ID          URLEncoded `json:"id"`
+	Name        string     `json:"name"`
+	DisplayName string     `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string     `json:"type"`
+	ID         URLEncoded `json:"id"`
+	Transports []string   `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions are options of navigator.credentials.create
+type CreationOptions struct {
+	Challenge              URLEncoded             `json:"challenge"`
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are options of navigator.credentials.get, credentials are discoverable, so none is listed
+type RequestOptions struct {
+	Challenge        URLEncoded             `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+type AuthenticatorAttestationResponse struct {
+	ClientDataJSON    URLEncoded `json:"clientDataJSON"`
+	AttestationObject URLEncoded `json:"attestationObject"`
+	Transports        []string   `json:"transports,omitempty"`
+}
+
+// RegistrationResponse is PublicKeyCredential returned by navigator.credentials.create
+type RegistrationResponse struct {
+	ID       string                           `json:"id"`
+	RawID    URLEncoded                       `json:"rawId"`
+	Type     string                           `json:"type"`
+	Response AuthenticatorAttestationResponse `json:"response"`
+}
+
+type AuthenticatorAssertionResponse struct {
+	ClientDataJSON    URLEncoded `json:"clientDataJSON"`
+	AuthenticatorData URLEncoded `json:"authenticatorData"`
+	Signature         URLEncoded `json:"signature"`
+	UserHandle        URLEncoded `json:"userHandle,omitempty"`
+}
+
+// AssertionResponse is PublicKeyCredential returned by navigator.credentials.get
+type AssertionResponse struct {
+	ID       string                         `json:"id"`
+	RawID    URLEncoded                     `json:"rawId"`
+	Type     string                         `json:"type"`
+	Response AuthenticatorAssertionResponse `json:"response"`
+}
+
+// Credential is a verified new credential
+type Credential struct {
+	ID []byte
+	// PublicKey is COSE_Key of the credential
+	PublicKey  []byte
+	SignCount  uint32
+	AAGUID     []byte
+	Transports []string
+}
+
+// Assertion is the result of the verified login ceremony
+type Assertion struct {
+	SignCount uint32
+	// UserVerified is set if the authenticator verified the user by PIN or biometrics,
+	// otherwise the assertion proves only the possession of the authenticator
+	UserVerified bool
+}
+
+type clientData struct {
+	Type        string     `json:"type"`
+	Challenge   URLEncoded `json:"challenge"`
+	Origin      string     `json:"origin"`
+	CrossOrigin bool       `json:"crossOrigin"`
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+// RelyingParty runs registration and login ceremonies of the site
+type RelyingParty struct {
+	cfg      *Config
+	rpIDHash [sha256.Size]byte
+	origins  map[string]struct{}
+}
+
+func New(cfg *Config) (*RelyingParty, error) {
+	if cfg.RPID == "" || len(cfg.Origins) == 0 {
+		return nil, fmt.Errorf("%w: RPID and Origins are required", ErrInvalidRelyingPartyConfig)
+	}
+
+	switch cfg.UserVerification {
+	case UserVerificationRequired, UserVerificationPreferred, UserVerificationDiscouraged:
+	default:
+		return nil, fmt.Errorf("%w: unknown user verification %q", ErrInvalidRelyingPartyConfig, cfg.UserVerification)
+	}
+
+	rp := &RelyingParty{
+		cfg:      cfg,
+		rpIDHash: sha256.Sum256([]byte(cfg.RPID)),
+		origins:  make(map[string]struct{}, len(cfg.Origins)),
+	}
+
+	for _, origin := range cfg.Origins {
+		rp.origins[origin] = struct{}{}
+	}
+
+	return rp, nil
+}
+
+// NewChallenge returns a random challenge of the ceremony
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, ChallengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// CreationOptions returns options of the registration, credentials of exclude are already registered by the user
+func (rp *RelyingParty) CreationOptions(challenge []byte, user UserEntity, exclude [][]byte) *CreationOptions {
+	options := &CreationOptions{
+		Challenge: challenge,
+		RP:        RelyingPartyEntity{ID: rp.cfg.RPID, Name: rp.cfg.RPName},
+		User:      user,
+		PubKeyCredParams: []CredentialParameter{
+			{Type: credentialType, Alg: AlgES256},
+			{Type: credentialType, Alg: AlgEdDSA},
+			{Type: credentialType, Alg: AlgRS256},
+		},
+		Timeout:            rp.cfg.Timeout.Milliseconds(),
+		ExcludeCredentials: make([]CredentialDescriptor, 0, len(exclude)),
+		// Passkeys are discoverable credentials, login doesn't ask for the user name
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   rp.cfg.UserVerification,
+		},
+		Attestation: attestationNone,
+	}
+
+	for _, id := range exclude {
+		options.ExcludeCredentials = append(options.ExcludeCredentials, CredentialDescriptor{Type: credentialType, ID: id})
+	}
+
+	return options
+}
+
+// RequestOptions returns options of the login
+func (rp *RelyingParty) RequestOptions(challenge []byte) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          rp.cfg.Timeout.Milliseconds(),
+		RPID:             rp.cfg.RPID,
+		AllowCredentials: []CredentialDescriptor{},
+		UserVerification: rp.cfg.UserVerification,
+	}
+}
+
+// Challenge returns the challenge signed by the authenticator, it identifies the ceremony
+func (r *RegistrationResponse) Challenge() ([]byte, error) {
+	data, err := parseClientData(r.Response.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.Challenge, nil
+}
+
+// Challenge returns the challenge signed by the authenticator, it identifies the ceremony
+func (r *AssertionResponse) Challenge() ([]byte, error) {
+	data, err := parseClientData(r.Response.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.Challenge, nil
+}
+
+// VerifyRegistration verifies the response of the registration ceremony by the challenge.
+// Only "none" attestation is accepted, the site trusts the authenticator of the user.
+func (rp *RelyingParty) VerifyRegistration(r *RegistrationResponse, challenge []byte) (*Credential, error) {
+	if r.Type != credentialType {
+		return nil, fmt.Errorf("%w: type %q", ErrMalformedResponse, r.Type)
+	}
+
+	if err := rp.checkClientData(r.Response.ClientDataJSON, ceremonyCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	item, rest, err := decodeCBOR(r.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+
+	attestation, ok := item.(map[interface{}]interface{})
+	if !ok || len(rest) != 0 {
+		return nil, fmt.Errorf("%w: invalid attestation object", ErrMalformedResponse)
+	}
+
+	format, _ := attestation["fmt"].(string)
+	statement, _ := attestation["attStmt"].(map[interface{}]interface{})
+	rawAuthData, _ := attestation["authData"].([]byte)
+
+	if format != attestationNone || len(statement) != 0 {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAttestation, format)
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = rp.checkAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+
+	if authData.flags&flagAttestedCredentialData == 0 {
+		return nil, fmt.Errorf("%w: no attested credential data", ErrInvalidAuthenticatorData)
+	}
+
+	if len(r.RawID) != 0 && !bytes.Equal(r.RawID, authData.credentialID) {
+		return nil, fmt.Errorf("%w: rawId doesn't match the credential", ErrMalformedResponse)
+	}
+
+	if _, err = parsePublicKey(authData.publicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:         authData.credentialID,
+		PublicKey:  authData.publicKey,
+		SignCount:  authData.signCount,
+		AAGUID:     authData.aaguid,
+		Transports: r.Response.Transports,
+	}, nil
+}
+
+// VerifyAssertion verifies the response of the login ceremony by the challenge and the stored credential,
+// it returns the new value of the signature counter and the flag of user verification
+func (rp *RelyingParty) VerifyAssertion(
+	r *AssertionResponse,
+	challenge, credentialPublicKey []byte,
+	signCount uint32) (*Assertion, error) {
+	if r.Type != credentialType {
+		return nil, fmt.Errorf("%w: type %q", ErrMalformedResponse, r.Type)
+	}
+
+	if err := rp.checkClientData(r.Response.ClientDataJSON, ceremonyGet, challenge); err != nil {
+		return nil, err
+	}
+
+	authData, err := parseAuthenticatorData(r.Response.AuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = rp.checkAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+
+	key, err := parsePublicKey(credentialPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(r.Response.ClientDataJSON)
+	signed := append(append([]byte(nil), r.Response.AuthenticatorData...), clientDataHash[:]...)
+
+	if err = key.verify(signed, r.Response.Signature); err != nil {
+		return nil, err
+	}
+
+	// Authenticators without the counter always send zero
+	if (authData.signCount != 0 || signCount != 0) && authData.signCount <= signCount {
+		return nil, ErrSignCountRollback
+	}
+
+	return &Assertion{
+		SignCount:    authData.signCount,
+		UserVerified: authData.flags&flagUserVerified != 0,
+	}, nil
+}
+
+func parseClientData(raw []byte) (*clientData, error) {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidClientData, err)
+	}
+
+	return &data, nil
+}
+
+// checkClientData checks the type of the ceremony, the challenge and the origin
+func (rp *RelyingParty) checkClientData(raw []byte, ceremony string, challenge []byte) error {
+	data, err := parseClientData(raw)
+	if err != nil {
+		return err
+	}
+
+	if data.Type != ceremony {
+		return fmt.Errorf("%w: type %q", ErrInvalidClientData, data.Type)
+	}
+
+	if len(challenge) == 0 || subtle.ConstantTimeCompare(data.Challenge, challenge) != 1 {
+		return fmt.Errorf("%w: challenge doesn't match", ErrInvalidClientData)
+	}
+
+	if _, ok := rp.origins[data.Origin]; !ok || data.CrossOrigin {
+		return fmt.Errorf("%w: origin %q isn't allowed", ErrInvalidClientData, data.Origin)
+	}
+
+	return nil
+}
+
+// checkAuthenticatorData checks the scope of the credential and the presence of the user
+func (rp *RelyingParty) checkAuthenticatorData(data *authenticatorData) error {
+	if subtle.ConstantTimeCompare(data.rpIDHash, rp.rpIDHash[:]) != 1 {
+		return fmt.Errorf("%w: RP ID doesn't match", ErrInvalidAuthenticatorData)
+	}
+
+	if data.flags&flagUserPresent == 0 {
+		return fmt.Errorf("%w: user isn't present", ErrInvalidAuthenticatorData)
+	}
+
+	if rp.cfg.UserVerification == UserVerificationRequired && data.flags&flagUserVerified == 0 {
+		return fmt.Errorf("%w: user isn't verified", ErrInvalidAuthenticatorData)
+	}
+
+	return nil
+}
+
+// parseAuthenticatorData parses authenticator data, extensions are ignored
+func parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	const (
+		headerSize = sha256.Size + 1 + 4
+		aaguidSize = 16
+	)
+
+	if len(raw) < headerSize {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidAuthenticatorData)
+	}
+
+	data := &authenticatorData{
+		rpIDHash:  raw[:sha256.Size],
+		flags:     raw[sha256.Size],
+		signCount: binary.BigEndian.Uint32(raw[sha256.Size+1:]),
+	}
+
+	if data.flags&flagAttestedCredentialData == 0 {
+		return data, nil
+	}
+
+	rest := raw[headerSize:]
+	if len(rest) < aaguidSize+2 {
+		return nil, fmt.Errorf("%w: too short attested credential data", ErrInvalidAuthenticatorData)
+	}
+
+	data.aaguid = rest[:aaguidSize]
+	idSize := int(binary.BigEndian.Uint16(rest[aaguidSize:]))
+	rest = rest[aaguidSize+2:]
+
+	if idSize == 0 || len(rest) < idSize {
+		return nil, fmt.Errorf("%w: invalid credential ID", ErrInvalidAuthenticatorData)
+	}
+
+	data.credentialID = rest[:idSize]
+	rest = rest[idSize:]
+
+	// The key is followed by extensions, so its size is known only after decoding
+	_, tail, err := decodeCBOR(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.flags&flagExtensionData == 0 && len(tail) != 0 {
+		return nil, fmt.Errorf("%w: trailing data", ErrInvalidAuthenticatorData)
+	}
+
+	data.publicKey = rest[:len(rest)-len(tail)]
+
+	return data, nil
+}
@@ -0,0 +1,444 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+const testOrigin = "https://example.com"
+
+// encodeCBOR encodes the subset of CBOR used by authenticators, map keys are sorted for determinism
+func encodeCBOR(item interface{}) []byte {
+	head := func(major byte, value uint64) []byte {
+		switch {
+		case value < 24:
+			return []byte{major<<5 | byte(value)}
+		case value <= 0xff:
+			return []byte{major<<5 | 24, byte(value)}
+		case value <= 0xffff:
+			b := []byte{major<<5 | 25, 0, 0}
+			binary.BigEndian.PutUint16(b[1:], uint16(value))
+
+			return b
+		default:
+			b := []byte{major<<5 | 26, 0, 0, 0, 0}
+			binary.BigEndian.PutUint32(b[1:], uint32(value))
+
+			return b
+		}
+	}
+
+	switch v := item.(type) {
+	case int:
+		if v < 0 {
+			return head(cborNegative, uint64(-1-v))
+		}
+
+		return head(cborUnsigned, uint64(v))
+	case []byte:
+		return append(head(cborBytes, uint64(len(v))), v...)
+	case string:
+		return append(head(cborText, uint64(len(v))), v...)
+	case map[interface{}]interface{}:
+		keys := make([][]byte, 0, len(v))
+		values := make(map[string][]byte, len(v))
+
+		for key, value := range v {
+			encoded := encodeCBOR(key)
+			keys = append(keys, encoded)
+			values[string(encoded)] = encodeCBOR(value)
+		}
+
+		sort.Slice(keys, func(i, j int) bool { return string(keys[i]) < string(keys[j]) })
+
+		b := head(cborMap, uint64(len(v)))
+		for _, key := range keys {
+			b = append(append(b, key...), values[string(key)]...)
+		}
+
+		return b
+	}
+
+	panic("unsupported type")
+}
+
+// softAuthenticator is a software authenticator with one credential
+type softAuthenticator struct {
+	rpID         string
+	origin       string
+	credentialID []byte
+	signer       crypto.Signer
+	coseKey      []byte
+	signCount    uint32
+	flags        byte
+}
+
+func newSoftAuthenticator(t *testing.T, alg int) *softAuthenticator {
+	a := &softAuthenticator{
+		rpID:         "example.com",
+		origin:       testOrigin,
+		credentialID: make([]byte, 16),
+		flags:        flagUserPresent | flagUserVerified,
+	}
+
+	if _, err := rand.Read(a.credentialID); err != nil {
+		t.Fatal(err)
+	}
+
+	switch alg {
+	case AlgES256:
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		x, y := make([]byte, 32), make([]byte, 32)
+		key.X.FillBytes(x)
+		key.Y.FillBytes(y)
+
+		a.signer = key
+		a.coseKey = encodeCBOR(map[interface{}]interface{}{
+			coseKeyType: coseKeyTypeEC2, coseKeyAlg: AlgES256, coseKeyCrv: coseCurveP256, coseKeyX: x, coseKeyY: y,
+		})
+	case AlgEdDSA:
+		pub, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		a.signer = key
+		a.coseKey = encodeCBOR(map[interface{}]interface{}{
+			coseKeyType: coseKeyTypeOKP, coseKeyAlg: AlgEdDSA, coseKeyCrv: coseCurveEd25519, coseKeyX: []byte(pub),
+		})
+	}
+
+	return a
+}
+
+func (a *softAuthenticator) clientData(ceremony string, challenge []byte) []byte {
+	data, _ := json.Marshal(clientData{Type: ceremony, Challenge: challenge, Origin: a.origin})
+	return data
+}
+
+func (a *softAuthenticator) authData(attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	flags := a.flags
+
+	if attested {
+		flags |= flagAttestedCredentialData
+	}
+
+	data := append(rpIDHash[:], flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[sha256.Size+1:], a.signCount)
+
+	if attested {
+		data = append(data, make([]byte, 16)...)
+		data = append(data, byte(len(a.credentialID)>>8), byte(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.coseKey...)
+	}
+
+	return data
+}
+
+func (a *softAuthenticator) create(challenge []byte) *RegistrationResponse {
+	return &RegistrationResponse{
+		ID:    "id",
+		RawID: a.credentialID,
+		Type:  credentialType,
+		Response: AuthenticatorAttestationResponse{
+			ClientDataJSON: a.clientData(ceremonyCreate, challenge),
+			AttestationObject: encodeCBOR(map[interface{}]interface{}{
+				"fmt":      attestationNone,
+				"attStmt":  map[interface{}]interface{}{},
+				"authData": a.authData(true),
+			}),
+		},
+	}
+}
+
+func (a *softAuthenticator) get(t *testing.T, challenge []byte) *AssertionResponse {
+	a.signCount++
+
+	clientDataJSON := a.clientData(ceremonyGet, challenge)
+	authData := a.authData(false)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), authData...), clientDataHash[:]...)
+
+	var (
+		signature []byte
+		err       error
+	)
+
+	if _, ok := a.signer.(ed25519.PrivateKey); ok {
+		signature, err = a.signer.Sign(rand.Reader, signed, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(signed)
+		signature, err = a.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &AssertionResponse{
+		ID:    "id",
+		RawID: a.credentialID,
+		Type:  credentialType,
+		Response: AuthenticatorAssertionResponse{
+			ClientDataJSON:    clientDataJSON,
+			AuthenticatorData: authData,
+			Signature:         signature,
+			UserHandle:        []byte("1"),
+		},
+	}
+}
+
+func testRelyingParty(t *testing.T, userVerification string) *RelyingParty {
+	rp, err := New(&Config{
+		RPID:             "example.com",
+		RPName:           "Example",
+		Origins:          []string{testOrigin},
+		Timeout:          time.Minute,
+		UserVerification: userVerification,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rp
+}
+
+func testChallenge(t *testing.T) []byte {
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return challenge
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	rp := testRelyingParty(t, UserVerificationPreferred)
+
+	for _, alg := range []int{AlgES256, AlgEdDSA} {
+		authenticator := newSoftAuthenticator(t, alg)
+
+		challenge := testChallenge(t)
+		response := authenticator.create(challenge)
+
+		// The response passes JSON like in the request of the browser
+		data, err := json.Marshal(response)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		response = &RegistrationResponse{}
+		if err = json.Unmarshal(data, response); err != nil {
+			t.Fatal(err)
+		}
+
+		signed, err := response.Challenge()
+		if err != nil || string(signed) != string(challenge) {
+			t.Fatalf("alg %d: challenge isn't returned, %v", alg, err)
+		}
+
+		credential, err := rp.VerifyRegistration(response, challenge)
+		if err != nil {
+			t.Fatalf("alg %d: registration failed: %v", alg, err)
+		}
+
+		if string(credential.ID) != string(authenticator.credentialID) {
+			t.Fatalf("alg %d: unexpected credential ID", alg)
+		}
+
+		signCount := credential.SignCount
+
+		for i := 0; i < 2; i++ {
+			challenge = testChallenge(t)
+
+			assertion, err := rp.VerifyAssertion(authenticator.get(t, challenge), challenge, credential.PublicKey, signCount)
+			if err != nil {
+				t.Fatalf("alg %d: assertion failed: %v", alg, err)
+			}
+
+			if assertion.SignCount != authenticator.signCount {
+				t.Fatalf("alg %d: expected sign count %d, got %d", alg, authenticator.signCount, assertion.SignCount)
+			}
+
+			if !assertion.UserVerified {
+				t.Fatalf("alg %d: expected verified user", alg)
+			}
+
+			signCount = assertion.SignCount
+		}
+
+		// The clone of the authenticator has the same counter
+		challenge = testChallenge(t)
+		authenticator.signCount--
+
+		_, err = rp.VerifyAssertion(authenticator.get(t, challenge), challenge, credential.PublicKey, signCount)
+		if !errors.Is(err, ErrSignCountRollback) {
+			t.Fatalf("alg %d: expected sign count rollback, got %v", alg, err)
+		}
+	}
+}
+
+func TestRegistrationErrors(t *testing.T) {
+	rp := testRelyingParty(t, UserVerificationRequired)
+
+	for name, test := range map[string]struct {
+		modify   func(a *softAuthenticator, r *RegistrationResponse)
+		expected error
+	}{
+		"wrong challenge": {
+			modify: func(a *softAuthenticator, r *RegistrationResponse) {
+				r.Response.ClientDataJSON = a.clientData(ceremonyCreate, []byte("other"))
+			},
+			expected: ErrInvalidClientData,
+		},
+		"wrong ceremony": {
+			modify: func(a *softAuthenticator, r *RegistrationResponse) {
+				r.Response.ClientDataJSON = a.clientData(ceremonyGet, mustChallenge(r))
+			},
+			expected: ErrInvalidClientData,
+		},
+		"wrong origin": {
+			modify: func(a *softAuthenticator, r *RegistrationResponse) {
+				a.origin = "https://evil.example"
+				r.Response.ClientDataJSON = a.clientData(ceremonyCreate, mustChallenge(r))
+			},
+			expected: ErrInvalidClientData,
+		},
+		"wrong RP ID": {
+			modify: func(a *softAuthenticator, r *RegistrationResponse) {
+				a.rpID = "evil.example"
+				*r = *a.create(mustChallenge(r))
+			},
+			expected: ErrInvalidAuthenticatorData,
+		},
+		"user isn't verified": {
+			modify: func(a *softAuthenticator, r *RegistrationResponse) {
+				a.flags = flagUserPresent
+				*r = *a.create(mustChallenge(r))
+			},
+			expected: ErrInvalidAuthenticatorData,
+		},
+		"attestation": {
+			modify: func(a *softAuthenticator, r *RegistrationResponse) {
+				r.Response.AttestationObject = encodeCBOR(map[interface{}]interface{}{
+					"fmt":      "packed",
+					"attStmt":  map[interface{}]interface{}{"alg": AlgES256},
+					"authData": a.authData(true),
+				})
+			},
+			expected: ErrUnsupportedAttestation,
+		},
+		"malformed attestation": {
+			modify: func(a *softAuthenticator, r *RegistrationResponse) {
+				r.Response.AttestationObject = r.Response.AttestationObject[:10]
+			},
+			expected: ErrMalformedCBOR,
+		},
+		"unsupported key": {
+			modify: func(a *softAuthenticator, r *RegistrationResponse) {
+				a.coseKey = encodeCBOR(map[interface{}]interface{}{coseKeyType: coseKeyTypeEC2, coseKeyAlg: -35})
+				*r = *a.create(mustChallenge(r))
+			},
+			expected: ErrUnsupportedKey,
+		},
+	} {
+		authenticator := newSoftAuthenticator(t, AlgES256)
+		challenge := testChallenge(t)
+		response := authenticator.create(challenge)
+		test.modify(authenticator, response)
+
+		if _, err := rp.VerifyRegistration(response, challenge); !errors.Is(err, test.expected) {
+			t.Errorf("%s: expected %v, got %v", name, test.expected, err)
+		}
+	}
+}
+
+func TestAssertionErrors(t *testing.T) {
+	rp := testRelyingParty(t, UserVerificationPreferred)
+	authenticator := newSoftAuthenticator(t, AlgES256)
+
+	challenge := testChallenge(t)
+
+	credential, err := rp.VerifyRegistration(authenticator.create(challenge), challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	challenge = testChallenge(t)
+	response := authenticator.get(t, challenge)
+
+	if _, err = rp.VerifyAssertion(response, testChallenge(t), credential.PublicKey, 0); !errors.Is(err, ErrInvalidClientData) {
+		t.Errorf("wrong challenge: expected %v, got %v", ErrInvalidClientData, err)
+	}
+
+	response.Response.Signature[len(response.Response.Signature)-1] ^= 0xff
+
+	if _, err = rp.VerifyAssertion(response, challenge, credential.PublicKey, 0); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("tampered signature: expected %v, got %v", ErrInvalidSignature, err)
+	}
+
+	other := newSoftAuthenticator(t, AlgES256)
+
+	if _, err = rp.VerifyAssertion(other.get(t, challenge), challenge, credential.PublicKey, 0); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("other key: expected %v, got %v", ErrInvalidSignature, err)
+	}
+}
+
+func TestAssertionUserVerification(t *testing.T) {
+	authenticator := newSoftAuthenticator(t, AlgES256)
+
+	challenge := testChallenge(t)
+
+	credential, err := testRelyingParty(t, UserVerificationPreferred).VerifyRegistration(authenticator.create(challenge), challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Security keys without PIN prove only the possession
+	authenticator.flags = flagUserPresent
+
+	challenge = testChallenge(t)
+
+	assertion, err := testRelyingParty(t, UserVerificationPreferred).VerifyAssertion(authenticator.get(t, challenge),
+		challenge, credential.PublicKey, credential.SignCount)
+	if err != nil {
+		t.Fatalf("preferred: unexpected error %v", err)
+	}
+
+	if assertion.UserVerified {
+		t.Error("preferred: expected unverified user")
+	}
+
+	challenge = testChallenge(t)
+
+	_, err = testRelyingParty(t, UserVerificationRequired).VerifyAssertion(authenticator.get(t, challenge),
+		challenge, credential.PublicKey, assertion.SignCount)
+	if !errors.Is(err, ErrInvalidAuthenticatorData) {
+		t.Errorf("required: expected %v, got %v", ErrInvalidAuthenticatorData, err)
+	}
+}
+
+func mustChallenge(r *RegistrationResponse) []byte {
+	challenge, err := r.Challenge()
+	if err != nil {
+		panic(err)
+	}
+
+	return challenge
+}
@@ -55,3 +55,40 @@ type UpdateCredentials struct {
 func (c *UpdateCredentials) Validate() bool {
 	return c.Password != "" && c.OldPassword != ""
 }
+
+// Activation is a struct for activate user by the activation token
+type Activation struct {
+	Token string `json:"activation_token"`
+}
+
+func (a *Activation) Validate() bool {
+	return a.Token != ""
+}
+
+// ActivationResend is a struct for request a new activation token
+type ActivationResend struct {
+	Email string `json:"user_email"`
+}
+
+func (a *ActivationResend) Validate() bool {
+	return a.Email != ""
+}
+
+// PasswordForgot is a struct for request password reset
+type PasswordForgot struct {
+	Email string `json:"user_email"`
+}
+
+func (p *PasswordForgot) Validate() bool {
+	return p.Email != ""
+}
+
+// PasswordReset is a struct for set a new password by the reset token
+type PasswordReset struct {
+	Token    string `json:"reset_token"`
+	Password string `json:"user_password"`
+}
+
+func (p *PasswordReset) Validate() bool {
+	return p.Token != "" && p.Password != ""
+}
@@ -0,0 +1,34 @@
+package models
+
+// MFAEnrollment is a new TOTP secret of the user, it must be confirmed by a code
+type MFAEnrollment struct {
+	// Secret is base32 encoded secret for manual input into authenticator
+	Secret string `json:"mfa_secret"`
+	// URI is otpauth:// URI for QR code
+	URI string `json:"otpauth_uri"`
+}
+
+// MFACode is a struct for confirm the enrollment
+type MFACode struct {
+	Code string `json:"mfa_code"`
+}
+
+func (c *MFACode) Validate() bool {
+	return c.Code != ""
+}
+
+// MFAVerify is a struct for exchange MFA challenge token for a session
+type MFAVerify struct {
+	Token string `json:"mfa_token"`
+	Code  string `json:"mfa_code"`
+}
+
+func (v *MFAVerify) Validate() bool {
+	return v.Token != "" && v.Code != ""
+}
+
+// MFAChallenge is returned by login if the user has to confirm it by TOTP code
+type MFAChallenge struct {
+	Token     string `json:"mfa_token"`
+	ExpiredAt int64  `json:"expired_at"`
+}
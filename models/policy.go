@@ -0,0 +1,55 @@
+package models
+
+import (
+	libpq "github.com/lib/pq"
+	"github.com/soldatov-s/go-garage/types"
+)
+
+// Policy allows or denies the actions if the condition is true, see internal/policy for the language of conditions
+type Policy struct {
+	ID          int64             `json:"policy_id" db:"policy_id"`
+	Name        string            `json:"policy_name" db:"policy_name"`
+	Description types.NullString  `json:"policy_description" db:"policy_description"`
+	Effect      string            `json:"policy_effect" db:"policy_effect"`
+	Actions     libpq.StringArray `json:"policy_actions" db:"policy_actions"`
+	Condition   string            `json:"policy_condition" db:"policy_condition"`
+	CreatedAt   types.NullTime    `json:"created_at" db:"created_at"`
+	UpdatedAt   types.NullTime    `json:"updated_at" db:"updated_at"`
+}
+
+func (p *Policy) SQLParamsRequest() []string {
+	return []string{
+		"policy_name",
+		"policy_description",
+		"policy_effect",
+		"policy_actions",
+		"policy_condition",
+		"created_at",
+		"updated_at",
+	}
+}
+
+// Validate checks the name, the effect and the actions of the policy, the condition is checked by compiling
+func (p *Policy) Validate() bool {
+	return p.Name != "" && (p.Effect == "allow" || p.Effect == "deny") && len(p.Actions) > 0
+}
+
+// AuthorizeRequest is a request of authorization decision, the subject is ID of the user
+type AuthorizeRequest struct {
+	Subject  string                 `json:"subject"`
+	Action   string                 `json:"action"`
+	Resource map[string]interface{} `json:"resource"`
+	Context  map[string]interface{} `json:"context"`
+}
+
+func (r *AuthorizeRequest) Validate() bool {
+	return r.Subject != "" && r.Action != ""
+}
+
+// AuthorizeResult is an authorization decision
+type AuthorizeResult struct {
+	Allowed  bool   `json:"allowed"`
+	Decision string `json:"decision"`
+	Reason   string `json:"reason"`
+	Policy   string `json:"policy,omitempty"`
+}
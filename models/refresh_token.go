@@ -0,0 +1,47 @@
+package models
+
+import (
+	"github.com/soldatov-s/go-garage/types"
+)
+
+// RefreshToken is a token for getting a new access token without credentials.
+// All refresh tokens issued from one login have the same family.
+type RefreshToken struct {
+	Signature       string         `db:"signature"`
+	Family          string         `db:"family"`
+	Subject         string         `db:"subject"`
+	AccessSignature string         `db:"access_signature"`
+	Meta            types.NullMeta `db:"meta"`
+	CreatedAt       types.NullTime `db:"created_at"`
+	UsedAt          types.NullTime `db:"used_at"`
+	ExpiredAt       types.NullTime `db:"expired_at"`
+}
+
+func (s *RefreshToken) SQLParamsRequest() []string {
+	return []string{
+		"signature",
+		"family",
+		"subject",
+		"access_signature",
+		"meta",
+		"created_at",
+		"used_at",
+		"expired_at",
+	}
+}
+
+// TokenPair is an access token with a refresh token
+type TokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiredAt    int64  `json:"expired_at"`
+}
+
+// RefreshRequest is a struct for exchange a refresh token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" form:"refresh_token"`
+}
+
+func (r *RefreshRequest) Validate() bool {
+	return r.RefreshToken != ""
+}
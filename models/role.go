@@ -0,0 +1,63 @@
+package models
+
+import (
+	"regexp"
+
+	"github.com/soldatov-s/go-garage/types"
+)
+
+// permissionName is a format of permission names, e.g. users:write
+var permissionName = regexp.MustCompile(`^[a-z0-9_]+:[a-z0-9_]+$`)
+
+// Role is a named set of permissions, roles are assigned to users
+type Role struct {
+	ID          int64            `json:"role_id" db:"role_id"`
+	Name        string           `json:"role_name" db:"role_name"`
+	Description types.NullString `json:"role_description" db:"role_description"`
+	// Permissions are stored separately from the role
+	Permissions []string       `json:"permissions" db:"-"`
+	CreatedAt   types.NullTime `json:"created_at" db:"created_at"`
+	UpdatedAt   types.NullTime `json:"updated_at" db:"updated_at"`
+}
+
+func (r *Role) SQLParamsRequest() []string {
+	return []string{
+		"role_name",
+		"role_description",
+		"created_at",
+		"updated_at",
+	}
+}
+
+func (r *Role) Validate() bool {
+	if r.Name == "" {
+		return false
+	}
+
+	for _, permission := range r.Permissions {
+		if !permissionName.MatchString(permission) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Permission is an action allowed to the subject, it is named as resource:action
+type Permission struct {
+	Name        string           `json:"permission_name" db:"permission_name"`
+	Description types.NullString `json:"permission_description" db:"permission_description"`
+	CreatedAt   types.NullTime   `json:"created_at" db:"created_at"`
+}
+
+func (p *Permission) SQLParamsRequest() []string {
+	return []string{
+		"permission_name",
+		"permission_description",
+		"created_at",
+	}
+}
+
+func (p *Permission) Validate() bool {
+	return permissionName.MatchString(p.Name)
+}
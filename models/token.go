@@ -1,6 +1,9 @@
 package models
 
 import (
+	"time"
+
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
 	"github.com/soldatov-s/go-garage/types"
 )
 
@@ -9,6 +12,27 @@ type Token struct {
 	Subject   string         `db:"subject"`
 	Meta      types.NullMeta `db:"meta"`
 	ExpiredAt types.NullTime `db:"expired_at"`
+	CreatedAt types.NullTime `db:"created_at"`
+	// SessionID is an opaque ID of the session, all tokens of one login have the same SessionID
+	SessionID  string           `db:"session_id"`
+	LastUsedAt types.NullTime   `db:"last_used_at"`
+	ClientIP   types.NullString `db:"client_ip"`
+	UserAgent  types.NullString `db:"user_agent"`
+	// Role, Status and Permissions of the subject are filled by introspection and aren't stored with the token
+	Role        goGarageAuthTypes.Role   `db:"-"`
+	Status      goGarageAuthTypes.Status `db:"-"`
+	Permissions []string                 `db:"-"`
+}
+
+// HasPermission checks that the permission is granted to the subject
+func (s *Token) HasPermission(permission string) bool {
+	for _, p := range s.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (s *Token) SQLParamsRequest() []string {
@@ -17,17 +41,89 @@ func (s *Token) SQLParamsRequest() []string {
 		"subject",
 		"meta",
 		"expired_at",
+		"created_at",
+		"session_id",
+		"last_used_at",
+		"client_ip",
+		"user_agent",
 	}
 }
 
+// TokenClaims describes the token, self-contained tokens carry the claims inside
+type TokenClaims struct {
+	// ID is an unique ID of token, jti of JWT
+	ID        string
+	Subject   string
+	SessionID string
+	Role      goGarageAuthTypes.Role
+	Status    goGarageAuthTypes.Status
+	IssuedAt  time.Time
+	ExpiredAt time.Time
+	// Meta is carried only by strategies which support arbitrary claims
+	Meta map[string]interface{}
+}
+
+// SessionClient describes the client which has logged in
+type SessionClient struct {
+	ClientIP  string
+	UserAgent string
+}
+
+// Session is an active session of the user
+type Session struct {
+	SessionID  string           `json:"session_id" db:"session_id"`
+	CreatedAt  types.NullTime   `json:"created_at" db:"created_at"`
+	ExpiredAt  types.NullTime   `json:"expired_at" db:"expired_at"`
+	LastUsedAt types.NullTime   `json:"last_used_at" db:"last_used_at"`
+	ClientIP   types.NullString `json:"client_ip" db:"client_ip"`
+	UserAgent  types.NullString `json:"user_agent" db:"user_agent"`
+}
+
 type TokenIntrospection struct {
-	Active    bool                   `json:"active"`
-	Subject   string                 `json:"subject,omitempty"`
-	Meta      map[string]interface{} `json:"meta,omitempty"`
-	ExpiredAt int64                  `json:"expired_at,omitempty"`
+	Active      bool                   `json:"active"`
+	Subject     string                 `json:"subject,omitempty"`
+	Role        string                 `json:"role,omitempty"`
+	Permissions []string               `json:"permissions,omitempty"`
+	Meta        map[string]interface{} `json:"meta,omitempty"`
+	ExpiredAt   int64                  `json:"expired_at,omitempty"`
+}
+
+// OAuth2Introspection is a response of token introspection endpoint by RFC 7662
+type OAuth2Introspection struct {
+	Active    bool   `json:"active"`
+	Subject   string `json:"sub,omitempty"`
+	ExpiredAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+}
+
+// OAuth2TokenRequest is a request of OAuth2 introspection and revocation endpoints
+type OAuth2TokenRequest struct {
+	Token         string `json:"token" form:"token"`
+	TokenTypeHint string `json:"token_type_hint" form:"token_type_hint"`
+}
+
+func (r *OAuth2TokenRequest) Validate() bool {
+	return r.Token != ""
+}
+
+// OAuth2Error is an error response of OAuth2 endpoints by RFC 6749 Section 5.2
+type OAuth2Error struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
 }
 
 type TokenAndUser struct {
-	Token string `json:"token"`
-	User  *User  `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// MFAToken is returned instead of the tokens if the user has to confirm the login by TOTP code
+	MFAToken string `json:"mfa_token,omitempty"`
+	User     *User  `json:"user,omitempty"`
+}
+
+// CSRFToken is a token for the CSRF header of requests authenticated by the session cookie
+type CSRFToken struct {
+	Token string `json:"csrf_token"`
 }
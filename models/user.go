@@ -23,6 +23,12 @@ type User struct {
 	Role           goGarageAuthTypes.Role   `json:"user_role" db:"user_role" swagtype:"string"`
 	Meta           types.NullMeta           `json:"user_meta" db:"user_meta"`
 	ActivationHash types.NullString         `json:"-" db:"user_activation_hash"`
+	// ActivationExpiredAt is an expiration time of the activation token
+	ActivationExpiredAt types.NullTime `json:"-" db:"user_activation_expired_at"`
+	// ActivationSentAt is a generation time of the last activation token, it limits the rate of resending
+	ActivationSentAt types.NullTime `json:"-" db:"user_activation_sent_at"`
+	// ActivationToken is only mailed to the user when it is generated, the database keeps only its hash
+	ActivationToken string `json:"-" db:"-"`
 	models.Timestamp
 }
 
@@ -36,6 +42,8 @@ func (u *User) SQLParamsRequest() []string {
 		"user_role",
 		"user_meta",
 		"user_activation_hash",
+		"user_activation_expired_at",
+		"user_activation_sent_at",
 		"created_at",
 		"updated_at",
 		"deleted_at",
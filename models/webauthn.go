@@ -0,0 +1,32 @@
+package models
+
+import (
+	libpq "github.com/lib/pq"
+	"github.com/soldatov-s/go-garage-auth/internal/webauthn"
+	"github.com/soldatov-s/go-garage/types"
+)
+
+// WebAuthnCredential is a passkey of the user, ID is base64url of the credential ID
+type WebAuthnCredential struct {
+	ID     string `json:"credential_id" db:"credential_id"`
+	UserID int64  `json:"user_id" db:"user_id"`
+	Name   string `json:"credential_name" db:"credential_name"`
+	// PublicKey is COSE_Key of the credential
+	PublicKey  []byte            `json:"-" db:"credential_public_key"`
+	SignCount  int64             `json:"sign_count" db:"credential_sign_count"`
+	AAGUID     string            `json:"aaguid" db:"credential_aaguid"`
+	Transports libpq.StringArray `json:"transports" db:"credential_transports"`
+	CreatedAt  types.NullTime    `json:"created_at" db:"created_at"`
+	LastUsedAt types.NullTime    `json:"last_used_at" db:"last_used_at"`
+}
+
+// WebAuthnRegistration is a struct for finish the registration of the passkey,
+// Credential is PublicKeyCredential returned by navigator.credentials.create
+type WebAuthnRegistration struct {
+	Name       string                        `json:"credential_name"`
+	Credential webauthn.RegistrationResponse `json:"credential"`
+}
+
+func (r *WebAuthnRegistration) Validate() bool {
+	return len(r.Credential.Response.ClientDataJSON) != 0 && len(r.Credential.Response.AttestationObject) != 0
+}
@@ -0,0 +1,287 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/soldatov-s/go-garage-auth/models"
+	httpclient "github.com/soldatov-s/go-garage/clients/http"
+	"github.com/soldatov-s/go-garage/providers/httpsrv"
+)
+
+const apiPrefix = "/api/v1"
+
+// Client is a typed client of private API
+type Client struct {
+	url           string
+	token         string
+	httpClient    *http.Client
+	retries       int
+	retryInterval time.Duration
+}
+
+func NewClient(cfg *Config) (*Client, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		url:           strings.TrimSuffix(cfg.URL, "/") + apiPrefix,
+		token:         cfg.Token,
+		httpClient:    httpclient.NewClient(cfg.HTTP, httpclient.NewNetTransport(cfg.HTTP)).Client,
+		retries:       cfg.Retries,
+		retryInterval: cfg.RetryInterval,
+	}, nil
+}
+
+// CreateUser creates a new user, the error is httpsrv.ErrorAnsw with status 406 if login or email is occupied
+func (c *Client) CreateUser(ctx context.Context, creds *models.NewCredentials) (*models.User, error) {
+	user := &models.User{}
+	if err := c.do(ctx, http.MethodPost, "/users", nil, creds, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetUser returns the user by ID
+func (c *Client) GetUser(ctx context.Context, id int64) (*models.User, error) {
+	user := &models.User{}
+	if err := c.do(ctx, http.MethodGet, "/users/"+strconv.FormatInt(id, 10), nil, nil, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// UpdateUser merges the patch into the user data by RFC 7386
+func (c *Client) UpdateUser(ctx context.Context, id int64, patch map[string]interface{}) (*models.User, error) {
+	user := &models.User{}
+	if err := c.do(ctx, http.MethodPut, "/users/"+strconv.FormatInt(id, 10), nil, patch, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// UpdateCredentials changes the password of the user
+func (c *Client) UpdateCredentials(ctx context.Context, id int64, creds *models.UpdateCredentials) (*models.User, error) {
+	user := &models.User{}
+	if err := c.do(ctx, http.MethodPut, "/credentials/"+strconv.FormatInt(id, 10), nil, creds, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// DeleteUser deletes the user softly or hard
+func (c *Client) DeleteUser(ctx context.Context, id int64, hard bool) error {
+	query := url.Values{}
+	if hard {
+		query.Set("hard", "true")
+	}
+
+	return c.do(ctx, http.MethodDelete, "/users/"+strconv.FormatInt(id, 10), query, nil, nil)
+}
+
+// SearchUsers finds users matching any of the filters, the filters have the same fields as models.User
+func (c *Client) SearchUsers(ctx context.Context, filters []map[string]interface{}) ([]models.User, error) {
+	if filters == nil {
+		filters = []map[string]interface{}{}
+	}
+
+	var users []models.User
+	if err := c.do(ctx, http.MethodPost, "/users/search", nil, filters, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// ResendActivation generates a new activation token of NEW user and mails it to the user
+func (c *Client) ResendActivation(ctx context.Context, id int64) (*models.User, error) {
+	user := &models.User{}
+	if err := c.do(ctx, http.MethodPost, "/users/"+strconv.FormatInt(id, 10)+"/activation", nil, nil, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// CheckCredentials checks user credentials and creates a new session.
+// If MFA of the user is enabled, only MFAToken is returned, it must be passed to VerifyMFA
+func (c *Client) CheckCredentials(ctx context.Context, creds *models.Credentials) (*models.TokenAndUser, error) {
+	tokenAndUser := &models.TokenAndUser{}
+	if err := c.do(ctx, http.MethodPost, "/credentials", nil, creds, tokenAndUser); err != nil {
+		return nil, err
+	}
+
+	return tokenAndUser, nil
+}
+
+// VerifyMFA exchanges MFA token of CheckCredentials and TOTP code for a new session
+func (c *Client) VerifyMFA(ctx context.Context, mfaVerify *models.MFAVerify) (*models.TokenAndUser, error) {
+	tokenAndUser := &models.TokenAndUser{}
+	if err := c.do(ctx, http.MethodPost, "/credentials/mfa", nil, mfaVerify, tokenAndUser); err != nil {
+		return nil, err
+	}
+
+	return tokenAndUser, nil
+}
+
+// Introspect returns the state of the token, the invalid token is inactive
+func (c *Client) Introspect(ctx context.Context, token string) (*models.TokenIntrospection, error) {
+	if token == "" {
+		return nil, ErrEmptyToken
+	}
+
+	introspection := &models.TokenIntrospection{}
+	if err := c.doWithToken(ctx, http.MethodGet, "/auth/introspect", token, introspection); err != nil {
+		return nil, err
+	}
+
+	return introspection, nil
+}
+
+// Revoke revokes the access token
+func (c *Client) Revoke(ctx context.Context, token string) error {
+	if token == "" {
+		return ErrEmptyToken
+	}
+
+	return c.doWithToken(ctx, http.MethodPost, "/auth/revoke", token, nil)
+}
+
+func (c *Client) doWithToken(ctx context.Context, method, path, token string, result interface{}) error {
+	return c.send(ctx, method, path, nil, nil, result, func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	})
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, result interface{}) error {
+	var payload []byte
+
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	return c.send(ctx, method, path, query, payload, result, c.authorize)
+}
+
+// authorize sets the token of the service account
+func (c *Client) authorize(req *http.Request) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+}
+
+// send sends the request, idempotent requests are retried on network errors and 5xx answers
+func (c *Client) send(
+	ctx context.Context,
+	method, path string,
+	query url.Values,
+	payload []byte,
+	result interface{},
+	prepare func(req *http.Request)) error {
+	retries := 0
+	if method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete {
+		retries = c.retries
+	}
+
+	interval := c.retryInterval
+
+	for attempt := 0; ; attempt++ {
+		retry, err := c.sendOnce(ctx, method, path, query, payload, result, prepare)
+		if err == nil || !retry || attempt >= retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		interval *= 2
+	}
+}
+
+func (c *Client) sendOnce(
+	ctx context.Context,
+	method, path string,
+	query url.Values,
+	payload []byte,
+	result interface{},
+	prepare func(req *http.Request)) (retry bool, err error) {
+	target := c.url + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return false, err
+	}
+
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if prepare != nil {
+		prepare(req)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return true, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode >= http.StatusInternalServerError, decodeError(resp.StatusCode, data)
+	}
+
+	if result == nil {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(data, &httpsrv.ResultAnsw{Body: result}); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrUnexpectedAnswer, err)
+	}
+
+	return false, nil
+}
+
+// decodeError returns httpsrv.ErrorAnsw answered by the service
+func decodeError(statusCode int, data []byte) error {
+	answer := httpsrv.ErrorAnsw{}
+	if err := json.Unmarshal(data, &answer); err != nil || answer.Body.Code == "" {
+		return fmt.Errorf("%w: status %d", ErrUnexpectedAnswer, statusCode)
+	}
+
+	answer.Body.StatusCode = statusCode
+
+	return answer
+}
@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/soldatov-s/go-garage-auth/models"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+	"github.com/soldatov-s/go-garage/providers/httpsrv"
+)
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newTestClient(t *testing.T, handler http.Handler, retries int) *Client {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient(&Config{URL: srv.URL, Retries: retries, RetryInterval: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestNewClientEmptyURL(t *testing.T) {
+	if _, err := NewClient(&Config{}); !errors.Is(err, ErrEmptyURL) {
+		t.Fatalf("expected %v, got %v", ErrEmptyURL, err)
+	}
+}
+
+func TestClientGetUser(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/v1/users/42" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{"result": map[string]interface{}{
+			"user_id":    42,
+			"user_email": "user@example.com",
+			"user_role":  "ADMIN",
+		}})
+	}), 0)
+
+	user, err := c.GetUser(context.Background(), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.ID != 42 || user.Email != "user@example.com" || user.Role != goGarageAuthTypes.Admin {
+		t.Fatalf("unexpected user %+v", user)
+	}
+}
+
+func TestClientCreateUserOccupied(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var creds map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&creds); err != nil || creds["user_email"] != "user@example.com" {
+			t.Errorf("unexpected body %v, %v", creds, err)
+		}
+
+		writeJSON(w, http.StatusNotAcceptable, httpsrv.NewErrorAnsw(http.StatusNotAcceptable, "email is occupied",
+			errors.New("email is occupied")))
+	}), 0)
+
+	_, err := c.CreateUser(context.Background(), &models.NewCredentials{
+		Credentials: models.Credentials{Email: "user@example.com", Password: "secret"},
+	})
+
+	var answer httpsrv.ErrorAnsw
+	if !errors.As(err, &answer) {
+		t.Fatalf("expected httpsrv.ErrorAnsw, got %v", err)
+	}
+
+	if answer.Body.StatusCode != http.StatusNotAcceptable || answer.Body.Code != "EMAIL_IS_OCCUPIED" {
+		t.Fatalf("unexpected error %+v", answer)
+	}
+}
+
+func TestClientDeleteUserHard(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Query().Get("hard") != "true" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+
+		writeJSON(w, http.StatusOK, httpsrv.OkResult())
+	}), 0)
+
+	if err := c.DeleteUser(context.Background(), 1, true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientSearchUsers(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"result": []map[string]interface{}{
+			{"user_id": 1}, {"user_id": 2},
+		}})
+	}), 0)
+
+	users, err := c.SearchUsers(context.Background(), []map[string]interface{}{{"user_role": "ADMIN"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users) != 2 || users[1].ID != 2 {
+		t.Fatalf("unexpected users %+v", users)
+	}
+}
+
+func TestClientIntrospectSendsBearer(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" || r.URL.Query().Get("token") != "" {
+			t.Errorf("token must be sent in Authorization header only")
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{"result": models.TokenIntrospection{
+			Active: true, Subject: "1", Role: "USER_L1",
+		}})
+	}), 0)
+
+	introspection, err := c.Introspect(context.Background(), "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !introspection.Active || introspection.Subject != "1" || introspection.Role != "USER_L1" {
+		t.Fatalf("unexpected introspection %+v", introspection)
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	var calls int32
+
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			writeJSON(w, http.StatusServiceUnavailable, httpsrv.NewErrorAnsw(http.StatusServiceUnavailable,
+				"unavailable", errors.New("unavailable")))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{"result": map[string]interface{}{"user_id": 7}})
+	}), 2)
+
+	user, err := c.GetUser(context.Background(), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.ID != 7 || atomic.LoadInt32(&calls) != 3 {
+		t.Fatalf("unexpected user %+v after %d calls", user, calls)
+	}
+}
+
+func TestClientDoesNotRetryPost(t *testing.T) {
+	var calls int32
+
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		writeJSON(w, http.StatusInternalServerError, httpsrv.NewErrorAnsw(http.StatusInternalServerError,
+			"internal", errors.New("internal")))
+	}), 2)
+
+	if _, err := c.CheckCredentials(context.Background(), &models.Credentials{}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if calls != 1 {
+		t.Fatalf("POST must not be retried, got %d calls", calls)
+	}
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"time"
+
+	httpclient "github.com/soldatov-s/go-garage/clients/http"
+)
+
+const (
+	defaultRetryInterval = 100 * time.Millisecond
+	defaultCacheSize     = 10000
+	defaultCacheTTL      = 30 * time.Second
+	defaultNegativeTTL   = 5 * time.Second
+)
+
+// Config describes the client of private API
+type Config struct {
+	// URL of private API, e.g. http://localhost:9100
+	URL string
+	// Token is a bearer token of the service account, it authorizes requests to the user routes
+	Token string
+	// HTTP configures timeouts of HTTP client
+	HTTP *httpclient.ClientConfig
+	// Retries is a number of retries of idempotent requests failed by network errors or 5xx answers
+	Retries int
+	// RetryInterval is an interval before the first retry, it is doubled on every next retry
+	RetryInterval time.Duration
+}
+
+func (c *Config) validate() error {
+	if c.URL == "" {
+		return ErrEmptyURL
+	}
+
+	if c.HTTP == nil {
+		c.HTTP = &httpclient.ClientConfig{}
+	}
+
+	if c.RetryInterval == 0 {
+		c.RetryInterval = defaultRetryInterval
+	}
+
+	return nil
+}
+
+// IntrospectorConfig describes the cache of introspection results
+type IntrospectorConfig struct {
+	// CacheSize is a maximum number of cached results, negative value disables the cache
+	CacheSize int
+	// CacheTTL is a lifetime of results for active tokens, it isn't longer than the token lives
+	CacheTTL time.Duration
+	// NegativeTTL is a lifetime of results for inactive tokens
+	NegativeTTL time.Duration
+}
+
+func (c *IntrospectorConfig) validate() {
+	if c.CacheSize == 0 {
+		c.CacheSize = defaultCacheSize
+	}
+
+	if c.CacheTTL == 0 {
+		c.CacheTTL = defaultCacheTTL
+	}
+
+	if c.NegativeTTL == 0 {
+		c.NegativeTTL = defaultNegativeTTL
+	}
+}
@@ -0,0 +1,12 @@
+package client
+
+import (
+	"errors"
+)
+
+var (
+	ErrEmptyURL         = errors.New("url of private API is empty")
+	ErrEmptyToken       = errors.New("token is required")
+	ErrInactiveToken    = errors.New("token is inactive")
+	ErrUnexpectedAnswer = errors.New("unexpected answer")
+)
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/soldatov-s/go-garage-auth/models"
+)
+
+type cacheKey [sha256.Size]byte
+
+type cacheEntry struct {
+	key           cacheKey
+	introspection *models.TokenIntrospection
+	expiredAt     time.Time
+}
+
+// Introspector introspects tokens by private API and caches the results,
+// so a revoked token might stay active not longer than CacheTTL
+type Introspector struct {
+	client *Client
+	cfg    IntrospectorConfig
+
+	mu    sync.Mutex
+	order *list.List
+	items map[cacheKey]*list.Element
+}
+
+func NewIntrospector(client *Client, cfg *IntrospectorConfig) *Introspector {
+	i := &Introspector{
+		client: client,
+		order:  list.New(),
+		items:  make(map[cacheKey]*list.Element),
+	}
+
+	if cfg != nil {
+		i.cfg = *cfg
+	}
+
+	i.cfg.validate()
+
+	return i
+}
+
+// Introspect returns the state of the token. Errors of private API aren't cached.
+func (i *Introspector) Introspect(ctx context.Context, token string) (*models.TokenIntrospection, error) {
+	if token == "" {
+		return nil, ErrEmptyToken
+	}
+
+	key := sha256.Sum256([]byte(token))
+
+	if introspection, ok := i.get(key); ok {
+		return introspection, nil
+	}
+
+	introspection, err := i.client.Introspect(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	i.set(key, introspection)
+
+	return introspection, nil
+}
+
+func (i *Introspector) get(key cacheKey) (*models.TokenIntrospection, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	el, ok := i.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expiredAt) {
+		i.order.Remove(el)
+		delete(i.items, key)
+
+		return nil, false
+	}
+
+	i.order.MoveToFront(el)
+
+	// Callers must not modify the cached result
+	cached := *entry.introspection
+
+	return &cached, true
+}
+
+func (i *Introspector) set(key cacheKey, introspection *models.TokenIntrospection) {
+	if i.cfg.CacheSize < 0 {
+		return
+	}
+
+	cached := *introspection
+	entry := &cacheEntry{
+		key:           key,
+		introspection: &cached,
+		expiredAt:     time.Now().Add(i.cfg.NegativeTTL),
+	}
+
+	if introspection.Active {
+		entry.expiredAt = time.Now().Add(i.cfg.CacheTTL)
+		if expiredAt := time.Unix(introspection.ExpiredAt, 0); expiredAt.Before(entry.expiredAt) {
+			entry.expiredAt = expiredAt
+		}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if el, ok := i.items[key]; ok {
+		i.order.Remove(el)
+	}
+
+	i.items[key] = i.order.PushFront(entry)
+
+	for i.order.Len() > i.cfg.CacheSize {
+		delete(i.items, i.order.Remove(i.order.Back()).(*cacheEntry).key)
+	}
+}
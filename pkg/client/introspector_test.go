@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soldatov-s/go-garage-auth/models"
+)
+
+func newTestIntrospector(t *testing.T, calls *int32, active bool, cfg *IntrospectorConfig) *Introspector {
+	t.Helper()
+
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+
+		introspection := models.TokenIntrospection{}
+		if active {
+			introspection = models.TokenIntrospection{
+				Active:      true,
+				Subject:     "1",
+				Role:        "ADMIN",
+				Permissions: []string{"users:read"},
+				ExpiredAt:   time.Now().Add(time.Hour).Unix(),
+			}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{"result": introspection})
+	}), 0)
+
+	return NewIntrospector(c, cfg)
+}
+
+func TestIntrospectorCaches(t *testing.T) {
+	var calls int32
+
+	i := newTestIntrospector(t, &calls, true, nil)
+
+	for n := 0; n < 3; n++ {
+		introspection, err := i.Introspect(context.Background(), "token")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !introspection.Active || introspection.Subject != "1" {
+			t.Fatalf("unexpected introspection %+v", introspection)
+		}
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected 1 call, got %d", calls)
+	}
+
+	if _, err := i.Introspect(context.Background(), "other"); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestIntrospectorNegativeTTL(t *testing.T) {
+	var calls int32
+
+	i := newTestIntrospector(t, &calls, false, &IntrospectorConfig{NegativeTTL: time.Millisecond})
+
+	if _, err := i.Introspect(context.Background(), "token"); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	introspection, err := i.Introspect(context.Background(), "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if introspection.Active || calls != 2 {
+		t.Fatalf("inactive result must expire after negative TTL, got %+v after %d calls", introspection, calls)
+	}
+}
+
+func TestIntrospectorCacheSize(t *testing.T) {
+	var calls int32
+
+	i := newTestIntrospector(t, &calls, true, &IntrospectorConfig{CacheSize: 1})
+
+	for _, token := range []string{"first", "second", "first"} {
+		if _, err := i.Introspect(context.Background(), token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if calls != 3 {
+		t.Fatalf("the first token must be evicted, got %d calls", calls)
+	}
+}
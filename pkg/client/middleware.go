@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+	"github.com/soldatov-s/go-garage/providers/httpsrv"
+)
+
+const bearerPrefix = "bearer "
+
+type identityKey struct{}
+
+// Identity is the introspected subject of the request
+type Identity struct {
+	Subject     string
+	Role        goGarageAuthTypes.Role
+	Permissions []string
+	Meta        map[string]interface{}
+	ExpiredAt   time.Time
+}
+
+// HasPermission checks that the permission is granted to the subject
+func (i *Identity) HasPermission(permission string) bool {
+	for _, p := range i.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IdentityFromContext returns the identity put by the middleware
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok
+}
+
+// ContextWithIdentity returns a copy of the context with the identity
+func ContextWithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// Middleware authenticates requests by the bearer token and puts the identity into the request context
+func Middleware(introspector *Introspector) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, code, err := authenticate(introspector, r)
+			if err != nil {
+				writeError(w, code, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ContextWithIdentity(r.Context(), identity)))
+		})
+	}
+}
+
+// EchoMiddleware authenticates requests by the bearer token and puts the identity into the request context
+func EchoMiddleware(introspector *Introspector) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			identity, code, err := authenticate(introspector, ec.Request())
+			if err != nil {
+				writeError(ec.Response(), code, err)
+				return nil
+			}
+
+			ec.SetRequest(ec.Request().WithContext(ContextWithIdentity(ec.Request().Context(), identity)))
+
+			return next(ec)
+		}
+	}
+}
+
+// authenticate returns the identity or the status code of the failure
+func authenticate(introspector *Introspector, r *http.Request) (*Identity, int, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, http.StatusUnauthorized, ErrEmptyToken
+	}
+
+	introspection, err := introspector.Introspect(r.Context(), token)
+	if err != nil {
+		return nil, http.StatusServiceUnavailable, err
+	}
+
+	if !introspection.Active {
+		return nil, http.StatusUnauthorized, ErrInactiveToken
+	}
+
+	return &Identity{
+		Subject:     introspection.Subject,
+		Role:        goGarageAuthTypes.StringToRole()[introspection.Role],
+		Permissions: introspection.Permissions,
+		Meta:        introspection.Meta,
+		ExpiredAt:   time.Unix(introspection.ExpiredAt, 0),
+	}, http.StatusOK, nil
+}
+
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return ""
+	}
+
+	return strings.TrimSpace(header[len(bearerPrefix):])
+}
+
+// writeError answers in the same format as the services on go-garage
+func writeError(w http.ResponseWriter, code int, err error) {
+	answer := httpsrv.Unauthorized(err)
+
+	switch {
+	case code == http.StatusServiceUnavailable:
+		answer = httpsrv.NewErrorAnsw(code, "service unavailable", err)
+	case err == ErrEmptyToken:
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	default:
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(answer)
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	goGarageAuthTypes "github.com/soldatov-s/go-garage-auth/types"
+)
+
+func identityHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := IdentityFromContext(r.Context())
+		if !ok || identity.Subject != "1" || identity.Role != goGarageAuthTypes.Admin ||
+			!identity.HasPermission(goGarageAuthTypes.PermissionUsersRead) {
+			t.Errorf("unexpected identity %+v", identity)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var calls int32
+
+	handler := Middleware(newTestIntrospector(t, &calls, true, nil))(identityHandler(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected %d, got %d", http.StatusNoContent, rec.Code)
+	}
+}
+
+func TestMiddlewareWithoutToken(t *testing.T) {
+	var calls int32
+
+	handler := Middleware(newTestIntrospector(t, &calls, true, nil))(identityHandler(t))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") != "Bearer" || calls != 0 {
+		t.Fatalf("unexpected answer %d %q after %d calls", rec.Code, rec.Header().Get("WWW-Authenticate"), calls)
+	}
+}
+
+func TestMiddlewareInactiveToken(t *testing.T) {
+	var calls int32
+
+	handler := Middleware(newTestIntrospector(t, &calls, false, nil))(identityHandler(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+}
+
+func TestMiddlewareUnavailable(t *testing.T) {
+	c, err := NewClient(&Config{URL: "http://127.0.0.1:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := Middleware(NewIntrospector(c, nil))(identityHandler(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+}
+
+func TestEchoMiddleware(t *testing.T) {
+	var calls int32
+
+	e := echo.New()
+	e.Use(EchoMiddleware(newTestIntrospector(t, &calls, true, nil)))
+	e.GET("/", echo.WrapHandler(identityHandler(t)))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected %d, got %d", http.StatusNoContent, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+}
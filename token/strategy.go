@@ -0,0 +1,50 @@
+package token
+
+import (
+	"context"
+	"time"
+
+	"github.com/soldatov-s/go-garage-auth/models"
+	"github.com/soldatov-s/go-garage/domains"
+)
+
+const (
+	// DomainPrefix is a prefix of the strategy names in the domains registry
+	DomainPrefix = "tokenStrategy."
+)
+
+// Strategy generates and validates access tokens. The strategy of access tokens
+// is chosen in config by the name, which the strategy is registered with.
+type Strategy interface {
+	// Generate generates a token for the claims and returns the token and its signature,
+	// the signature identifies the token in the storage. Generate fills ID, IssuedAt and
+	// ExpiredAt of the claims.
+	Generate(claims *models.TokenClaims) (token, signature string, err error)
+	// Validate returns an error if the token is not valid
+	Validate(token string) error
+	// Signature returns the signature of the token, the token must be validated before
+	Signature(token string) string
+	// TTL returns the lifetime of the tokens
+	TTL() time.Duration
+}
+
+// SelfContainedStrategy is a strategy which tokens carry the claims inside,
+// so the tokens are validated without the storage
+type SelfContainedStrategy interface {
+	Strategy
+	// Claims validates the token and returns its claims
+	Claims(token string) (*models.TokenClaims, error)
+}
+
+// Registrate registers the strategy in the domains registry by the name
+func Registrate(ctx context.Context, name string, s Strategy) context.Context {
+	return domains.RegistrateByName(ctx, DomainPrefix+name, s)
+}
+
+// Get returns the strategy registered by the name
+func Get(ctx context.Context, name string) (Strategy, error) {
+	if v, ok := domains.GetByName(ctx, DomainPrefix+name).(Strategy); ok {
+		return v, nil
+	}
+	return nil, domains.ErrInvalidDomainType
+}
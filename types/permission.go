@@ -0,0 +1,16 @@
+package types
+
+// Permissions seeded by migrations, the permissions are granted to roles in the database
+const (
+	PermissionUsersRead       = "users:read"
+	PermissionUsersWrite      = "users:write"
+	PermissionUsersDelete     = "users:delete"
+	PermissionUsersHardDelete = "users:hard_delete"
+	PermissionUsersMFAReset   = "users:mfa_reset"
+	PermissionSessionsRead    = "sessions:read"
+	PermissionSessionsWrite   = "sessions:write"
+	PermissionRolesRead       = "roles:read"
+	PermissionRolesWrite      = "roles:write"
+	PermissionPoliciesRead    = "policies:read"
+	PermissionPoliciesWrite   = "policies:write"
+)